@@ -4,15 +4,12 @@ import { useCameraStore } from '@/store/cameraStore';
 import { useEffect } from 'react';
 
 const Live = () => {
-  const { cameras, fetchCameras } = useCameraStore();
+  const { cameras, fetchCameras, subscribeEvents } = useCameraStore();
 
   useEffect(() => {
     if (fetchCameras) fetchCameras();
-    const t = setInterval(() => {
-      if (fetchCameras) fetchCameras();
-    }, 5000);
-    return () => clearInterval(t);
-  }, [fetchCameras]);
+    return subscribeEvents();
+  }, [fetchCameras, subscribeEvents]);
   const onlineCameras = cameras.filter(cam => cam.isOnline);
   const offlineCameras = cameras.filter(cam => !cam.isOnline);
 
@@ -2,8 +2,39 @@ import { HardDrive, Bell, Shield, Moon, Globe, Info, ChevronRight, Trash2, Refre
 import Header from '@/components/Header';
 import { Switch } from '@/components/ui/switch';
 import { toast } from '@/hooks/use-toast';
+import { useEffect, useState } from 'react';
 
 const Settings = () => {
+  const [offlineAlerts, setOfflineAlerts] = useState(true);
+
+  useEffect(() => {
+    fetch('/api/alerts/settings')
+      .then((res) => (res.ok ? res.json() : null))
+      .then((data) => {
+        if (data) setOfflineAlerts(Boolean(data.enabled));
+      })
+      .catch(() => {});
+  }, []);
+
+  const handleOfflineAlerts = async (enabled: boolean) => {
+    setOfflineAlerts(enabled);
+    try {
+      const res = await fetch('/api/alerts/settings', {
+        method: 'PUT',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ enabled }),
+      });
+      if (!res.ok) throw new Error('failed to update alert settings');
+    } catch (err) {
+      setOfflineAlerts(!enabled);
+      toast({
+        title: 'บันทึกการตั้งค่าไม่สำเร็จ',
+        description: 'ไม่สามารถเปลี่ยนการแจ้งเตือนกล้องออฟไลน์ได้',
+        variant: 'destructive',
+      });
+    }
+  };
+
   const handleClearStorage = () => {
     toast({
       title: 'ล้างไฟล์เก่าสำเร็จ',
@@ -48,8 +79,9 @@ const Settings = () => {
         {
           icon: Shield,
           label: 'แจ้งเตือนกล้องออฟไลน์',
-          value: true,
+          value: offlineAlerts,
           type: 'switch',
+          onChange: handleOfflineAlerts,
         },
       ],
     },
@@ -112,7 +144,11 @@ const Settings = () => {
                     </span>
                   </div>
                   {item.type === 'switch' ? (
-                    <Switch defaultChecked={item.value as boolean} />
+                    item.onChange ? (
+                      <Switch checked={item.value as boolean} onCheckedChange={item.onChange} />
+                    ) : (
+                      <Switch defaultChecked={item.value as boolean} />
+                    )
                   ) : (
                     <div className="flex items-center gap-2 text-muted-foreground">
                       <span className="text-sm">{item.value}</span>
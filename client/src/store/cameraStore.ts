@@ -20,6 +20,7 @@ interface CameraState {
   fetchCameras?: () => Promise<void>;
   createCamera?: (payload: { name: string; location?: string; rtsp_url: string, username?: string, password?: string }) => Promise<Camera | null>;
   deleteCamera?: (cameraId: string) => Promise<boolean>;
+  subscribeEvents: () => () => void;
 }
 
 export const useCameraStore = create<CameraState>((set) => ({
@@ -106,6 +107,37 @@ export const useCameraStore = create<CameraState>((set) => ({
         cam.id === cameraId ? { ...cam, isOnline } : cam
       ),
     })),
+  // open a server-sent event stream and apply status changes as they happen;
+  // returns a function that closes the stream
+  subscribeEvents: () => {
+    const source = new EventSource('/api/events/stream');
+    const cameraId = (e: MessageEvent) => {
+      try {
+        return String((JSON.parse(e.data) as Record<string, unknown>)['camera_id'] ?? '');
+      } catch {
+        return '';
+      }
+    };
+    const setRecording = (id: string, isRecording: boolean) =>
+      set((state) => ({
+        cameras: state.cameras.map((cam) => (cam.id === id ? { ...cam, isRecording } : cam)),
+      }));
+    const refetch = () => useCameraStore.getState().fetchCameras?.();
+
+    source.addEventListener('camera.online', (e) =>
+      useCameraStore.getState().updateCameraStatus(cameraId(e as MessageEvent), true));
+    source.addEventListener('camera.offline', (e) =>
+      useCameraStore.getState().updateCameraStatus(cameraId(e as MessageEvent), false));
+    source.addEventListener('recording.started', (e) => setRecording(cameraId(e as MessageEvent), true));
+    source.addEventListener('capture.started', (e) => setRecording(cameraId(e as MessageEvent), true));
+    // a camera may still have another session running, so ask the server;
+    // after a truncated replay some changes were never seen
+    for (const type of ['recording.stopped', 'recording.failed', 'capture.stopped', 'capture.failed',
+      'camera.created', 'camera.updated', 'camera.deleted', 'replay.truncated']) {
+      source.addEventListener(type, refetch);
+    }
+    return () => source.close();
+  },
   deleteCamera: async (cameraId: string) => {
     try {
       const res = await fetch(`/api/cameras/${cameraId}`, {
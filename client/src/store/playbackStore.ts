@@ -116,18 +116,20 @@ export const usePlaybackStore = create<PlaybackState>((set) => ({
   fetchTimeline: async (cameraId: string, date: Date) => {
     try {
       const d = date.toISOString().slice(0, 10);
-      const res = await fetch(`/api/timeline?cameraId=${encodeURIComponent(cameraId)}&date=${d}`);
+      // one bucket per hour for the timeline bar
+      const res = await fetch(`/api/timeline?cameraId=${encodeURIComponent(cameraId)}&date=${d}&bucket=3600`);
       if (!res.ok) {
         console.error('Failed to fetch timeline:', res.status);
         set({ timelineSegments: [] });
         return;
       }
-      const raw = (await res.json()) as Array<Record<string, unknown>>;
-      const segs: RecordingSegment[] = raw.map((s) => ({
-        startTime: String(s['startTime'] ?? s['start_time'] ?? ''),
-        endTime: String(s['endTime'] ?? s['end_time'] ?? ''),
-        duration: Number(s['duration'] ?? 0),
-        hasRecording: Boolean(s['hasRecording'] ?? s['has_recording'] ?? false),
+      const raw = (await res.json()) as Record<string, unknown>;
+      const buckets = (raw['buckets'] ?? []) as Array<Record<string, unknown>>;
+      const segs: RecordingSegment[] = buckets.map((s) => ({
+        startTime: String(s['startTime'] ?? ''),
+        endTime: String(s['endTime'] ?? ''),
+        duration: Number(s['duration'] ?? 0) / 60,
+        hasRecording: Boolean(s['hasRecording'] ?? false),
       }));
       set({ timelineSegments: segs });
     } catch (err) {
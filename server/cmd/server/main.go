@@ -8,10 +8,16 @@ import (
 
 	dbadapter "github.com/boytur/cctv-recording-center/server/internal/adapter/db"
 	httpadapter "github.com/boytur/cctv-recording-center/server/internal/adapter/http"
+	"github.com/boytur/cctv-recording-center/server/internal/alert"
 	"github.com/boytur/cctv-recording-center/server/internal/autorecord"
+	"github.com/boytur/cctv-recording-center/server/internal/events"
+	"github.com/boytur/cctv-recording-center/server/internal/mail"
 	"github.com/boytur/cctv-recording-center/server/internal/monitor"
+	"github.com/boytur/cctv-recording-center/server/internal/mqtt"
+	"github.com/boytur/cctv-recording-center/server/internal/onvif"
 	"github.com/boytur/cctv-recording-center/server/internal/recorder"
 	"github.com/boytur/cctv-recording-center/server/internal/usecase"
+	"github.com/boytur/cctv-recording-center/server/internal/webhook"
 
 	"time"
 )
@@ -24,21 +30,86 @@ func main() {
 		log.Fatalf("failed to open db: %v", err)
 	}
 
+	// persist everything published on the event bus
+	eventRepo := dbadapter.NewGormEventRepo(db)
+	events.SetStore(eventRepo)
+	eventRetention := events.NewRetention(eventRepo, events.RetentionFromEnv())
+	// the PTZ audit trail outlives the rest of the event log
+	eventRetention.Keep(events.AuditRetentionFromEnv(), events.AuditPrefixes...)
+
 	// create repository and usecase
 	repo := dbadapter.NewGormCameraRepo(db)
-	uc := usecase.NewCameraUsecase(repo)
+	captures := usecase.NewCaptureUsecase(dbadapter.NewGormCaptureRepo(db), repo)
+	uc := usecase.NewCameraUsecase(repo, captures)
+	bookmarks := usecase.NewBookmarkUsecase(dbadapter.NewGormBookmarkRepo(db), repo)
+	eventLog := usecase.NewEventUsecase(eventRepo)
+	webhookRepo := dbadapter.NewGormWebhookRepo(db)
+	dispatcher := webhook.NewDispatcher(webhookRepo)
+	deliveryRetention := webhook.NewRetention(webhookRepo, webhook.RetentionFromEnv())
+	webhooks := usecase.NewWebhookUsecase(webhookRepo, dispatcher)
+	alertRepo := dbadapter.NewGormAlertRepo(db)
+	emailGroupRepo := dbadapter.NewGormEmailGroupRepo(db)
+	statusHistory := dbadapter.NewGormStatusHistoryRepo(db)
+	availability := usecase.NewAvailabilityUsecase(statusHistory, repo)
+	emailNotifier := alert.NewEmailNotifier(mail.ConfigFromEnv(), emailGroupRepo, repo, eventRepo, availability)
+	notifiers := alert.NotifiersFromEnv()
+	if emailNotifier.Enabled() {
+		notifiers = append(notifiers, emailNotifier)
+	}
+	alerter := alert.NewAlerter(repo, alertRepo, eventRepo, notifiers...)
+	alerts := usecase.NewAlertUsecase(alertRepo, repo, alerter)
+	emailGroups := usecase.NewEmailGroupUsecase(emailGroupRepo, emailNotifier)
+	discovery := usecase.NewDiscoveryUsecase(onvif.DiscovererFromEnv(), repo)
+	onvifDevices := usecase.NewONVIFUsecase(uc, repo)
+	ptz := usecase.NewPTZUsecase(repo, dbadapter.NewGormPatrolTourRepo(db))
+	deviceRepo := dbadapter.NewGormDeviceRepo(db)
+	devices := usecase.NewDeviceUsecase(deviceRepo, uc, repo)
 
 	// create handlers
-	h := httpadapter.NewHandler(uc)
+	h := httpadapter.NewHandler(uc, captures, bookmarks, eventLog, webhooks, alerts, emailGroups, availability, discovery, onvifDevices, ptz, devices)
+
+	// delete events past the retention period
+	eventRetention.Start()
+
+	// deliver events to webhook subscribers
+	dispatcher.Start()
+
+	// delete finished webhook deliveries past the retention period
+	deliveryRetention.Start()
+
+	// alert on cameras that stay offline past the grace period
+	alerter.Start()
+	if emailNotifier.Enabled() {
+		emailNotifier.StartDigest()
+	}
+
+	// mirror camera state and events to MQTT (Home Assistant) if configured
+	var bridge *mqtt.Bridge
+	if cfg := mqtt.ConfigFromEnv(); cfg.Enabled() {
+		bridge = mqtt.NewBridge(cfg, repo, captures)
+		bridge.Start()
+	}
 
-	// start background monitor to update camera online/offline status
-	monitor.StartMonitor(repo, 1*time.Minute)
+	// probe cameras in the background to keep their status up to date
+	cameraMonitor := monitor.NewMonitor(repo, statusHistory, monitor.Config{Interval: 1 * time.Minute, Timeout: 5 * time.Second, Workers: 16})
+	cameraMonitor.Start()
 
-	// start automatic recording for online cameras
-	autoRecorder := autorecord.NewManager(repo, 30*time.Second)
+	// keep NVR/DVR device health up to date
+	deviceMonitor := monitor.NewDeviceMonitor(deviceRepo, repo, 1*time.Minute)
+	deviceMonitor.Start()
+
+	// start automatic recording for online cameras, reacting to status
+	// changes and resyncing every minute
+	autoRecorder := autorecord.NewManager(repo, 1*time.Minute)
 	autoRecorder.Start()
 	log.Println("Auto-recording enabled: cameras will record automatically when online")
 
+	// run PTZ patrol tours on their schedules
+	ptz.Start()
+
+	// resume timed manual captures interrupted by the last shutdown
+	captures.ResumePending()
+
 	// setup router
 	r := httpadapter.SetupRouter(h)
 
@@ -54,7 +125,19 @@ func main() {
 		<-sigChan
 		log.Println("Shutting down gracefully...")
 		autoRecorder.Stop()
+		cameraMonitor.Stop()
+		deviceMonitor.Stop()
+		ptz.Stop()
+		captures.Shutdown()
 		recorder.StopAll()
+		dispatcher.Stop()
+		deliveryRetention.Stop()
+		eventRetention.Stop()
+		alerter.Stop()
+		emailNotifier.Stop()
+		if bridge != nil {
+			bridge.Stop()
+		}
 		os.Exit(0)
 	}()
 
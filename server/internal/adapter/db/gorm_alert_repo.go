@@ -0,0 +1,113 @@
+package dbadapter
+
+import (
+	"errors"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"gorm.io/gorm"
+)
+
+// gormAlertSettings is the single-row table holding domain.AlertSettings.
+type gormAlertSettings struct {
+	ID                 uint `gorm:"primaryKey"`
+	Enabled            bool
+	GracePeriodSeconds int
+	UpdatedAt          time.Time
+}
+
+// alertSettingsRowID is the primary key of the only settings row.
+const alertSettingsRowID = 1
+
+// gormAlertMute is the GORM representation of domain.AlertMute. START and END
+// are SQL keywords, hence the column names.
+type gormAlertMute struct {
+	ID        string    `gorm:"primaryKey"`
+	CameraID  string    `gorm:"index"`
+	Start     time.Time `gorm:"column:start_at"`
+	End       time.Time `gorm:"column:end_at;index"`
+	Reason    string
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+func (g *gormAlertMute) toDomain() *domain.AlertMute {
+	return &domain.AlertMute{ID: g.ID, CameraID: g.CameraID, Start: g.Start, End: g.End, Reason: g.Reason, CreatedBy: g.CreatedBy, CreatedAt: g.CreatedAt}
+}
+
+func muteFromDomain(d *domain.AlertMute) *gormAlertMute {
+	return &gormAlertMute{ID: d.ID, CameraID: d.CameraID, Start: d.Start.UTC(), End: d.End.UTC(), Reason: d.Reason, CreatedBy: d.CreatedBy, CreatedAt: d.CreatedAt}
+}
+
+// GormAlertRepo stores alert settings and mute windows via GORM.
+type GormAlertRepo struct {
+	db *gorm.DB
+}
+
+// NewGormAlertRepo returns an alert repository backed by gorm DB.
+func NewGormAlertRepo(db *gorm.DB) *GormAlertRepo {
+	return &GormAlertRepo{db: db}
+}
+
+// GetSettings returns the saved settings or the defaults: enabled with
+// domain.DefaultAlertGracePeriod.
+func (r *GormAlertRepo) GetSettings() (*domain.AlertSettings, error) {
+	var g gormAlertSettings
+	err := r.db.First(&g, alertSettingsRowID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &domain.AlertSettings{Enabled: true, GracePeriod: domain.DefaultAlertGracePeriod}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &domain.AlertSettings{Enabled: g.Enabled, GracePeriod: time.Duration(g.GracePeriodSeconds) * time.Second, UpdatedAt: g.UpdatedAt}, nil
+}
+
+// SaveSettings stores the settings.
+func (r *GormAlertRepo) SaveSettings(s *domain.AlertSettings) error {
+	return r.db.Save(&gormAlertSettings{
+		ID:                 alertSettingsRowID,
+		Enabled:            s.Enabled,
+		GracePeriodSeconds: int(s.GracePeriod / time.Second),
+		UpdatedAt:          s.UpdatedAt,
+	}).Error
+}
+
+// ListMutes returns mute windows ordered by start time.
+func (r *GormAlertRepo) ListMutes(cameraID string, notEndedBefore time.Time) ([]*domain.AlertMute, error) {
+	q := r.db.Model(&gormAlertMute{})
+	if cameraID != "" {
+		q = q.Where("camera_id = ?", cameraID)
+	}
+	if !notEndedBefore.IsZero() {
+		q = q.Where("end_at > ?", notEndedBefore.UTC())
+	}
+	var gs []gormAlertMute
+	if err := q.Order("start_at").Find(&gs).Error; err != nil {
+		return nil, err
+	}
+	res := make([]*domain.AlertMute, 0, len(gs))
+	for _, g := range gs {
+		res = append(res, g.toDomain())
+	}
+	return res, nil
+}
+
+// GetMute returns a mute window by id.
+func (r *GormAlertRepo) GetMute(id string) (*domain.AlertMute, error) {
+	var g gormAlertMute
+	if err := r.db.First(&g, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return g.toDomain(), nil
+}
+
+// CreateMute inserts a mute window.
+func (r *GormAlertRepo) CreateMute(m *domain.AlertMute) error {
+	return r.db.Create(muteFromDomain(m)).Error
+}
+
+// DeleteMute removes a mute window.
+func (r *GormAlertRepo) DeleteMute(id string) error {
+	return r.db.Delete(&gormAlertMute{}, "id = ?", id).Error
+}
@@ -0,0 +1,111 @@
+package dbadapter
+
+import (
+	"strings"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"gorm.io/gorm"
+)
+
+// gormBookmark is the GORM representation of domain.Bookmark. Tags are stored
+// as a comma-separated list wrapped in commas (",a,b,") so a single tag can
+// be matched with LIKE.
+type gormBookmark struct {
+	ID          string `gorm:"primaryKey"`
+	CameraID    string `gorm:"index"`
+	Title       string
+	Description string
+	Tags        string
+	Time        time.Time `gorm:"index"`
+	EndTime     *time.Time
+	CreatedBy   string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (g *gormBookmark) toDomain() *domain.Bookmark {
+	tags := []string{}
+	for _, t := range strings.Split(strings.Trim(g.Tags, ","), ",") {
+		if t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return &domain.Bookmark{ID: g.ID, CameraID: g.CameraID, Title: g.Title, Description: g.Description, Tags: tags, Time: g.Time, EndTime: g.EndTime, CreatedBy: g.CreatedBy, CreatedAt: g.CreatedAt, UpdatedAt: g.UpdatedAt}
+}
+
+func bookmarkFromDomain(d *domain.Bookmark) *gormBookmark {
+	tags := ""
+	if len(d.Tags) > 0 {
+		tags = "," + strings.Join(d.Tags, ",") + ","
+	}
+	return &gormBookmark{ID: d.ID, CameraID: d.CameraID, Title: d.Title, Description: d.Description, Tags: tags, Time: d.Time.UTC(), EndTime: utcPtr(d.EndTime), CreatedBy: d.CreatedBy, CreatedAt: d.CreatedAt, UpdatedAt: d.UpdatedAt}
+}
+
+// GormBookmarkRepo stores bookmarks via GORM.
+type GormBookmarkRepo struct {
+	db *gorm.DB
+}
+
+// NewGormBookmarkRepo returns a bookmark repository backed by gorm DB.
+func NewGormBookmarkRepo(db *gorm.DB) *GormBookmarkRepo {
+	return &GormBookmarkRepo{db: db}
+}
+
+// List returns bookmarks matching the filter, ordered by time.
+func (r *GormBookmarkRepo) List(f domain.BookmarkFilter) ([]*domain.Bookmark, error) {
+	q := r.db.Model(&gormBookmark{})
+	if f.CameraID != "" {
+		q = q.Where("camera_id = ?", f.CameraID)
+	}
+	if !f.From.IsZero() {
+		// include spans that started earlier but reach into the window
+		q = q.Where("time >= ? OR (end_time IS NOT NULL AND end_time >= ?)", f.From.UTC(), f.From.UTC())
+	}
+	if !f.To.IsZero() {
+		q = q.Where("time < ?", f.To.UTC())
+	}
+	if f.Query != "" {
+		like := "%" + f.Query + "%"
+		q = q.Where("title LIKE ? OR description LIKE ?", like, like)
+	}
+	if f.Tag != "" {
+		q = q.Where("tags LIKE ?", "%,"+f.Tag+",%")
+	}
+	if f.Limit > 0 {
+		q = q.Limit(f.Limit)
+	}
+	var gs []gormBookmark
+	if err := q.Order("time").Find(&gs).Error; err != nil {
+		return nil, err
+	}
+	res := make([]*domain.Bookmark, 0, len(gs))
+	for _, g := range gs {
+		res = append(res, g.toDomain())
+	}
+	return res, nil
+}
+
+// GetByID returns a bookmark by id.
+func (r *GormBookmarkRepo) GetByID(id string) (*domain.Bookmark, error) {
+	var g gormBookmark
+	if err := r.db.First(&g, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return g.toDomain(), nil
+}
+
+// Create inserts a new bookmark.
+func (r *GormBookmarkRepo) Create(b *domain.Bookmark) error {
+	return r.db.Create(bookmarkFromDomain(b)).Error
+}
+
+// Update saves all fields of an existing bookmark.
+func (r *GormBookmarkRepo) Update(b *domain.Bookmark) error {
+	return r.db.Save(bookmarkFromDomain(b)).Error
+}
+
+// Delete removes a bookmark by id.
+func (r *GormBookmarkRepo) Delete(id string) error {
+	return r.db.Delete(&gormBookmark{}, "id = ?", id).Error
+}
@@ -0,0 +1,96 @@
+package dbadapter
+
+import (
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"gorm.io/gorm"
+)
+
+// gormCapture is the GORM representation of domain.Capture.
+type gormCapture struct {
+	ID          string `gorm:"primaryKey"`
+	CameraID    string `gorm:"index"`
+	FileName    string
+	Operator    string
+	Note        string
+	StartedAt   time.Time `gorm:"index"`
+	Duration    int
+	StopAt      *time.Time
+	StoppedAt   *time.Time
+	Status      string `gorm:"index"`
+	ResumedFrom string
+}
+
+func (g *gormCapture) toDomain() *domain.Capture {
+	return &domain.Capture{ID: g.ID, CameraID: g.CameraID, FileName: g.FileName, Operator: g.Operator, Note: g.Note, StartedAt: g.StartedAt, Duration: g.Duration, StopAt: g.StopAt, StoppedAt: g.StoppedAt, Status: g.Status, ResumedFrom: g.ResumedFrom}
+}
+
+func captureFromDomain(d *domain.Capture) *gormCapture {
+	return &gormCapture{ID: d.ID, CameraID: d.CameraID, FileName: d.FileName, Operator: d.Operator, Note: d.Note, StartedAt: d.StartedAt.UTC(), Duration: d.Duration, StopAt: utcPtr(d.StopAt), StoppedAt: utcPtr(d.StoppedAt), Status: d.Status, ResumedFrom: d.ResumedFrom}
+}
+
+// GormCaptureRepo stores manual captures via GORM.
+type GormCaptureRepo struct {
+	db *gorm.DB
+}
+
+// NewGormCaptureRepo returns a capture repository backed by gorm DB.
+func NewGormCaptureRepo(db *gorm.DB) *GormCaptureRepo {
+	return &GormCaptureRepo{db: db}
+}
+
+// ListByCamera returns captures for a camera started within [from, to).
+func (r *GormCaptureRepo) ListByCamera(cameraID string, from, to time.Time) ([]*domain.Capture, error) {
+	var gs []gormCapture
+	if err := r.db.Where("camera_id = ? AND started_at >= ? AND started_at < ?", cameraID, from.UTC(), to.UTC()).Order("started_at").Find(&gs).Error; err != nil {
+		return nil, err
+	}
+	res := make([]*domain.Capture, 0, len(gs))
+	for _, g := range gs {
+		res = append(res, g.toDomain())
+	}
+	return res, nil
+}
+
+// ListByStatus returns all captures in the given state.
+func (r *GormCaptureRepo) ListByStatus(status string) ([]*domain.Capture, error) {
+	var gs []gormCapture
+	if err := r.db.Where("status = ?", status).Order("started_at").Find(&gs).Error; err != nil {
+		return nil, err
+	}
+	res := make([]*domain.Capture, 0, len(gs))
+	for _, g := range gs {
+		res = append(res, g.toDomain())
+	}
+	return res, nil
+}
+
+// GetByID returns a capture by id.
+func (r *GormCaptureRepo) GetByID(id string) (*domain.Capture, error) {
+	var g gormCapture
+	if err := r.db.First(&g, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return g.toDomain(), nil
+}
+
+// Create inserts a new capture.
+func (r *GormCaptureRepo) Create(c *domain.Capture) error {
+	return r.db.Create(captureFromDomain(c)).Error
+}
+
+// Update saves all fields of an existing capture.
+func (r *GormCaptureRepo) Update(c *domain.Capture) error {
+	return r.db.Save(captureFromDomain(c)).Error
+}
+
+// utcPtr normalises an optional timestamp to UTC. Times are stored as text
+// in SQLite, so a consistent zone keeps range comparisons correct.
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
@@ -0,0 +1,102 @@
+package dbadapter
+
+import (
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"gorm.io/gorm"
+)
+
+// gormDevice is the GORM representation of domain.Device with the health
+// fields inlined.
+type gormDevice struct {
+	ID             string `gorm:"primaryKey"`
+	Name           string
+	Location       string
+	Vendor         string
+	Host           string
+	Username       string
+	Password       string
+	URLTemplate    string
+	Stream         string
+	Status         string
+	StatusReason   string
+	ChannelsOnline int
+	ChannelsTotal  int
+	CheckedAt      *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (g *gormDevice) toDomain() *domain.Device {
+	return &domain.Device{ID: g.ID, Name: g.Name, Location: g.Location, Vendor: g.Vendor, Host: g.Host, Username: g.Username, Password: g.Password, URLTemplate: g.URLTemplate, Stream: g.Stream,
+		Health:    domain.DeviceHealth{Status: g.Status, Reason: g.StatusReason, ChannelsOnline: g.ChannelsOnline, ChannelsTotal: g.ChannelsTotal, CheckedAt: g.CheckedAt},
+		CreatedAt: g.CreatedAt, UpdatedAt: g.UpdatedAt}
+}
+
+func deviceFromDomain(d *domain.Device) *gormDevice {
+	return &gormDevice{ID: d.ID, Name: d.Name, Location: d.Location, Vendor: d.Vendor, Host: d.Host, Username: d.Username, Password: d.Password, URLTemplate: d.URLTemplate, Stream: d.Stream,
+		Status: d.Health.Status, StatusReason: d.Health.Reason, ChannelsOnline: d.Health.ChannelsOnline, ChannelsTotal: d.Health.ChannelsTotal, CheckedAt: utcPtr(d.Health.CheckedAt),
+		CreatedAt: d.CreatedAt, UpdatedAt: d.UpdatedAt}
+}
+
+// GormDeviceRepo stores multi-channel devices via GORM.
+type GormDeviceRepo struct {
+	db *gorm.DB
+}
+
+// NewGormDeviceRepo returns a device repository backed by gorm DB.
+func NewGormDeviceRepo(db *gorm.DB) *GormDeviceRepo {
+	return &GormDeviceRepo{db: db}
+}
+
+// List returns all devices ordered by name.
+func (r *GormDeviceRepo) List() ([]*domain.Device, error) {
+	var gs []gormDevice
+	if err := r.db.Order("name").Find(&gs).Error; err != nil {
+		return nil, err
+	}
+	res := make([]*domain.Device, 0, len(gs))
+	for _, g := range gs {
+		res = append(res, g.toDomain())
+	}
+	return res, nil
+}
+
+// GetByID returns a device by id.
+func (r *GormDeviceRepo) GetByID(id string) (*domain.Device, error) {
+	var g gormDevice
+	if err := r.db.First(&g, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return g.toDomain(), nil
+}
+
+// Create inserts a new device.
+func (r *GormDeviceRepo) Create(d *domain.Device) error {
+	return r.db.Create(deviceFromDomain(d)).Error
+}
+
+// Update saves a device's settings. The health is left alone so an edit
+// never overwrites a newer health check.
+func (r *GormDeviceRepo) Update(d *domain.Device) error {
+	return r.db.Model(&gormDevice{}).Where("id = ?", d.ID).
+		Select("name", "location", "vendor", "host", "username", "password", "url_template", "stream", "updated_at").
+		Updates(deviceFromDomain(d)).Error
+}
+
+// UpdateHealth stores the outcome of a health check.
+func (r *GormDeviceRepo) UpdateHealth(id string, h domain.DeviceHealth) error {
+	return r.db.Model(&gormDevice{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          h.Status,
+		"status_reason":   h.Reason,
+		"channels_online": h.ChannelsOnline,
+		"channels_total":  h.ChannelsTotal,
+		"checked_at":      utcPtr(h.CheckedAt),
+	}).Error
+}
+
+// Delete removes a device by id.
+func (r *GormDeviceRepo) Delete(id string) error {
+	return r.db.Delete(&gormDevice{}, "id = ?", id).Error
+}
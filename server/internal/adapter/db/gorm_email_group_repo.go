@@ -0,0 +1,76 @@
+package dbadapter
+
+import (
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"gorm.io/gorm"
+)
+
+// gormEmailGroup is the GORM representation of domain.EmailGroup.
+type gormEmailGroup struct {
+	ID             string `gorm:"primaryKey"`
+	Name           string
+	Recipients     string
+	CameraIDs      string
+	Alerts         bool
+	Digest         bool
+	AttachSnapshot bool
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (g *gormEmailGroup) toDomain() *domain.EmailGroup {
+	return &domain.EmailGroup{ID: g.ID, Name: g.Name, Recipients: splitList(g.Recipients), CameraIDs: splitList(g.CameraIDs), Alerts: g.Alerts, Digest: g.Digest, AttachSnapshot: g.AttachSnapshot, CreatedAt: g.CreatedAt, UpdatedAt: g.UpdatedAt}
+}
+
+func emailGroupFromDomain(d *domain.EmailGroup) *gormEmailGroup {
+	return &gormEmailGroup{ID: d.ID, Name: d.Name, Recipients: joinList(d.Recipients), CameraIDs: joinList(d.CameraIDs), Alerts: d.Alerts, Digest: d.Digest, AttachSnapshot: d.AttachSnapshot, CreatedAt: d.CreatedAt, UpdatedAt: d.UpdatedAt}
+}
+
+// GormEmailGroupRepo stores email recipient groups via GORM.
+type GormEmailGroupRepo struct {
+	db *gorm.DB
+}
+
+// NewGormEmailGroupRepo returns an email group repository backed by gorm DB.
+func NewGormEmailGroupRepo(db *gorm.DB) *GormEmailGroupRepo {
+	return &GormEmailGroupRepo{db: db}
+}
+
+// List returns all email groups.
+func (r *GormEmailGroupRepo) List() ([]*domain.EmailGroup, error) {
+	var gs []gormEmailGroup
+	if err := r.db.Order("name").Find(&gs).Error; err != nil {
+		return nil, err
+	}
+	res := make([]*domain.EmailGroup, 0, len(gs))
+	for _, g := range gs {
+		res = append(res, g.toDomain())
+	}
+	return res, nil
+}
+
+// GetByID returns an email group by id.
+func (r *GormEmailGroupRepo) GetByID(id string) (*domain.EmailGroup, error) {
+	var g gormEmailGroup
+	if err := r.db.First(&g, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return g.toDomain(), nil
+}
+
+// Create inserts a new email group.
+func (r *GormEmailGroupRepo) Create(g *domain.EmailGroup) error {
+	return r.db.Create(emailGroupFromDomain(g)).Error
+}
+
+// Update saves an email group.
+func (r *GormEmailGroupRepo) Update(g *domain.EmailGroup) error {
+	return r.db.Save(emailGroupFromDomain(g)).Error
+}
+
+// Delete removes an email group.
+func (r *GormEmailGroupRepo) Delete(id string) error {
+	return r.db.Delete(&gormEmailGroup{}, "id = ?", id).Error
+}
@@ -0,0 +1,134 @@
+package dbadapter
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"gorm.io/gorm"
+)
+
+// gormEvent is the GORM representation of domain.Event. The payload is kept
+// as a JSON document.
+type gormEvent struct {
+	ID       uint64    `gorm:"primaryKey;autoIncrement"`
+	Type     string    `gorm:"index"`
+	CameraID string    `gorm:"index"`
+	Severity string    `gorm:"index"`
+	Time     time.Time `gorm:"index"`
+	Payload  string
+}
+
+func (g *gormEvent) toDomain() *domain.Event {
+	e := &domain.Event{ID: g.ID, Type: g.Type, CameraID: g.CameraID, Severity: g.Severity, Time: g.Time}
+	if g.Payload != "" {
+		_ = json.Unmarshal([]byte(g.Payload), &e.Payload)
+	}
+	return e
+}
+
+// GormEventRepo stores events via GORM.
+type GormEventRepo struct {
+	db *gorm.DB
+}
+
+// NewGormEventRepo returns an event repository backed by gorm DB.
+func NewGormEventRepo(db *gorm.DB) *GormEventRepo {
+	return &GormEventRepo{db: db}
+}
+
+// Append stores an event and sets its ID.
+func (r *GormEventRepo) Append(e *domain.Event) error {
+	g := &gormEvent{Type: e.Type, CameraID: e.CameraID, Severity: e.Severity, Time: e.Time.UTC()}
+	if len(e.Payload) > 0 {
+		b, err := json.Marshal(e.Payload)
+		if err != nil {
+			return err
+		}
+		g.Payload = string(b)
+	}
+	if err := r.db.Create(g).Error; err != nil {
+		return err
+	}
+	e.ID = g.ID
+	return nil
+}
+
+// List returns events matching the filter, newest first, together with the
+// total number of matches ignoring limit and offset. When AfterID is set the
+// events are returned oldest first instead, which suits replaying a stream.
+func (r *GormEventRepo) List(f domain.EventFilter) ([]*domain.Event, int64, error) {
+	q := r.db.Model(&gormEvent{})
+	if f.CameraID != "" {
+		q = q.Where("camera_id = ?", f.CameraID)
+	}
+	if len(f.CameraIDs) > 0 {
+		q = q.Where("camera_id IN ?", f.CameraIDs)
+	}
+	if len(f.Types) > 0 {
+		q = q.Where("type IN ?", f.Types)
+	}
+	if f.Severity != "" {
+		q = q.Where("severity = ?", f.Severity)
+	}
+	if !f.From.IsZero() {
+		q = q.Where("time >= ?", f.From.UTC())
+	}
+	if !f.To.IsZero() {
+		q = q.Where("time < ?", f.To.UTC())
+	}
+	if f.AfterID > 0 {
+		q = q.Where("id > ?", f.AfterID)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	order := "id DESC"
+	if f.AfterID > 0 {
+		order = "id ASC"
+	}
+	q = q.Order(order)
+	if f.Limit > 0 {
+		q = q.Limit(f.Limit)
+	}
+	if f.Offset > 0 {
+		q = q.Offset(f.Offset)
+	}
+	var gs []gormEvent
+	if err := q.Find(&gs).Error; err != nil {
+		return nil, 0, err
+	}
+	res := make([]*domain.Event, 0, len(gs))
+	for _, g := range gs {
+		res = append(res, g.toDomain())
+	}
+	return res, total, nil
+}
+
+// DeleteBefore removes events older than t, except those whose type starts
+// with one of except, and returns how many were removed.
+func (r *GormEventRepo) DeleteBefore(t time.Time, except []string) (int64, error) {
+	q := r.db.Where("time < ?", t.UTC())
+	for _, p := range except {
+		q = q.Where("type NOT LIKE ?", p+"%")
+	}
+	res := q.Delete(&gormEvent{})
+	return res.RowsAffected, res.Error
+}
+
+// DeleteTypesBefore removes events older than t whose type starts with one
+// of prefixes and returns how many were removed.
+func (r *GormEventRepo) DeleteTypesBefore(t time.Time, prefixes []string) (int64, error) {
+	if len(prefixes) == 0 {
+		return 0, nil
+	}
+	types := r.db.Where("type LIKE ?", prefixes[0]+"%")
+	for _, p := range prefixes[1:] {
+		types = types.Or("type LIKE ?", p+"%")
+	}
+	res := r.db.Where("time < ?", t.UTC()).Where(types).Delete(&gormEvent{})
+	return res.RowsAffected, res.Error
+}
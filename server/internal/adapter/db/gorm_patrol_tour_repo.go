@@ -0,0 +1,103 @@
+package dbadapter
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"gorm.io/gorm"
+)
+
+// gormPatrolTour is the GORM representation of domain.PatrolTour. Steps and
+// Days are kept as JSON documents.
+type gormPatrolTour struct {
+	ID        string `gorm:"primaryKey"`
+	CameraID  string `gorm:"index"`
+	Name      string
+	Steps     string
+	Enabled   bool
+	Days      string
+	StartTime string
+	EndTime   string
+	CreatedBy string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (g *gormPatrolTour) toDomain() *domain.PatrolTour {
+	t := &domain.PatrolTour{ID: g.ID, CameraID: g.CameraID, Name: g.Name, Enabled: g.Enabled, StartTime: g.StartTime, EndTime: g.EndTime, CreatedBy: g.CreatedBy, CreatedAt: g.CreatedAt, UpdatedAt: g.UpdatedAt}
+	if g.Steps != "" {
+		_ = json.Unmarshal([]byte(g.Steps), &t.Steps)
+	}
+	if g.Days != "" {
+		_ = json.Unmarshal([]byte(g.Days), &t.Days)
+	}
+	if t.Steps == nil {
+		t.Steps = []domain.PatrolStep{}
+	}
+	if t.Days == nil {
+		t.Days = []int{}
+	}
+	return t
+}
+
+func patrolTourFromDomain(d *domain.PatrolTour) *gormPatrolTour {
+	return &gormPatrolTour{ID: d.ID, CameraID: d.CameraID, Name: d.Name, Steps: jsonString(d.Steps), Enabled: d.Enabled, Days: jsonString(d.Days), StartTime: d.StartTime, EndTime: d.EndTime, CreatedBy: d.CreatedBy, CreatedAt: d.CreatedAt, UpdatedAt: d.UpdatedAt}
+}
+
+// GormPatrolTourRepo stores PTZ patrol tours via GORM.
+type GormPatrolTourRepo struct {
+	db *gorm.DB
+}
+
+// NewGormPatrolTourRepo returns a patrol tour repository backed by gorm DB.
+func NewGormPatrolTourRepo(db *gorm.DB) *GormPatrolTourRepo {
+	return &GormPatrolTourRepo{db: db}
+}
+
+// List returns the tours of a camera, or of all cameras when cameraID is
+// empty, ordered by creation.
+func (r *GormPatrolTourRepo) List(cameraID string) ([]*domain.PatrolTour, error) {
+	q := r.db.Model(&gormPatrolTour{})
+	if cameraID != "" {
+		q = q.Where("camera_id = ?", cameraID)
+	}
+	var gs []gormPatrolTour
+	if err := q.Order("created_at").Find(&gs).Error; err != nil {
+		return nil, err
+	}
+	res := make([]*domain.PatrolTour, 0, len(gs))
+	for _, g := range gs {
+		res = append(res, g.toDomain())
+	}
+	return res, nil
+}
+
+// GetByID returns a patrol tour by id.
+func (r *GormPatrolTourRepo) GetByID(id string) (*domain.PatrolTour, error) {
+	var g gormPatrolTour
+	if err := r.db.First(&g, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return g.toDomain(), nil
+}
+
+// Create inserts a new patrol tour.
+func (r *GormPatrolTourRepo) Create(t *domain.PatrolTour) error {
+	return r.db.Create(patrolTourFromDomain(t)).Error
+}
+
+// Update saves all fields of an existing patrol tour.
+func (r *GormPatrolTourRepo) Update(t *domain.PatrolTour) error {
+	return r.db.Save(patrolTourFromDomain(t)).Error
+}
+
+// Delete removes a patrol tour by id.
+func (r *GormPatrolTourRepo) Delete(id string) error {
+	return r.db.Delete(&gormPatrolTour{}, "id = ?", id).Error
+}
+
+// DeleteByCamera removes every tour of a camera.
+func (r *GormPatrolTourRepo) DeleteByCamera(cameraID string) error {
+	return r.db.Delete(&gormPatrolTour{}, "camera_id = ?", cameraID).Error
+}
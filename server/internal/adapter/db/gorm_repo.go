@@ -2,8 +2,10 @@ package dbadapter
 
 import (
 	"database/sql"
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/boytur/cctv-recording-center/server/internal/domain"
 	gsqlite "gorm.io/driver/sqlite"
@@ -20,15 +22,53 @@ type gormCamera struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 	Status   string `json:"status"`
+	// Disabled is stored rather than Enabled so existing rows and zero
+	// values mean enabled.
+	Disabled          bool       `json:"disabled"`
+	MaintenanceUntil  *time.Time `json:"maintenance_until"`
+	MaintenanceReason string     `json:"maintenance_reason"`
+	// ProbeIntervalSeconds and ProbeTimeoutSeconds are 0 for the defaults.
+	ProbeIntervalSeconds int        `json:"probe_interval_seconds"`
+	ProbeTimeoutSeconds  int        `json:"probe_timeout_seconds"`
+	LastProbeAt          *time.Time `json:"last_probe_at"`
+	ProbeLatencyMs       *int64     `json:"probe_latency_ms"`
+	DeviceID             string     `gorm:"index" json:"device_id"`
+	Channel              int        `json:"channel"`
+	// Hardware, Stream and StreamChange are kept as JSON documents.
+	Hardware     string `gorm:"column:device" json:"device"`
+	Stream       string `json:"stream"`
+	StreamChange string `json:"stream_change"`
 }
 
 // Ensure mapping between domain and gorm model.
 func (g *gormCamera) toDomain() *domain.Camera {
-	return &domain.Camera{ID: g.ID, Name: g.Name, Location: g.Location, RTSPURL: g.RTSPURL, Username: g.Username, Password: g.Password, Status: g.Status}
+	c := &domain.Camera{ID: g.ID, Name: g.Name, Location: g.Location, RTSPURL: g.RTSPURL, Username: g.Username, Password: g.Password, Status: g.Status,
+		Enabled: !g.Disabled, MaintenanceUntil: g.MaintenanceUntil, MaintenanceReason: g.MaintenanceReason,
+		ProbeIntervalSeconds: g.ProbeIntervalSeconds, ProbeTimeoutSeconds: g.ProbeTimeoutSeconds, LastProbeAt: g.LastProbeAt, ProbeLatencyMs: g.ProbeLatencyMs,
+		DeviceID: g.DeviceID, Channel: g.Channel}
+	if g.Hardware != "" {
+		_ = json.Unmarshal([]byte(g.Hardware), &c.Hardware)
+	}
+	if g.Stream != "" {
+		_ = json.Unmarshal([]byte(g.Stream), &c.Stream)
+	}
+	if g.StreamChange != "" {
+		_ = json.Unmarshal([]byte(g.StreamChange), &c.StreamChange)
+	}
+	return c
 }
 
 func fromDomain(d *domain.Camera) *gormCamera {
-	return &gormCamera{ID: d.ID, Name: d.Name, Location: d.Location, RTSPURL: d.RTSPURL, Username: d.Username, Password: d.Password, Status: d.Status}
+	return &gormCamera{ID: d.ID, Name: d.Name, Location: d.Location, RTSPURL: d.RTSPURL, Username: d.Username, Password: d.Password, Status: d.Status,
+		Disabled: !d.Enabled, MaintenanceUntil: utcPtr(d.MaintenanceUntil), MaintenanceReason: d.MaintenanceReason,
+		ProbeIntervalSeconds: d.ProbeIntervalSeconds, ProbeTimeoutSeconds: d.ProbeTimeoutSeconds, LastProbeAt: utcPtr(d.LastProbeAt), ProbeLatencyMs: d.ProbeLatencyMs,
+		DeviceID: d.DeviceID, Channel: d.Channel, Hardware: jsonString(d.Hardware), Stream: jsonString(d.Stream), StreamChange: jsonString(d.StreamChange)}
+}
+
+// jsonString encodes v as JSON; a nil pointer becomes "null".
+func jsonString(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
 }
 
 // GormCameraRepo implements repository via GORM.
@@ -46,8 +86,10 @@ func NewGormDB(path string) (*gorm.DB, error) {
 		return nil, err
 	}
 	// open database using modernc.org/sqlite driver (pure-Go) and pass the
-	// *sql.DB to GORM using gorm's OpenDB.
-	sqlDB, err := sql.Open("sqlite", path)
+	// *sql.DB to GORM using gorm's OpenDB. The monitor and auto-recorder
+	// work on several cameras at once, so writers wait for the lock instead
+	// of failing with SQLITE_BUSY.
+	sqlDB, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)")
 	if err != nil {
 		return nil, err
 	}
@@ -55,7 +97,7 @@ func NewGormDB(path string) (*gorm.DB, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err := db.AutoMigrate(&gormCamera{}); err != nil {
+	if err := db.AutoMigrate(&gormCamera{}, &gormCapture{}, &gormBookmark{}, &gormEvent{}, &gormWebhook{}, &gormWebhookDelivery{}, &gormAlertSettings{}, &gormAlertMute{}, &gormEmailGroup{}, &gormStatusChange{}, &gormPatrolTour{}, &gormDevice{}); err != nil {
 		return nil, err
 	}
 	// No seed data - cameras will be added via UI
@@ -94,9 +136,52 @@ func (r *GormCameraRepo) Create(c *domain.Camera) error {
 	return r.db.Create(fromDomain(c)).Error
 }
 
-// Update updates an existing camera.
+// Update updates an existing camera's settings and status. Probe results
+// and stream parameters are written by UpdateProbe and UpdateStream so a
+// background probe never overwrites an edit with stale settings.
 func (r *GormCameraRepo) Update(c *domain.Camera) error {
-	return r.db.Model(&gormCamera{}).Where("id = ?", c.ID).Updates(fromDomain(c)).Error
+	return r.db.Model(&gormCamera{}).Where("id = ?", c.ID).
+		Select("name", "location", "rtsp_url", "username", "password", "status", "disabled", "maintenance_until", "maintenance_reason", "probe_interval_seconds", "probe_timeout_seconds", "device", "device_id", "channel").
+		Updates(fromDomain(c)).Error
+}
+
+// UpdateStatus sets a camera's status without touching anything else.
+func (r *GormCameraRepo) UpdateStatus(id, status string) error {
+	return r.db.Model(&gormCamera{}).Where("id = ?", id).Update("status", status).Error
+}
+
+// UpdateMaintenance sets or, with a nil until, clears a camera's
+// maintenance window without touching its status.
+func (r *GormCameraRepo) UpdateMaintenance(id string, until *time.Time, reason string) error {
+	return r.db.Model(&gormCamera{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"maintenance_until":  utcPtr(until),
+		"maintenance_reason": reason,
+	}).Error
+}
+
+// UpdateHardware stores the ONVIF device information of a camera without
+// touching anything else.
+func (r *GormCameraRepo) UpdateHardware(id string, info *domain.DeviceInfo) error {
+	return r.db.Model(&gormCamera{}).Where("id = ?", id).Update("device", jsonString(info)).Error
+}
+
+// UpdateProbe stores the outcome of a health probe. A nil latency means the
+// camera did not answer.
+func (r *GormCameraRepo) UpdateProbe(id, status string, at time.Time, latencyMs *int64) error {
+	return r.db.Model(&gormCamera{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":           status,
+		"last_probe_at":    at.UTC(),
+		"probe_latency_ms": latencyMs,
+	}).Error
+}
+
+// UpdateStream stores the discovered stream parameters and the pending
+// stream change of a camera. A nil change clears it.
+func (r *GormCameraRepo) UpdateStream(id string, stream *domain.StreamInfo, change *domain.StreamChange) error {
+	return r.db.Model(&gormCamera{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"stream":        jsonString(stream),
+		"stream_change": jsonString(change),
+	}).Error
 }
 
 // Delete removes a camera by id.
@@ -0,0 +1,68 @@
+package dbadapter
+
+import (
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"gorm.io/gorm"
+)
+
+// gormStatusChange is the GORM representation of domain.StatusChange.
+type gormStatusChange struct {
+	ID       uint64 `gorm:"primaryKey;autoIncrement"`
+	CameraID string `gorm:"index:idx_status_camera_at"`
+	Status   string
+	Previous string
+	Reason   string
+	At       time.Time `gorm:"index:idx_status_camera_at"`
+}
+
+func (g *gormStatusChange) toDomain() *domain.StatusChange {
+	return &domain.StatusChange{ID: g.ID, CameraID: g.CameraID, Status: g.Status, Previous: g.Previous, Reason: g.Reason, At: g.At}
+}
+
+// GormStatusHistoryRepo stores camera status transitions via GORM.
+type GormStatusHistoryRepo struct {
+	db *gorm.DB
+}
+
+// NewGormStatusHistoryRepo returns a status history repository backed by
+// gorm DB.
+func NewGormStatusHistoryRepo(db *gorm.DB) *GormStatusHistoryRepo {
+	return &GormStatusHistoryRepo{db: db}
+}
+
+// Append stores a transition and sets its ID.
+func (r *GormStatusHistoryRepo) Append(c *domain.StatusChange) error {
+	g := &gormStatusChange{CameraID: c.CameraID, Status: c.Status, Previous: c.Previous, Reason: c.Reason, At: c.At.UTC()}
+	if err := r.db.Create(g).Error; err != nil {
+		return err
+	}
+	c.ID = g.ID
+	return nil
+}
+
+// List returns the transitions of a camera in [from, to), oldest first.
+func (r *GormStatusHistoryRepo) List(cameraID string, from, to time.Time) ([]*domain.StatusChange, error) {
+	var gs []gormStatusChange
+	err := r.db.Where("camera_id = ? AND at >= ? AND at < ?", cameraID, from.UTC(), to.UTC()).
+		Order("at ASC, id ASC").Find(&gs).Error
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*domain.StatusChange, 0, len(gs))
+	for i := range gs {
+		res = append(res, gs[i].toDomain())
+	}
+	return res, nil
+}
+
+// Last returns the latest transition of a camera before t, or nil.
+func (r *GormStatusHistoryRepo) Last(cameraID string, before time.Time) (*domain.StatusChange, error) {
+	var gs []gormStatusChange
+	err := r.db.Where("camera_id = ? AND at < ?", cameraID, before.UTC()).Order("at DESC, id DESC").Limit(1).Find(&gs).Error
+	if err != nil || len(gs) == 0 {
+		return nil, err
+	}
+	return gs[0].toDomain(), nil
+}
@@ -0,0 +1,190 @@
+package dbadapter
+
+import (
+	"strings"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"gorm.io/gorm"
+)
+
+// gormWebhook is the GORM representation of domain.Webhook. Event types and
+// camera ids are stored with joinList, in the same format as bookmark tags.
+type gormWebhook struct {
+	ID         string `gorm:"primaryKey"`
+	Name       string
+	URL        string
+	EventTypes string
+	CameraIDs  string
+	Secret     string
+	Enabled    bool
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (g *gormWebhook) toDomain() *domain.Webhook {
+	return &domain.Webhook{ID: g.ID, Name: g.Name, URL: g.URL, EventTypes: splitList(g.EventTypes), CameraIDs: splitList(g.CameraIDs), Secret: g.Secret, Enabled: g.Enabled, CreatedAt: g.CreatedAt, UpdatedAt: g.UpdatedAt}
+}
+
+func webhookFromDomain(d *domain.Webhook) *gormWebhook {
+	return &gormWebhook{ID: d.ID, Name: d.Name, URL: d.URL, EventTypes: joinList(d.EventTypes), CameraIDs: joinList(d.CameraIDs), Secret: d.Secret, Enabled: d.Enabled, CreatedAt: d.CreatedAt, UpdatedAt: d.UpdatedAt}
+}
+
+// gormWebhookDelivery is the GORM representation of domain.WebhookDelivery.
+type gormWebhookDelivery struct {
+	ID            string `gorm:"primaryKey"`
+	WebhookID     string `gorm:"index"`
+	EventID       uint64
+	EventType     string
+	Body          string
+	Status        string `gorm:"index"`
+	Attempts      int
+	StatusCode    int
+	LastError     string
+	NextAttemptAt *time.Time `gorm:"index"`
+	DeliveredAt   *time.Time
+	CreatedAt     time.Time `gorm:"index"`
+	UpdatedAt     time.Time
+}
+
+func (g *gormWebhookDelivery) toDomain() *domain.WebhookDelivery {
+	return &domain.WebhookDelivery{ID: g.ID, WebhookID: g.WebhookID, EventID: g.EventID, EventType: g.EventType, Body: g.Body, Status: g.Status, Attempts: g.Attempts, StatusCode: g.StatusCode, LastError: g.LastError, NextAttemptAt: g.NextAttemptAt, DeliveredAt: g.DeliveredAt, CreatedAt: g.CreatedAt, UpdatedAt: g.UpdatedAt}
+}
+
+func deliveryFromDomain(d *domain.WebhookDelivery) *gormWebhookDelivery {
+	return &gormWebhookDelivery{ID: d.ID, WebhookID: d.WebhookID, EventID: d.EventID, EventType: d.EventType, Body: d.Body, Status: d.Status, Attempts: d.Attempts, StatusCode: d.StatusCode, LastError: d.LastError, NextAttemptAt: utcPtr(d.NextAttemptAt), DeliveredAt: utcPtr(d.DeliveredAt), CreatedAt: d.CreatedAt, UpdatedAt: d.UpdatedAt}
+}
+
+// joinList stores a list wrapped in commas (",a,b,") so a single item can be
+// matched with LIKE.
+func joinList(list []string) string {
+	if len(list) == 0 {
+		return ""
+	}
+	return "," + strings.Join(list, ",") + ","
+}
+
+// splitList is the inverse of joinList.
+func splitList(s string) []string {
+	list := []string{}
+	for _, v := range strings.Split(strings.Trim(s, ","), ",") {
+		if v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// GormWebhookRepo stores webhooks and their deliveries via GORM.
+type GormWebhookRepo struct {
+	db *gorm.DB
+}
+
+// NewGormWebhookRepo returns a webhook repository backed by gorm DB.
+func NewGormWebhookRepo(db *gorm.DB) *GormWebhookRepo {
+	return &GormWebhookRepo{db: db}
+}
+
+// List returns all webhooks.
+func (r *GormWebhookRepo) List() ([]*domain.Webhook, error) {
+	var gs []gormWebhook
+	if err := r.db.Order("created_at").Find(&gs).Error; err != nil {
+		return nil, err
+	}
+	res := make([]*domain.Webhook, 0, len(gs))
+	for _, g := range gs {
+		res = append(res, g.toDomain())
+	}
+	return res, nil
+}
+
+// GetByID returns a webhook by id.
+func (r *GormWebhookRepo) GetByID(id string) (*domain.Webhook, error) {
+	var g gormWebhook
+	if err := r.db.First(&g, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return g.toDomain(), nil
+}
+
+// Create inserts a new webhook.
+func (r *GormWebhookRepo) Create(w *domain.Webhook) error {
+	return r.db.Create(webhookFromDomain(w)).Error
+}
+
+// Update saves a webhook.
+func (r *GormWebhookRepo) Update(w *domain.Webhook) error {
+	return r.db.Save(webhookFromDomain(w)).Error
+}
+
+// Delete removes a webhook and its delivery log.
+func (r *GormWebhookRepo) Delete(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&gormWebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&gormWebhook{}, "id = ?", id).Error
+	})
+}
+
+// CreateDelivery inserts a new delivery.
+func (r *GormWebhookRepo) CreateDelivery(d *domain.WebhookDelivery) error {
+	return r.db.Create(deliveryFromDomain(d)).Error
+}
+
+// UpdateDelivery saves a delivery.
+func (r *GormWebhookRepo) UpdateDelivery(d *domain.WebhookDelivery) error {
+	return r.db.Save(deliveryFromDomain(d)).Error
+}
+
+// GetDelivery returns a delivery by id.
+func (r *GormWebhookRepo) GetDelivery(id string) (*domain.WebhookDelivery, error) {
+	var g gormWebhookDelivery
+	if err := r.db.First(&g, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return g.toDomain(), nil
+}
+
+// ListDeliveries returns a webhook's deliveries, newest first.
+func (r *GormWebhookRepo) ListDeliveries(webhookID, status string, limit int) ([]*domain.WebhookDelivery, error) {
+	q := r.db.Where("webhook_id = ?", webhookID)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	var gs []gormWebhookDelivery
+	if err := q.Order("created_at DESC").Find(&gs).Error; err != nil {
+		return nil, err
+	}
+	res := make([]*domain.WebhookDelivery, 0, len(gs))
+	for _, g := range gs {
+		res = append(res, g.toDomain())
+	}
+	return res, nil
+}
+
+// DueDeliveries returns deliveries waiting for an attempt at or before t.
+func (r *GormWebhookRepo) DueDeliveries(t time.Time) ([]*domain.WebhookDelivery, error) {
+	var gs []gormWebhookDelivery
+	err := r.db.Where("status IN ? AND next_attempt_at <= ?", []string{domain.DeliveryPending, domain.DeliveryRetrying}, t.UTC()).
+		Order("next_attempt_at").Find(&gs).Error
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*domain.WebhookDelivery, 0, len(gs))
+	for _, g := range gs {
+		res = append(res, g.toDomain())
+	}
+	return res, nil
+}
+
+// DeleteFinishedDeliveries removes succeeded and dead deliveries last updated
+// before t and returns how many were removed.
+func (r *GormWebhookRepo) DeleteFinishedDeliveries(t time.Time) (int64, error) {
+	res := r.db.Where("status IN ? AND updated_at < ?", []string{domain.DeliverySucceeded, domain.DeliveryDead}, t.UTC()).
+		Delete(&gormWebhookDelivery{})
+	return res.RowsAffected, res.Error
+}
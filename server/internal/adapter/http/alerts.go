@@ -0,0 +1,150 @@
+package httpadapter
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+func alertSettingsJSON(s *domain.AlertSettings) gin.H {
+	return gin.H{
+		"enabled":              s.Enabled,
+		"grace_period_seconds": int(s.GracePeriod / time.Second),
+		"updated_at":           s.UpdatedAt,
+	}
+}
+
+// Alerts handles GET /api/alerts and lists the cameras that are currently
+// offline, with whether an alert has been sent for them yet.
+func (h *Handler) Alerts(c *gin.Context) {
+	c.JSON(http.StatusOK, h.alerts.Outages())
+}
+
+// AlertSettings handles GET /api/alerts/settings
+func (h *Handler) AlertSettings(c *gin.Context) {
+	s, err := h.alerts.GetSettings()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	c.JSON(http.StatusOK, alertSettingsJSON(s))
+}
+
+// UpdateAlertSettings handles PUT /api/alerts/settings. The body may set
+// `enabled` and `grace_period_seconds`.
+func (h *Handler) UpdateAlertSettings(c *gin.Context) {
+	var payload struct {
+		Enabled            *bool `json:"enabled"`
+		GracePeriodSeconds *int  `json:"grace_period_seconds"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+		return
+	}
+	var grace *time.Duration
+	if payload.GracePeriodSeconds != nil {
+		d := time.Duration(*payload.GracePeriodSeconds) * time.Second
+		grace = &d
+	}
+	s, err := h.alerts.UpdateSettings(payload.Enabled, grace)
+	if errors.Is(err, usecase.ErrInvalidAlertSettings) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update"})
+		return
+	}
+	c.JSON(http.StatusOK, alertSettingsJSON(s))
+}
+
+// ListAlertMutes handles GET /api/alerts/mutes. Optional filters: cameraId,
+// and all=true to include windows that are over.
+func (h *Handler) ListAlertMutes(c *gin.Context) {
+	mutes, err := h.alerts.ListMutes(c.Query("cameraId"), c.Query("all") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	c.JSON(http.StatusOK, mutes)
+}
+
+// CreateAlertMute handles POST /api/alerts/mutes. The body gives the
+// `camera_id`, an optional RFC3339 `start` (default now) and either an
+// RFC3339 `end` or `duration_minutes`, plus an optional `reason`.
+func (h *Handler) CreateAlertMute(c *gin.Context) {
+	var payload struct {
+		CameraID        string `json:"camera_id"`
+		Start           string `json:"start"`
+		End             string `json:"end"`
+		DurationMinutes int    `json:"duration_minutes"`
+		Reason          string `json:"reason"`
+		CreatedBy       string `json:"created_by"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+		return
+	}
+	dto := &usecase.MuteDTO{CameraID: payload.CameraID, Reason: payload.Reason, CreatedBy: payload.CreatedBy}
+	if dto.CreatedBy == "" {
+		dto.CreatedBy = c.GetHeader("X-Operator")
+	}
+	if payload.Start != "" {
+		t, err := parseTimeParam(payload.Start)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start, use RFC3339"})
+			return
+		}
+		dto.Start = t
+	}
+	switch {
+	case payload.End != "":
+		t, err := parseTimeParam(payload.End)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end, use RFC3339"})
+			return
+		}
+		dto.End = t
+	case payload.DurationMinutes > 0:
+		start := dto.Start
+		if start.IsZero() {
+			start = time.Now()
+		}
+		dto.End = start.Add(time.Duration(payload.DurationMinutes) * time.Minute)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing end or duration_minutes"})
+		return
+	}
+
+	m, err := h.alerts.CreateMute(dto)
+	switch {
+	case errors.Is(err, usecase.ErrCameraNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "camera not found"})
+		return
+	case errors.Is(err, usecase.ErrInvalidAlertSettings):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create"})
+		return
+	}
+	c.JSON(http.StatusCreated, m)
+}
+
+// DeleteAlertMute handles DELETE /api/alerts/mutes/{id}
+func (h *Handler) DeleteAlertMute(c *gin.Context) {
+	err := h.alerts.DeleteMute(c.Param("id"))
+	if errors.Is(err, usecase.ErrMuteNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,146 @@
+package httpadapter
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultAvailabilityRange is used when a request gives neither a month nor
+// a from/to range.
+const defaultAvailabilityRange = 7 * 24 * time.Hour
+
+// StatusHistory handles GET /api/cameras/{id}/status-history and lists the
+// camera's status transitions, oldest first. Accepts the same range
+// parameters as CameraAvailability.
+func (h *Handler) StatusHistory(c *gin.Context) {
+	from, to, err := availabilityRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	list, err := h.availability.StatusHistory(c.Param("id"), from, to)
+	if !availabilityError(c, err) {
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+// CameraAvailability handles GET /api/cameras/{id}/availability. The range is
+// given as `month` (YYYY-MM) or `from` and `to`, and defaults to the last
+// seven days.
+func (h *Handler) CameraAvailability(c *gin.Context) {
+	from, to, err := availabilityRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	a, err := h.availability.CameraAvailability(c.Param("id"), from, to)
+	if !availabilityError(c, err) {
+		c.JSON(http.StatusOK, a)
+	}
+}
+
+// AvailabilityReport handles GET /api/reports/availability, the availability
+// of every camera over a range (see CameraAvailability). `sla` checks each
+// camera against an uptime percentage and `format=csv` returns one row per
+// camera for spreadsheets.
+func (h *Handler) AvailabilityReport(c *gin.Context) {
+	from, to, err := availabilityRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var sla *float64
+	if v := c.Query("sla"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sla"})
+			return
+		}
+		sla = &f
+	}
+	report, err := h.availability.FleetReport(from, to, sla)
+	if availabilityError(c, err) {
+		return
+	}
+	if c.Query("format") != "csv" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	name := fmt.Sprintf("availability_%s_%s.csv", from.Format("20060102"), to.Format("20060102"))
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	w := csv.NewWriter(c.Writer)
+	header := []string{"camera_id", "camera_name", "uptime_percent", "monitored_seconds", "downtime_seconds", "outages", "mttr_seconds"}
+	if sla != nil {
+		header = append(header, "sla_target", "compliant")
+	}
+	w.Write(header)
+	for _, a := range report.Cameras {
+		row := []string{
+			a.CameraID,
+			a.CameraName,
+			strconv.FormatFloat(a.UptimePercent, 'f', 3, 64),
+			strconv.FormatInt(a.MonitoredSeconds, 10),
+			strconv.FormatInt(a.DowntimeSeconds, 10),
+			strconv.Itoa(a.OutageCount),
+			strconv.FormatInt(a.MTTRSeconds, 10),
+		}
+		if sla != nil {
+			row = append(row, strconv.FormatFloat(*sla, 'f', -1, 64), strconv.FormatBool(*a.Compliant))
+		}
+		w.Write(row)
+	}
+	w.Flush()
+}
+
+// availabilityRange reads `month` (YYYY-MM, local time) or `from`/`to`.
+func availabilityRange(c *gin.Context) (time.Time, time.Time, error) {
+	if v := c.Query("month"); v != "" {
+		m, err := time.ParseInLocation("2006-01", v, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid month, use YYYY-MM")
+		}
+		return m, m.AddDate(0, 1, 0), nil
+	}
+	to := time.Now()
+	if v := c.Query("to"); v != "" {
+		t, err := parseTimeParam(v)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid to, use RFC3339")
+		}
+		to = t
+	}
+	from := to.Add(-defaultAvailabilityRange)
+	if v := c.Query("from"); v != "" {
+		t, err := parseTimeParam(v)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid from, use RFC3339")
+		}
+		from = t
+	}
+	return from, to, nil
+}
+
+// availabilityError writes the response for a usecase error and reports
+// whether there was one.
+func availabilityError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, usecase.ErrCameraNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "camera not found"})
+	case errors.Is(err, usecase.ErrInvalidRange):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+	return true
+}
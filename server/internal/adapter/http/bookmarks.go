@@ -0,0 +1,240 @@
+package httpadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/export"
+	"github.com/boytur/cctv-recording-center/server/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type bookmarkPayload struct {
+	CameraID    string   `json:"camera_id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Time        string   `json:"time"`
+	EndTime     string   `json:"end_time"`
+	CreatedBy   string   `json:"created_by"`
+}
+
+// toDTO parses the payload timestamps. Empty timestamps are left zero.
+func (p *bookmarkPayload) toDTO() (*usecase.BookmarkDTO, error) {
+	dto := &usecase.BookmarkDTO{
+		CameraID:    p.CameraID,
+		Title:       p.Title,
+		Description: p.Description,
+		Tags:        p.Tags,
+		CreatedBy:   p.CreatedBy,
+	}
+	if p.Time != "" {
+		t, err := parseTimeParam(p.Time)
+		if err != nil {
+			return nil, errors.New("invalid time, use RFC3339")
+		}
+		dto.Time = t
+	}
+	if p.EndTime != "" {
+		t, err := parseTimeParam(p.EndTime)
+		if err != nil {
+			return nil, errors.New("invalid end_time, use RFC3339")
+		}
+		dto.EndTime = &t
+	}
+	return dto, nil
+}
+
+// ListBookmarks handles GET /api/bookmarks. Supported filters: cameraId,
+// from, to, q (title/description search), tag and limit.
+func (h *Handler) ListBookmarks(c *gin.Context) {
+	f := domain.BookmarkFilter{
+		CameraID: c.Query("cameraId"),
+		Query:    c.Query("q"),
+		Tag:      c.Query("tag"),
+	}
+	if v := c.Query("from"); v != "" {
+		t, err := parseTimeParam(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from, use RFC3339"})
+			return
+		}
+		f.From = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := parseTimeParam(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to, use RFC3339"})
+			return
+		}
+		f.To = t
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		f.Limit = n
+	}
+	bookmarks, err := h.bookmarks.ListBookmarks(f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	c.JSON(http.StatusOK, bookmarks)
+}
+
+// GetBookmark handles GET /api/bookmarks/{id}
+func (h *Handler) GetBookmark(c *gin.Context) {
+	b, err := h.bookmarks.GetBookmark(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+// CreateBookmark handles POST /api/bookmarks
+func (h *Handler) CreateBookmark(c *gin.Context) {
+	var payload bookmarkPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+		return
+	}
+	dto, err := payload.toDTO()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if dto.CreatedBy == "" {
+		dto.CreatedBy = c.GetHeader("X-Operator")
+	}
+	created, err := h.bookmarks.CreateBookmark(dto)
+	switch {
+	case errors.Is(err, usecase.ErrCameraNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "camera not found"})
+		return
+	case errors.Is(err, usecase.ErrInvalidBookmark):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create"})
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// UpdateBookmark handles PUT /api/bookmarks/{id}
+func (h *Handler) UpdateBookmark(c *gin.Context) {
+	var payload bookmarkPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+		return
+	}
+	dto, err := payload.toDTO()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	dto.ID = c.Param("id")
+	updated, err := h.bookmarks.UpdateBookmark(dto)
+	switch {
+	case errors.Is(err, usecase.ErrBookmarkNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	case errors.Is(err, usecase.ErrInvalidBookmark):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update"})
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteBookmark handles DELETE /api/bookmarks/{id}
+func (h *Handler) DeleteBookmark(c *gin.Context) {
+	if err := h.bookmarks.DeleteBookmark(c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ExportClip handles POST /api/export. The body gives either `camera_id`,
+// `from` and `to`, or a `bookmark_id` whose span is exported.
+func (h *Handler) ExportClip(c *gin.Context) {
+	var payload struct {
+		CameraID   string `json:"camera_id"`
+		From       string `json:"from"`
+		To         string `json:"to"`
+		BookmarkID string `json:"bookmark_id"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+		return
+	}
+
+	var cameraID string
+	var from, to time.Time
+	if payload.BookmarkID != "" {
+		b, err := h.bookmarks.GetBookmark(payload.BookmarkID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "bookmark not found"})
+			return
+		}
+		cameraID = b.CameraID
+		from, to = h.bookmarks.Span(b)
+	} else {
+		var err error
+		cameraID = payload.CameraID
+		if from, err = parseTimeParam(payload.From); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from, use RFC3339"})
+			return
+		}
+		if to, err = parseTimeParam(payload.To); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to, use RFC3339"})
+			return
+		}
+	}
+	if cameraID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing camera_id"})
+		return
+	}
+	// the id names the recordings directory and the clip file, so only a
+	// known camera's id is used
+	cam, err := h.uc.GetCamera(cameraID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "camera not found"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
+	defer cancel()
+	clip, err := export.Export(ctx, cam.ID, from, to)
+	if errors.Is(err, export.ErrNothingRecorded) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no recordings in range"})
+		return
+	}
+	if errors.Is(err, export.ErrInvalidRange) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to export clip: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"camera_id":     clip.CameraID,
+		"from":          clip.From.Format(time.RFC3339),
+		"to":            clip.To.Format(time.RFC3339),
+		"url":           clip.URL(),
+		"fileSizeBytes": clip.Size,
+	})
+}
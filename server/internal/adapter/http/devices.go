@@ -0,0 +1,143 @@
+package httpadapter
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/boytur/cctv-recording-center/server/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type devicePayload struct {
+	Name        string `json:"name"`
+	Location    string `json:"location"`
+	Vendor      string `json:"vendor"`
+	Host        string `json:"host"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	URLTemplate string `json:"url_template"`
+	Stream      string `json:"stream"`
+	// Channels lists the channel numbers to create. ChannelCount is a
+	// shorthand for channels 1 to ChannelCount.
+	Channels     []int `json:"channels"`
+	ChannelCount int   `json:"channel_count"`
+}
+
+func (p *devicePayload) channels() []int {
+	if len(p.Channels) > 0 || p.ChannelCount <= 0 {
+		return p.Channels
+	}
+	if p.ChannelCount > usecase.MaxDeviceChannel {
+		// left for the usecase to reject
+		return []int{p.ChannelCount}
+	}
+	chs := make([]int, p.ChannelCount)
+	for i := range chs {
+		chs[i] = i + 1
+	}
+	return chs
+}
+
+func (p *devicePayload) toDTO() *usecase.DeviceDTO {
+	return &usecase.DeviceDTO{
+		Name:        p.Name,
+		Location:    p.Location,
+		Vendor:      p.Vendor,
+		Host:        p.Host,
+		Username:    p.Username,
+		Password:    p.Password,
+		URLTemplate: p.URLTemplate,
+		Stream:      p.Stream,
+		Channels:    p.channels(),
+	}
+}
+
+// ListDevices handles GET /api/devices
+func (h *Handler) ListDevices(c *gin.Context) {
+	devices, err := h.devices.ListDevices()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	c.JSON(http.StatusOK, devices)
+}
+
+// GetDevice handles GET /api/devices/{id} and returns the device with its
+// channel cameras and health.
+func (h *Handler) GetDevice(c *gin.Context) {
+	d, err := h.devices.GetDevice(c.Param("id"))
+	if !deviceError(c, err) {
+		c.JSON(http.StatusOK, d)
+	}
+}
+
+// CreateDevice handles POST /api/devices. The device's channels are created
+// as cameras from `channels` or `channel_count`.
+func (h *Handler) CreateDevice(c *gin.Context) {
+	var payload devicePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+		return
+	}
+	d, err := h.devices.CreateDevice(payload.toDTO())
+	if !deviceError(c, err) {
+		c.JSON(http.StatusCreated, d)
+	}
+}
+
+// UpdateDevice handles PUT /api/devices/{id}. Host, URL template, stream and
+// credential changes are applied to every channel.
+func (h *Handler) UpdateDevice(c *gin.Context) {
+	var payload devicePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+		return
+	}
+	dto := payload.toDTO()
+	dto.ID = c.Param("id")
+	d, err := h.devices.UpdateDevice(dto)
+	if !deviceError(c, err) {
+		c.JSON(http.StatusOK, d)
+	}
+}
+
+// AddDeviceChannels handles POST /api/devices/{id}/channels with `channels`
+// or `channel_count`, and returns the channels added.
+func (h *Handler) AddDeviceChannels(c *gin.Context) {
+	var payload devicePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+		return
+	}
+	d, err := h.devices.AddChannels(c.Param("id"), payload.channels())
+	if !deviceError(c, err) {
+		c.JSON(http.StatusCreated, d)
+	}
+}
+
+// DeleteDevice handles DELETE /api/devices/{id} and deletes its channel
+// cameras too. `footage` is keep (the default), archive or purge.
+func (h *Handler) DeleteDevice(c *gin.Context) {
+	err := h.devices.DeleteDevice(c.Param("id"), usecase.FootageAction(c.Query("footage")))
+	if !deviceError(c, err) {
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// deviceError writes the response for a device usecase error and reports
+// whether there was one.
+func deviceError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, usecase.ErrDeviceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "device not found"})
+	case errors.Is(err, usecase.ErrInvalidDevice), errors.Is(err, usecase.ErrInvalidCamera):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrFootageNotRemoved):
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "deleted": true})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+package httpadapter
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultDiscoveryTimeout is how long discovery listens for answers unless
+// `timeout` says otherwise.
+const defaultDiscoveryTimeout = 3 * time.Second
+
+// DiscoverONVIF handles GET /api/discovery/onvif. It sends a WS-Discovery
+// probe on the local network and lists the ONVIF devices that answer within
+// `timeout` seconds (1 to 10, default 3), marking those already configured.
+func (h *Handler) DiscoverONVIF(c *gin.Context) {
+	timeout := defaultDiscoveryTimeout
+	if v := c.Query("timeout"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 10 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "timeout must be between 1 and 10 seconds"})
+			return
+		}
+		timeout = time.Duration(n) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+	devices, err := h.discovery.Discover(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, devices)
+}
@@ -0,0 +1,132 @@
+package httpadapter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type emailGroupPayload struct {
+	Name           string   `json:"name"`
+	Recipients     []string `json:"recipients"`
+	CameraIDs      []string `json:"camera_ids"`
+	Alerts         *bool    `json:"alerts"`
+	Digest         *bool    `json:"digest"`
+	AttachSnapshot *bool    `json:"attach_snapshot"`
+}
+
+func (p *emailGroupPayload) toDTO() *usecase.EmailGroupDTO {
+	return &usecase.EmailGroupDTO{
+		Name:           p.Name,
+		Recipients:     p.Recipients,
+		CameraIDs:      p.CameraIDs,
+		Alerts:         p.Alerts,
+		Digest:         p.Digest,
+		AttachSnapshot: p.AttachSnapshot,
+	}
+}
+
+// ListEmailGroups handles GET /api/email-groups
+func (h *Handler) ListEmailGroups(c *gin.Context) {
+	groups, err := h.emailGroups.ListGroups()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	c.JSON(http.StatusOK, groups)
+}
+
+// GetEmailGroup handles GET /api/email-groups/{id}
+func (h *Handler) GetEmailGroup(c *gin.Context) {
+	g, err := h.emailGroups.GetGroup(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, g)
+}
+
+// CreateEmailGroup handles POST /api/email-groups
+func (h *Handler) CreateEmailGroup(c *gin.Context) {
+	var payload emailGroupPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+		return
+	}
+	created, err := h.emailGroups.CreateGroup(payload.toDTO())
+	switch {
+	case errors.Is(err, usecase.ErrInvalidEmailGroup):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create"})
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// UpdateEmailGroup handles PUT /api/email-groups/{id}
+func (h *Handler) UpdateEmailGroup(c *gin.Context) {
+	var payload emailGroupPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+		return
+	}
+	dto := payload.toDTO()
+	dto.ID = c.Param("id")
+	updated, err := h.emailGroups.UpdateGroup(dto)
+	switch {
+	case errors.Is(err, usecase.ErrEmailGroupNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	case errors.Is(err, usecase.ErrInvalidEmailGroup):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update"})
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteEmailGroup handles DELETE /api/email-groups/{id}
+func (h *Handler) DeleteEmailGroup(c *gin.Context) {
+	if err := h.emailGroups.DeleteGroup(c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// TestEmailGroup handles POST /api/email-groups/{id}/test
+func (h *Handler) TestEmailGroup(c *gin.Context) {
+	h.sendEmail(c, h.emailGroups.SendTest)
+}
+
+// SendEmailDigest handles POST /api/email-groups/{id}/digest and sends the
+// group's digest immediately.
+func (h *Handler) SendEmailDigest(c *gin.Context) {
+	h.sendEmail(c, h.emailGroups.SendDigest)
+}
+
+func (h *Handler) sendEmail(c *gin.Context, send func(ctx context.Context, id string) error) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+	defer cancel()
+	err := send(ctx, c.Param("id"))
+	switch {
+	case errors.Is(err, usecase.ErrEmailGroupNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	case errors.Is(err, usecase.ErrEmailDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to send: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sent": true})
+}
@@ -0,0 +1,210 @@
+package httpadapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/events"
+	"github.com/gin-gonic/gin"
+)
+
+// sseHeartbeat is how often an idle event stream sends a comment line so
+// proxies and browsers don't drop the connection.
+const sseHeartbeat = 15 * time.Second
+
+// sseRetry is the reconnect delay suggested to EventSource clients.
+const sseRetry = 3 * time.Second
+
+// sseReplayTruncated is the SSE event telling a reconnecting client that only
+// the most recent of the events it missed are replayed. It is not a bus event.
+const sseReplayTruncated = "replay.truncated"
+
+// ListEvents handles GET /api/events. Supported filters: cameraId, type
+// (comma separated), severity, from, to, limit and offset. Events are
+// returned newest first.
+func (h *Handler) ListEvents(c *gin.Context) {
+	f := domain.EventFilter{
+		CameraID: c.Query("cameraId"),
+		Severity: c.Query("severity"),
+	}
+	f.Types = splitList(c.Query("type"))
+	if v := c.Query("from"); v != "" {
+		t, err := parseTimeParam(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from, use RFC3339"})
+			return
+		}
+		f.From = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := parseTimeParam(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to, use RFC3339"})
+			return
+		}
+		f.To = t
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		f.Limit = n
+	}
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+			return
+		}
+		f.Offset = n
+	}
+
+	list, total, err := h.events.ListEvents(f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"events": list,
+		"total":  total,
+		"limit":  f.Limit,
+		"offset": f.Offset,
+	})
+}
+
+// StreamEvents handles GET /api/events/stream as Server-Sent Events. Each
+// event is sent with its stored id as the SSE id, its type as the SSE event
+// name and the JSON event as data. Optional filters: cameraId and type (both
+// comma separated). A reconnecting client sending Last-Event-ID (or
+// ?lastEventId=) first receives the stored events it missed, at most the
+// last 1000. When there were more it is first sent a replay.truncated event
+// with the number missed and replayed, and should reload its state.
+func (h *Handler) StreamEvents(c *gin.Context) {
+	cameras := splitList(c.Query("cameraId"))
+	types := splitList(c.Query("type"))
+	filter := func(e domain.Event) bool {
+		return contains(cameras, e.CameraID) && contains(types, e.Type)
+	}
+
+	var lastID uint64
+	if v := c.GetHeader("Last-Event-ID"); v != "" {
+		lastID, _ = strconv.ParseUint(v, 10, 64)
+	} else if v := c.Query("lastEventId"); v != "" {
+		lastID, _ = strconv.ParseUint(v, 10, 64)
+	}
+
+	// Subscribe before replaying so nothing published in between is lost;
+	// live events already covered by the replay are skipped by id.
+	live, cancel := events.Subscribe(64, filter)
+	defer cancel()
+
+	var replay []*domain.Event
+	var missed int64
+	if lastID > 0 {
+		var err error
+		replay, missed, err = h.events.EventsAfter(lastID, domain.EventFilter{CameraIDs: cameras, Types: types})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			return
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry.Milliseconds())
+	if missed > int64(len(replay)) {
+		// no id, so a reconnect still resumes from the last event sent
+		data, _ := json.Marshal(gin.H{"missed": missed, "replayed": len(replay)})
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", sseReplayTruncated, data); err != nil {
+			return
+		}
+	}
+	for _, e := range replay {
+		if err := writeSSE(w, *e); err != nil {
+			return
+		}
+		lastID = e.ID
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case e, ok := <-live:
+			if !ok {
+				return
+			}
+			if e.ID != 0 && e.ID <= lastID {
+				continue
+			}
+			if err := writeSSE(w, e); err != nil {
+				return
+			}
+			if e.ID != 0 {
+				lastID = e.ID
+			}
+			w.Flush()
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			w.Flush()
+		}
+	}
+}
+
+func writeSSE(w io.Writer, e domain.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("events: failed to encode %s event: %v", e.Type, err)
+		return nil
+	}
+	if e.ID != 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", e.ID); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+	return err
+}
+
+// splitList splits a comma separated query value, dropping empty items.
+func splitList(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// contains reports whether v is in list; an empty list matches everything.
+func contains(list []string, v string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
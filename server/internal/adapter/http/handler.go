@@ -1,26 +1,41 @@
 package httpadapter
 
 import (
+	"errors"
 	"fmt"
-	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
-	"strings"
+	"strconv"
 	"time"
 
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
 	"github.com/boytur/cctv-recording-center/server/internal/recorder"
+	"github.com/boytur/cctv-recording-center/server/internal/segment"
+	"github.com/boytur/cctv-recording-center/server/internal/snapshot"
 	"github.com/boytur/cctv-recording-center/server/internal/stream"
 	"github.com/boytur/cctv-recording-center/server/internal/usecase"
 	"github.com/gin-gonic/gin"
 )
 
 type Handler struct {
-	uc *usecase.CameraUsecase
+	uc        *usecase.CameraUsecase
+	captures  *usecase.CaptureUsecase
+	bookmarks *usecase.BookmarkUsecase
+	events    *usecase.EventUsecase
+	webhooks  *usecase.WebhookUsecase
+	alerts    *usecase.AlertUsecase
+
+	emailGroups  *usecase.EmailGroupUsecase
+	availability *usecase.AvailabilityUsecase
+	discovery    *usecase.DiscoveryUsecase
+	onvif        *usecase.ONVIFUsecase
+	ptz          *usecase.PTZUsecase
+	devices      *usecase.DeviceUsecase
 }
 
-func NewHandler(uc *usecase.CameraUsecase) *Handler {
-	return &Handler{uc: uc}
+func NewHandler(uc *usecase.CameraUsecase, captures *usecase.CaptureUsecase, bookmarks *usecase.BookmarkUsecase, events *usecase.EventUsecase, webhooks *usecase.WebhookUsecase, alerts *usecase.AlertUsecase, emailGroups *usecase.EmailGroupUsecase, availability *usecase.AvailabilityUsecase, discovery *usecase.DiscoveryUsecase, onvif *usecase.ONVIFUsecase, ptz *usecase.PTZUsecase, devices *usecase.DeviceUsecase) *Handler {
+	return &Handler{uc: uc, captures: captures, bookmarks: bookmarks, events: events, webhooks: webhooks, alerts: alerts, emailGroups: emailGroups, availability: availability, discovery: discovery, onvif: onvif, ptz: ptz, devices: devices}
 }
 
 func (h *Handler) Health(c *gin.Context) {
@@ -47,98 +62,60 @@ func (h *Handler) Recordings(c *gin.Context) {
 	}
 
 	// Parse date
-	targetDate, err := time.Parse("2006-01-02", date)
+	targetDate, err := time.ParseInLocation("2006-01-02", date, time.Local)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format, use YYYY-MM-DD"})
 		return
 	}
 
-	// Build path to recordings directory
-	recordingsPath := filepath.Join("data", "recordings", cameraId, targetDate.Format("2006-01-02"))
-
-	// Check if directory exists
-	if _, err := os.Stat(recordingsPath); os.IsNotExist(err) {
-		// No recordings for this date
-		c.JSON(http.StatusOK, []map[string]interface{}{})
+	// Only show manual captures (capture_*.mp4), not auto-recordings (rec_*.mp4)
+	segs, err := segment.List(cameraId, targetDate, segment.KindCapture)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read recordings"})
 		return
 	}
 
-	// Read all MP4 files from the directory
-	// Filter: only show manual captures (capture_*.mp4), not auto-recordings (rec_*.mp4)
-	recordings := []map[string]interface{}{}
-	err = filepath.WalkDir(recordingsPath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if d.IsDir() {
-			return nil
-		}
-		if !strings.HasSuffix(strings.ToLower(d.Name()), ".mp4") {
-			return nil
-		}
-
-		name := d.Name()
-		// Skip auto-recordings (rec_*.mp4) - only show manual captures
-		if strings.HasPrefix(name, "rec_") {
-			return nil
-		}
-
-		info, err := d.Info()
-		if err != nil {
-			return nil
-		}
-
-		fileSize := info.Size()
-
-		// Skip files that are 0 bytes (corrupted or still being written)
-		if fileSize == 0 {
-			return nil
-		}
-
-		// Extract timestamp from filename if possible
-		// Format: capture_YYYYMMDD_HHMMSS.mp4
-		var startTime time.Time
-		if strings.HasPrefix(name, "capture_") && len(name) >= 23 {
-			timeStr := name[8:23] // YYYYMMDD_HHMMSS
-			if t, err := time.Parse("20060102_150405", timeStr); err == nil {
-				startTime = t
-			}
-		}
-		if startTime.IsZero() {
-			startTime = info.ModTime()
-		}
-
-		// Calculate relative path for URL
-		relPath := strings.TrimPrefix(path, "data/")
-		relPath = strings.ReplaceAll(relPath, "\\", "/")
-
-		fileSizeMB := float64(fileSize) / (1024 * 1024)
+	// Operator and note are kept in the database, keyed by file name
+	captures, err := h.captures.ListCaptures(cameraId, targetDate, targetDate.AddDate(0, 0, 1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read recordings"})
+		return
+	}
+	byFile := make(map[string]*domain.Capture, len(captures))
+	for _, cp := range captures {
+		byFile[cp.FileName] = cp
+	}
 
-		recordings = append(recordings, map[string]interface{}{
-			"id":            filepath.Base(path),
+	recordings := make([]map[string]interface{}, 0, len(segs))
+	for _, s := range segs {
+		fileSizeMB := float64(s.Size) / (1024 * 1024)
+		rec := map[string]interface{}{
+			"id":            s.Name,
 			"cameraId":      cameraId,
 			"cameraName":    cameraId,
-			"startTime":     startTime.Format(time.RFC3339),
-			"duration":      600, // Approximate, could parse from file metadata
+			"startTime":     s.Start.Format(time.RFC3339),
+			"endTime":       s.End.Format(time.RFC3339),
+			"duration":      int(s.Duration().Seconds()),
 			"fileSize":      fmt.Sprintf("%.2f MB", fileSizeMB),
-			"fileSizeBytes": fileSize,
-			"url":           "/" + relPath,
+			"fileSizeBytes": s.Size,
+			"url":           s.URL(),
 			"thumbnailUrl":  "/placeholder.svg",
 			"type":          "manual",
-		})
-
-		return nil
-	})
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read recordings"})
-		return
+		}
+		if cp, ok := byFile[s.Name]; ok {
+			rec["captureId"] = cp.ID
+			rec["operator"] = cp.Operator
+			rec["note"] = cp.Note
+		}
+		recordings = append(recordings, rec)
 	}
 
 	c.JSON(http.StatusOK, recordings)
 }
 
-// Timeline returns per-hour segments indicating if a recording exists.
+// Timeline returns the continuous recorded spans, the gaps between them and
+// the bookmarks for a camera on a given day. When `bucket` (seconds) is
+// given, the day is also summarised into fixed-size slots for the UI bar.
 func (h *Handler) Timeline(c *gin.Context) {
 	cameraId := c.Query("cameraId")
 	date := c.Query("date")
@@ -148,58 +125,86 @@ func (h *Handler) Timeline(c *gin.Context) {
 	}
 
 	// Parse date
-	targetDate, err := time.Parse("2006-01-02", date)
+	dayStart, err := time.ParseInLocation("2006-01-02", date, time.Local)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format"})
 		return
 	}
+	dayEnd := dayStart.AddDate(0, 0, 1)
 
-	// Build path to recordings directory
-	recordingsPath := filepath.Join("data", "recordings", cameraId, targetDate.Format("2006-01-02"))
+	var bucketSize time.Duration
+	if b := c.Query("bucket"); b != "" {
+		secs, err := strconv.Atoi(b)
+		if err != nil || secs <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid bucket, use a number of seconds"})
+			return
+		}
+		bucketSize = time.Duration(secs) * time.Second
+	}
 
-	// Check if directory exists
-	if _, err := os.Stat(recordingsPath); os.IsNotExist(err) {
-		c.JSON(http.StatusOK, []map[string]interface{}{})
+	segs, err := segment.Range(cameraId, dayStart, dayEnd, segment.KindContinuous)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read recordings"})
 		return
 	}
 
-	// Create hour map to track which hours have recordings
-	hourMap := make(map[int]bool)
+	spans := segment.Clip(segment.Merge(segs, 2*time.Second), dayStart, dayEnd)
+	gapEnd := dayEnd
+	if now := time.Now(); now.Before(dayEnd) {
+		// don't report the future as a gap
+		gapEnd = now
+	}
+	gaps := segment.Gaps(spans, dayStart, gapEnd)
 
-	// Read auto-recording files to determine timeline
-	filepath.WalkDir(recordingsPath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil || d.IsDir() {
-			return nil
-		}
-		name := d.Name()
-		if !strings.HasPrefix(name, "rec_") || !strings.HasSuffix(strings.ToLower(name), ".mp4") {
-			return nil
-		}
+	var recorded time.Duration
+	for _, s := range spans {
+		recorded += s.Duration()
+	}
 
-		info, _ := d.Info()
-		if info != nil && info.Size() > 0 {
-			// Extract hour from filename
-			if len(name) >= 19 {
-				timeStr := name[4:19]
-				if t, err := time.Parse("20060102_150405", timeStr); err == nil {
-					hourMap[t.Hour()] = true
-				}
-			}
+	bookmarks, err := h.bookmarks.ListBookmarks(domain.BookmarkFilter{CameraID: cameraId, From: dayStart, To: dayEnd})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read bookmarks"})
+		return
+	}
+
+	resp := gin.H{
+		"cameraId":        cameraId,
+		"date":            dayStart.Format("2006-01-02"),
+		"spans":           spansJSON(spans),
+		"gaps":            spansJSON(gaps),
+		"recordedSeconds": int(recorded.Seconds()),
+		"bookmarks":       bookmarks,
+	}
+	if bucketSize > 0 {
+		buckets := []map[string]interface{}{}
+		for _, b := range segment.Buckets(spans, dayStart, dayEnd, bucketSize) {
+			length := b.End.Sub(b.Start)
+			buckets = append(buckets, map[string]interface{}{
+				"startTime":    b.Start.Format("15:04:05"),
+				"endTime":      b.End.Format("15:04:05"),
+				"duration":     int(length.Seconds()),
+				"recorded":     int(b.Covered.Seconds()),
+				"coverage":     b.Covered.Seconds() / length.Seconds(),
+				"hasRecording": b.Covered > 0,
+			})
 		}
-		return nil
-	})
+		resp["bucketSize"] = int(bucketSize.Seconds())
+		resp["buckets"] = buckets
+	}
+	c.JSON(http.StatusOK, resp)
+}
 
-	// Build timeline segments
-	segments := []map[string]interface{}{}
-	for hour := 0; hour < 24; hour++ {
-		segments = append(segments, map[string]interface{}{
-			"startTime":    fmt.Sprintf("%02d:00", hour),
-			"endTime":      fmt.Sprintf("%02d:59", hour),
-			"duration":     60,
-			"hasRecording": hourMap[hour],
+// spansJSON converts spans to the JSON shape used by the timeline API.
+func spansJSON(spans []segment.Span) []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(spans))
+	for _, s := range spans {
+		out = append(out, map[string]interface{}{
+			"start":    s.Start.Format(time.RFC3339),
+			"end":      s.End.Format(time.RFC3339),
+			"duration": int(s.Duration().Seconds()),
 		})
 	}
-	c.JSON(http.StatusOK, segments)
+	return out
 }
 
 // PlaybackVideo returns concatenated list of auto-recording files for a specific day
@@ -212,89 +217,27 @@ func (h *Handler) PlaybackVideo(c *gin.Context) {
 	}
 
 	// Parse date
-	targetDate, err := time.Parse("2006-01-02", date)
+	targetDate, err := time.ParseInLocation("2006-01-02", date, time.Local)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format"})
 		return
 	}
 
-	// Build path to recordings directory
-	recordingsPath := filepath.Join("data", "recordings", cameraId, targetDate.Format("2006-01-02"))
-
-	if _, err := os.Stat(recordingsPath); os.IsNotExist(err) {
-		c.JSON(http.StatusOK, []map[string]interface{}{})
+	// Collect all auto-recording files (rec_*.mp4), sorted by start time
+	segs, err := segment.List(cameraId, targetDate, segment.KindContinuous)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read recordings"})
 		return
 	}
 
-	// Collect all auto-recording files (rec_*.mp4)
-	var files []map[string]interface{}
-	filepath.WalkDir(recordingsPath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil || d.IsDir() {
-			return nil
-		}
-		name := d.Name()
-		if !strings.HasPrefix(name, "rec_") || !strings.HasSuffix(strings.ToLower(name), ".mp4") {
-			return nil
-		}
-
-		info, err := d.Info()
-		if err != nil || info.Size() == 0 {
-			return nil
-		}
-
-		var startTime time.Time
-		if len(name) >= 19 {
-			timeStr := name[4:19]
-			if t, err := time.Parse("20060102_150405", timeStr); err == nil {
-				startTime = t
-			}
-		}
-		if startTime.IsZero() {
-			startTime = info.ModTime()
-		}
-
-		relPath := strings.TrimPrefix(path, "data/")
-		relPath = strings.ReplaceAll(relPath, "\\", "/")
-
-		files = append(files, map[string]interface{}{
-			"url":       "/" + relPath,
-			"startTime": startTime.Format(time.RFC3339),
-			"filename":  name,
-		})
-		return nil
-	})
-
-	// Sort by start time
-	type fileInfo struct {
-		URL       string
-		StartTime time.Time
-		Filename  string
-	}
-	var sortedFiles []fileInfo
-	for _, f := range files {
-		st, _ := time.Parse(time.RFC3339, f["startTime"].(string))
-		sortedFiles = append(sortedFiles, fileInfo{
-			URL:       f["url"].(string),
-			StartTime: st,
-			Filename:  f["filename"].(string),
-		})
-	}
-
-	// Sort by time
-	for i := 0; i < len(sortedFiles); i++ {
-		for j := i + 1; j < len(sortedFiles); j++ {
-			if sortedFiles[i].StartTime.After(sortedFiles[j].StartTime) {
-				sortedFiles[i], sortedFiles[j] = sortedFiles[j], sortedFiles[i]
-			}
-		}
-	}
-
-	result := []map[string]interface{}{}
-	for _, f := range sortedFiles {
+	result := make([]map[string]interface{}, 0, len(segs))
+	for _, s := range segs {
 		result = append(result, map[string]interface{}{
-			"url":       f.URL,
-			"startTime": f.StartTime.Format(time.RFC3339),
-			"filename":  f.Filename,
+			"url":       s.URL(),
+			"startTime": s.Start.Format(time.RFC3339),
+			"endTime":   s.End.Format(time.RFC3339),
+			"duration":  s.Duration().Seconds(),
+			"filename":  s.Name,
 		})
 	}
 
@@ -315,20 +258,20 @@ func (h *Handler) Stream(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
 		return
 	}
-	var cam *usecase.CameraDTO
-	var found bool
-	for _, c := range cams {
-		if c.ID == id {
-			// convert domain.Camera (returned by usecase.ListCameras) to DTO-like map
-			cam = &usecase.CameraDTO{ID: c.ID, Name: c.Name, Location: c.Location, RTSPURL: c.RTSPURL, Status: c.Status}
-			found = true
+	var cam *domain.Camera
+	for _, cc := range cams {
+		if cc.ID == id {
+			cam = cc
 			break
 		}
 	}
-	if !found || cam == nil {
+	if cam == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
+	if outOfService(c, cam) {
+		return
+	}
 	// Return JSON with RTSP URL so frontend can use it or handle accordingly
 	c.JSON(http.StatusOK, gin.H{"rtsp_url": cam.RTSPURL})
 }
@@ -346,9 +289,12 @@ func (h *Handler) StreamHLS(c *gin.Context) {
 		return
 	}
 	var rtsp string
-	for _, c := range cams {
-		if c.ID == id {
-			rtsp = c.RTSPURL
+	for _, cc := range cams {
+		if cc.ID == id {
+			if outOfService(c, cc) {
+				return
+			}
+			rtsp = recorder.AuthRTSPURL(cc.RTSPURL, cc.Username, cc.Password)
 			break
 		}
 	}
@@ -382,6 +328,33 @@ func (h *Handler) StreamHLS(c *gin.Context) {
 	c.JSON(http.StatusAccepted, gin.H{"url": url, "ready": false})
 }
 
+// Snapshot handles GET /api/cameras/{id}/snapshot and returns a current JPEG
+// frame from the camera.
+func (h *Handler) Snapshot(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing id"})
+		return
+	}
+	cam, err := h.uc.GetCamera(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if outOfService(c, cam) {
+		return
+	}
+	snap, err := snapshot.Get(c.Request.Context(), cam.ID, cam.RTSPURL, cam.Username, cam.Password)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("failed to grab snapshot: %v", err)})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Last-Modified", snap.TakenAt.UTC().Format(http.TimeFormat))
+	c.Header("X-Snapshot-Source", snap.Source)
+	c.Data(http.StatusOK, "image/jpeg", snap.Data)
+}
+
 // CreateCamera handles POST /api/cameras
 func (h *Handler) CreateCamera(c *gin.Context) {
 	var payload struct {
@@ -391,6 +364,10 @@ func (h *Handler) CreateCamera(c *gin.Context) {
 		RTSPURL  string `json:"rtsp_url"`
 		Username string `json:"username,omitempty"`
 		Password string `json:"password,omitempty"`
+
+		ProbeIntervalSeconds *int  `json:"probe_interval_seconds,omitempty"`
+		ProbeTimeoutSeconds  *int  `json:"probe_timeout_seconds,omitempty"`
+		Enabled              *bool `json:"enabled,omitempty"`
 	}
 	if err := c.ShouldBindJSON(&payload); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
@@ -403,8 +380,16 @@ func (h *Handler) CreateCamera(c *gin.Context) {
 		RTSPURL:  payload.RTSPURL,
 		Username: payload.Username,
 		Password: payload.Password,
+
+		ProbeIntervalSeconds: payload.ProbeIntervalSeconds,
+		ProbeTimeoutSeconds:  payload.ProbeTimeoutSeconds,
+		Enabled:              payload.Enabled,
 	}
 	created, err := h.uc.CreateCamera(cam)
+	if errors.Is(err, usecase.ErrInvalidCamera) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create"})
 		return
@@ -426,13 +411,22 @@ func (h *Handler) UpdateCamera(c *gin.Context) {
 		Username string `json:"username,omitempty"`
 		Password string `json:"password,omitempty"`
 		Status   string `json:"status"`
+
+		ProbeIntervalSeconds *int  `json:"probe_interval_seconds,omitempty"`
+		ProbeTimeoutSeconds  *int  `json:"probe_timeout_seconds,omitempty"`
+		Enabled              *bool `json:"enabled,omitempty"`
 	}
 	if err := c.ShouldBindJSON(&payload); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
 		return
 	}
-	cam := &usecase.CameraDTO{ID: id, Name: payload.Name, Location: payload.Location, RTSPURL: payload.RTSPURL, Username: payload.Username, Password: payload.Password, Status: payload.Status}
+	cam := &usecase.CameraDTO{ID: id, Name: payload.Name, Location: payload.Location, RTSPURL: payload.RTSPURL, Username: payload.Username, Password: payload.Password, Status: payload.Status,
+		ProbeIntervalSeconds: payload.ProbeIntervalSeconds, ProbeTimeoutSeconds: payload.ProbeTimeoutSeconds, Enabled: payload.Enabled}
 	updated, err := h.uc.UpdateCamera(cam)
+	if errors.Is(err, usecase.ErrInvalidCamera) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update"})
 		return
@@ -440,21 +434,142 @@ func (h *Handler) UpdateCamera(c *gin.Context) {
 	c.JSON(http.StatusOK, updated)
 }
 
-// DeleteCamera handles DELETE /api/cameras/{id}
+// AcknowledgeStreamChange handles POST /api/cameras/{id}/stream/ack and
+// clears the camera's flagged stream change.
+func (h *Handler) AcknowledgeStreamChange(c *gin.Context) {
+	cam, err := h.uc.AcknowledgeStreamChange(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, usecase.ErrCameraNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "camera not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to acknowledge"})
+		return
+	}
+	c.JSON(http.StatusOK, cam)
+}
+
+// StartMaintenance handles PUT /api/cameras/{id}/maintenance and takes the
+// camera out of service until an RFC3339 `until` or for `duration_minutes`,
+// with an optional `reason`.
+func (h *Handler) StartMaintenance(c *gin.Context) {
+	var payload struct {
+		Until           string `json:"until"`
+		DurationMinutes int    `json:"duration_minutes"`
+		Reason          string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+		return
+	}
+	var until time.Time
+	switch {
+	case payload.Until != "":
+		t, err := parseTimeParam(payload.Until)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid until, use RFC3339"})
+			return
+		}
+		until = t
+	case payload.DurationMinutes > 0:
+		until = time.Now().Add(time.Duration(payload.DurationMinutes) * time.Minute)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "until or duration_minutes is required"})
+		return
+	}
+	cam, err := h.uc.StartMaintenance(c.Param("id"), until, payload.Reason)
+	if !cameraError(c, err) {
+		c.JSON(http.StatusOK, cam)
+	}
+}
+
+// EndMaintenance handles DELETE /api/cameras/{id}/maintenance and returns
+// the camera to service.
+func (h *Handler) EndMaintenance(c *gin.Context) {
+	cam, err := h.uc.EndMaintenance(c.Param("id"))
+	if !cameraError(c, err) {
+		c.JSON(http.StatusOK, cam)
+	}
+}
+
+// ReportMotion handles POST /api/cameras/{id}/motion, the hook for cameras
+// and external detectors to report motion. An optional `source` names what
+// detected it. The motion is published as a motion.detected event.
+func (h *Handler) ReportMotion(c *gin.Context) {
+	var payload struct {
+		Source string `json:"source"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+			return
+		}
+	}
+	if !cameraError(c, h.uc.ReportMotion(c.Param("id"), payload.Source)) {
+		c.Status(http.StatusAccepted)
+	}
+}
+
+// cameraError writes the response for a camera usecase error and reports
+// whether there was one.
+func cameraError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, usecase.ErrCameraNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "camera not found"})
+	case errors.Is(err, usecase.ErrInvalidCamera):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrCameraOutOfService):
+		c.JSON(http.StatusConflict, gin.H{"error": "camera is disabled or in maintenance"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+	return true
+}
+
+// outOfService answers 409 for a camera that is disabled or in maintenance
+// and reports whether it did.
+func outOfService(c *gin.Context, cam *domain.Camera) bool {
+	switch cam.ServiceStatus(time.Now()) {
+	case domain.CameraStatusDisabled:
+		c.JSON(http.StatusConflict, gin.H{"error": "camera is disabled"})
+	case domain.CameraStatusMaintenance:
+		c.JSON(http.StatusConflict, gin.H{"error": "camera is in maintenance", "until": cam.MaintenanceUntil, "reason": cam.MaintenanceReason})
+	default:
+		return false
+	}
+	return true
+}
+
+// DeleteCamera handles DELETE /api/cameras/{id}. `footage` is keep (the
+// default), archive or purge.
 func (h *Handler) DeleteCamera(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing id"})
 		return
 	}
-	if err := h.uc.DeleteCamera(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete"})
+	if err := h.uc.DeleteCamera(id, usecase.FootageAction(c.Query("footage"))); err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrCameraNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "camera not found"})
+		case errors.Is(err, usecase.ErrInvalidCamera):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, usecase.ErrFootageNotRemoved):
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "deleted": true})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete"})
+		}
 		return
 	}
 	c.Status(http.StatusNoContent)
 }
 
-// StartRecording starts recording for a camera
+// StartRecording starts a manual capture for a camera. The optional JSON body
+// may set `duration_seconds` or an RFC3339 `end_time` to stop automatically,
+// an operator `note` and the `operator` name (falling back to the X-Operator
+// header).
 func (h *Handler) StartRecording(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
@@ -462,52 +577,74 @@ func (h *Handler) StartRecording(c *gin.Context) {
 		return
 	}
 
-	// Get camera details
-	cams, err := h.uc.ListCameras()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	var payload struct {
+		DurationSeconds int    `json:"duration_seconds"`
+		EndTime         string `json:"end_time"`
+		Note            string `json:"note"`
+		Operator        string `json:"operator"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+			return
+		}
+	}
+	if payload.DurationSeconds < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "duration_seconds must not be negative"})
 		return
 	}
-
-	var camera *usecase.CameraDTO
-	for _, cam := range cams {
-		if cam.ID == id {
-			camera = &usecase.CameraDTO{
-				ID:       cam.ID,
-				Name:     cam.Name,
-				Location: cam.Location,
-				RTSPURL:  cam.RTSPURL,
-				Status:   cam.Status,
-			}
-			break
+	var stopAt time.Time
+	if payload.EndTime != "" {
+		t, err := time.Parse(time.RFC3339, payload.EndTime)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_time, use RFC3339"})
+			return
 		}
+		stopAt = t
+	}
+	operator := payload.Operator
+	if operator == "" {
+		operator = c.GetHeader("X-Operator")
 	}
 
-	if camera == nil {
+	capture, err := h.captures.StartCapture(usecase.CaptureRequest{
+		CameraID: id,
+		Operator: operator,
+		Note:     payload.Note,
+		Duration: time.Duration(payload.DurationSeconds) * time.Second,
+		StopAt:   stopAt,
+	})
+	switch {
+	case errors.Is(err, usecase.ErrCameraNotFound):
 		c.JSON(http.StatusNotFound, gin.H{"error": "camera not found"})
 		return
-	}
-
-	// Check if camera is online
-	if camera.Status != "online" {
+	case errors.Is(err, usecase.ErrCameraOffline):
 		c.JSON(http.StatusBadRequest, gin.H{"error": "camera is offline"})
 		return
-	}
-
-	// Start recording
-	if err := recorder.StartRecording(camera.ID, camera.Name, camera.RTSPURL, camera.Username, camera.Password); err != nil {
+	case errors.Is(err, usecase.ErrCameraOutOfService):
+		c.JSON(http.StatusConflict, gin.H{"error": "camera is disabled or in maintenance"})
+		return
+	case errors.Is(err, usecase.ErrInvalidStopTime):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_time must be in the future"})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to start recording: %v", err)})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":     "recording started",
-		"camera_id":   camera.ID,
-		"camera_name": camera.Name,
-	})
+	resp := gin.H{
+		"message":   "recording started",
+		"camera_id": id,
+		"capture":   capture,
+		"stop_at":   nil,
+	}
+	if capture.StopAt != nil {
+		resp["stop_at"] = capture.StopAt.Format(time.RFC3339)
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
-// StopRecording stops recording for a camera
+// StopRecording stops the manual capture for a camera
 func (h *Handler) StopRecording(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
@@ -515,7 +652,7 @@ func (h *Handler) StopRecording(c *gin.Context) {
 		return
 	}
 
-	if err := recorder.StopRecording(id); err != nil {
+	if err := h.captures.StopCapture(id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to stop recording: %v", err)})
 		return
 	}
@@ -526,17 +663,31 @@ func (h *Handler) StopRecording(c *gin.Context) {
 	})
 }
 
-// ActiveRecordings returns list of active recording sessions
+// ActiveRecordings returns list of active continuous recordings and manual captures
 func (h *Handler) ActiveRecordings(c *gin.Context) {
 	sessions := recorder.GetActiveRecordings()
+	captures := recorder.GetActiveCaptures()
 
-	recordings := make([]map[string]interface{}, 0, len(sessions))
+	recordings := make([]map[string]interface{}, 0, len(sessions)+len(captures))
 	for _, session := range sessions {
 		recordings = append(recordings, map[string]interface{}{
 			"camera_id":   session.CameraID,
 			"camera_name": session.CameraName,
 			"start_time":  session.StartTime.Format(time.RFC3339),
 			"duration":    time.Since(session.StartTime).Seconds(),
+			"type":        "continuous",
+		})
+	}
+	for _, capture := range captures {
+		recordings = append(recordings, map[string]interface{}{
+			"camera_id":   capture.CameraID,
+			"camera_name": capture.CameraName,
+			"start_time":  capture.StartTime.Format(time.RFC3339),
+			"duration":    time.Since(capture.StartTime).Seconds(),
+			"type":        "manual",
+			"file_name":   capture.FileName,
+			"operator":    capture.Operator,
+			"note":        capture.Note,
 		})
 	}
 
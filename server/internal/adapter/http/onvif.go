@@ -0,0 +1,85 @@
+package httpadapter
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/boytur/cctv-recording-center/server/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type onvifDevicePayload struct {
+	Address  string `json:"address" binding:"required"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// InspectONVIFDevice handles POST /api/onvif/device. Given a device
+// `address` (host, host:port or device service URL) and credentials it
+// returns the device information and its streams, main stream first.
+func (h *Handler) InspectONVIFDevice(c *gin.Context) {
+	var payload onvifDevicePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "address is required"})
+		return
+	}
+	dev, err := h.onvif.InspectDevice(c.Request.Context(), payload.Address, payload.Username, payload.Password)
+	if !onvifError(c, err) {
+		c.JSON(http.StatusOK, dev)
+	}
+}
+
+// AddONVIFCamera handles POST /api/onvif/cameras and creates a camera from
+// the stream with `profile_token` (the main stream if omitted) of a device.
+func (h *Handler) AddONVIFCamera(c *gin.Context) {
+	var payload struct {
+		onvifDevicePayload
+		ProfileToken string `json:"profile_token"`
+		Name         string `json:"name"`
+		Location     string `json:"location"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "address is required"})
+		return
+	}
+	cam, err := h.onvif.AddCamera(c.Request.Context(), usecase.AddONVIFCameraRequest{
+		Address:      payload.Address,
+		Username:     payload.Username,
+		Password:     payload.Password,
+		ProfileToken: payload.ProfileToken,
+		Name:         payload.Name,
+		Location:     payload.Location,
+	})
+	if !onvifError(c, err) {
+		c.JSON(http.StatusCreated, cam)
+	}
+}
+
+// RefreshCameraHardware handles POST /api/cameras/{id}/onvif-device/refresh
+// and reads the device information of an ONVIF camera again.
+func (h *Handler) RefreshCameraHardware(c *gin.Context) {
+	cam, err := h.onvif.RefreshHardware(c.Request.Context(), c.Param("id"))
+	if !onvifError(c, err) {
+		c.JSON(http.StatusOK, cam)
+	}
+}
+
+// onvifError writes the response for an ONVIF usecase error and reports
+// whether there was one.
+func onvifError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, usecase.ErrCameraNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "camera not found"})
+	case errors.Is(err, usecase.ErrInvalidCamera):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrDeviceAuth):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrDeviceUnavailable):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+	return true
+}
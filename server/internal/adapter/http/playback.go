@@ -0,0 +1,132 @@
+package httpadapter
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/playback"
+	"github.com/boytur/cctv-recording-center/server/internal/recorder"
+	"github.com/boytur/cctv-recording-center/server/internal/segment"
+	"github.com/gin-gonic/gin"
+)
+
+// PlaybackDayPlaylist handles GET /api/playback/{cameraId}/{date}/index.m3u8
+// and returns an HLS playlist covering the whole day.
+func (h *Handler) PlaybackDayPlaylist(c *gin.Context) {
+	dayStart, err := time.ParseInLocation("2006-01-02", c.Param("date"), time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format, use YYYY-MM-DD"})
+		return
+	}
+	h.servePlaylist(c, c.Param("cameraId"), dayStart, dayStart.AddDate(0, 0, 1))
+}
+
+// PlaybackRangePlaylist handles GET /api/playback/{cameraId}/index.m3u8?from=&to=
+// and returns an HLS playlist for an arbitrary time range.
+func (h *Handler) PlaybackRangePlaylist(c *gin.Context) {
+	from, err := parseTimeParam(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from, use RFC3339"})
+		return
+	}
+	to, err := parseTimeParam(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to, use RFC3339"})
+		return
+	}
+	if !to.After(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be after from"})
+		return
+	}
+	h.servePlaylist(c, c.Param("cameraId"), from, to)
+}
+
+func (h *Handler) servePlaylist(c *gin.Context, cameraId string, from, to time.Time) {
+	if cameraId == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing cameraId"})
+		return
+	}
+	segs, err := segment.Range(cameraId, from, to, segment.KindContinuous)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read recordings"})
+		return
+	}
+
+	// While the window still reaches into the future of a camera that is
+	// recording, the playlist grows and the player should keep reloading it.
+	playlistType := playback.TypeVOD
+	if to.After(time.Now()) && recorder.IsRecording(cameraId) {
+		playlistType = playback.TypeEvent
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, "application/vnd.apple.mpegurl", []byte(playback.BuildPlaylist(segs, from, to, playlistType)))
+}
+
+// PlaybackSeek handles GET /api/playback/{cameraId}/seek?t= and resolves a
+// wall-clock time to the recorded segment and keyframe to start playing from.
+func (h *Handler) PlaybackSeek(c *gin.Context) {
+	cameraId := c.Param("cameraId")
+	t, err := parseTimeParam(c.Query("t"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid t, use RFC3339"})
+		return
+	}
+	pos, err := playback.Seek(cameraId, t)
+	if errors.Is(err, playback.ErrNoRecordings) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no recordings for camera"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read recordings"})
+		return
+	}
+
+	s := pos.Segment
+	resp := gin.H{
+		"cameraId":     cameraId,
+		"requested":    pos.Requested.Format(time.RFC3339),
+		"resolvedTime": s.Start.Add(pos.Offset).Format(time.RFC3339),
+		"inGap":        pos.InGap,
+		"segment": gin.H{
+			"filename":  s.Name,
+			"url":       s.URL(),
+			"startTime": s.Start.Format(time.RFC3339),
+			"endTime":   s.End.Format(time.RFC3339),
+			"duration":  s.Duration().Seconds(),
+		},
+		"offset": pos.Offset.Seconds(),
+	}
+	if pos.HasKeyframe {
+		resp["keyframe"] = gin.H{
+			"time":       s.Start.Add(pos.KeyframeOffset).Format(time.RFC3339Nano),
+			"offset":     pos.KeyframeOffset.Seconds(),
+			"byteOffset": pos.KeyframeByte,
+			"initSize":   s.Media.InitSize,
+		}
+	}
+	if pos.InGap {
+		gap := gin.H{"start": nil, "end": nil}
+		if !pos.GapStart.IsZero() {
+			gap["start"] = pos.GapStart.Format(time.RFC3339)
+		}
+		if !pos.GapEnd.IsZero() {
+			gap["end"] = pos.GapEnd.Format(time.RFC3339)
+		}
+		resp["gap"] = gap
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// parseTimeParam accepts RFC3339 timestamps, or local wall-clock time without
+// a zone offset.
+func parseTimeParam(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, errors.New("missing time")
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02T15:04:05", v, time.Local)
+}
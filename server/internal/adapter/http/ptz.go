@@ -0,0 +1,269 @@
+package httpadapter
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// PTZStatus handles GET /api/cameras/{id}/ptz and returns who holds the
+// camera's control lock and the patrol tour running on it.
+func (h *Handler) PTZStatus(c *gin.Context) {
+	status, err := h.ptz.Status(c.Param("id"))
+	if !h.ptzError(c, err) {
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// PTZLock handles POST /api/cameras/{id}/ptz/lock. It takes or renews
+// control of the camera for `duration_seconds` (two minutes by default).
+// Every PTZ command also takes the lock, so this is only needed to hold a
+// camera without moving it.
+func (h *Handler) PTZLock(c *gin.Context) {
+	var payload struct {
+		DurationSeconds int `json:"duration_seconds"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+			return
+		}
+	}
+	lock, err := h.ptz.Lock(c.Param("id"), operator(c), time.Duration(payload.DurationSeconds)*time.Second)
+	if !h.ptzError(c, err) {
+		c.JSON(http.StatusOK, lock)
+	}
+}
+
+// PTZUnlock handles DELETE /api/cameras/{id}/ptz/lock. `force=true`
+// releases another operator's lock.
+func (h *Handler) PTZUnlock(c *gin.Context) {
+	err := h.ptz.Unlock(c.Param("id"), operator(c), c.Query("force") == "true")
+	if !h.ptzError(c, err) {
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// PTZMove handles POST /api/cameras/{id}/ptz/move. `mode` is continuous
+// (pan, tilt and zoom are velocities from -1 to 1, stopped after
+// `timeout_ms`), absolute (a position) or relative (a translation).
+// Absolute and relative moves take an optional `speed` from 0 to 1.
+func (h *Handler) PTZMove(c *gin.Context) {
+	var payload struct {
+		Mode      string   `json:"mode" binding:"required"`
+		Pan       *float64 `json:"pan"`
+		Tilt      *float64 `json:"tilt"`
+		Zoom      *float64 `json:"zoom"`
+		Speed     *float64 `json:"speed"`
+		TimeoutMs int      `json:"timeout_ms"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode is required"})
+		return
+	}
+	cmd := usecase.PTZCommand{Mode: payload.Mode, Speed: payload.Speed, Timeout: time.Duration(payload.TimeoutMs) * time.Millisecond}
+	cmd.Pan, cmd.Tilt, cmd.Zoom = payload.Pan, payload.Tilt, payload.Zoom
+	err := h.ptz.Move(c.Request.Context(), c.Param("id"), operator(c), cmd)
+	if !h.ptzError(c, err) {
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// PTZZoom handles POST /api/cameras/{id}/ptz/zoom, a continuous zoom at
+// `velocity` (-1 zooms out, 1 zooms in) until stopped or `timeout_ms`.
+func (h *Handler) PTZZoom(c *gin.Context) {
+	var payload struct {
+		Velocity  *float64 `json:"velocity" binding:"required"`
+		TimeoutMs int      `json:"timeout_ms"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "velocity is required"})
+		return
+	}
+	cmd := usecase.PTZCommand{Mode: usecase.PTZContinuous, Timeout: time.Duration(payload.TimeoutMs) * time.Millisecond}
+	cmd.Zoom = payload.Velocity
+	err := h.ptz.Move(c.Request.Context(), c.Param("id"), operator(c), cmd)
+	if !h.ptzError(c, err) {
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// PTZStop handles POST /api/cameras/{id}/ptz/stop.
+func (h *Handler) PTZStop(c *gin.Context) {
+	err := h.ptz.StopMove(c.Request.Context(), c.Param("id"), operator(c))
+	if !h.ptzError(c, err) {
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// ListPTZPresets handles GET /api/cameras/{id}/ptz/presets.
+func (h *Handler) ListPTZPresets(c *gin.Context) {
+	presets, err := h.ptz.Presets(c.Request.Context(), c.Param("id"))
+	if !h.ptzError(c, err) {
+		c.JSON(http.StatusOK, presets)
+	}
+}
+
+// SavePTZPreset handles POST /api/cameras/{id}/ptz/presets and stores the
+// current position as a preset called `name`, overwriting the preset
+// `token` if given.
+func (h *Handler) SavePTZPreset(c *gin.Context) {
+	var payload struct {
+		Name  string `json:"name"`
+		Token string `json:"token"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+		return
+	}
+	preset, err := h.ptz.SavePreset(c.Request.Context(), c.Param("id"), operator(c), payload.Name, payload.Token)
+	if !h.ptzError(c, err) {
+		c.JSON(http.StatusCreated, preset)
+	}
+}
+
+// GotoPTZPreset handles POST /api/cameras/{id}/ptz/presets/{token}/goto
+// with an optional `speed`.
+func (h *Handler) GotoPTZPreset(c *gin.Context) {
+	var payload struct {
+		Speed *float64 `json:"speed"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+			return
+		}
+	}
+	err := h.ptz.GotoPreset(c.Request.Context(), c.Param("id"), operator(c), c.Param("token"), payload.Speed)
+	if !h.ptzError(c, err) {
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// RemovePTZPreset handles DELETE /api/cameras/{id}/ptz/presets/{token}.
+func (h *Handler) RemovePTZPreset(c *gin.Context) {
+	err := h.ptz.RemovePreset(c.Request.Context(), c.Param("id"), operator(c), c.Param("token"))
+	if !h.ptzError(c, err) {
+		c.Status(http.StatusNoContent)
+	}
+}
+
+type patrolTourPayload struct {
+	CameraID  string              `json:"camera_id"`
+	Name      string              `json:"name"`
+	Steps     []domain.PatrolStep `json:"steps"`
+	Enabled   *bool               `json:"enabled"`
+	Days      []int               `json:"days"`
+	StartTime *string             `json:"start_time"`
+	EndTime   *string             `json:"end_time"`
+}
+
+func (p *patrolTourPayload) toDTO() *usecase.PatrolTourDTO {
+	return &usecase.PatrolTourDTO{
+		CameraID:  p.CameraID,
+		Name:      p.Name,
+		Steps:     p.Steps,
+		Enabled:   p.Enabled,
+		Days:      p.Days,
+		StartTime: p.StartTime,
+		EndTime:   p.EndTime,
+	}
+}
+
+// ListPatrolTours handles GET /api/patrol-tours, optionally filtered by
+// cameraId.
+func (h *Handler) ListPatrolTours(c *gin.Context) {
+	tours, err := h.ptz.ListTours(c.Query("cameraId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	c.JSON(http.StatusOK, tours)
+}
+
+// GetPatrolTour handles GET /api/patrol-tours/{id}
+func (h *Handler) GetPatrolTour(c *gin.Context) {
+	t, err := h.ptz.GetTour(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// CreatePatrolTour handles POST /api/patrol-tours
+func (h *Handler) CreatePatrolTour(c *gin.Context) {
+	var payload patrolTourPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+		return
+	}
+	dto := payload.toDTO()
+	dto.CreatedBy = c.GetHeader("X-Operator")
+	created, err := h.ptz.CreateTour(dto)
+	if !h.ptzError(c, err) {
+		c.JSON(http.StatusCreated, created)
+	}
+}
+
+// UpdatePatrolTour handles PUT /api/patrol-tours/{id}
+func (h *Handler) UpdatePatrolTour(c *gin.Context) {
+	var payload patrolTourPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+		return
+	}
+	dto := payload.toDTO()
+	dto.ID = c.Param("id")
+	updated, err := h.ptz.UpdateTour(dto)
+	if !h.ptzError(c, err) {
+		c.JSON(http.StatusOK, updated)
+	}
+}
+
+// DeletePatrolTour handles DELETE /api/patrol-tours/{id}
+func (h *Handler) DeletePatrolTour(c *gin.Context) {
+	err := h.ptz.DeleteTour(c.Param("id"))
+	if !h.ptzError(c, err) {
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// operator identifies who sends a PTZ command, for the control lock and the
+// audit trail: the X-Operator header, or the client address without one. The
+// audit trail is the ptz.* and patrol.* events, read back through
+// GET /api/events; they are kept for PTZ_AUDIT_RETENTION_DAYS (365 days by
+// default, 0 forever) rather than the event log's EVENT_RETENTION_DAYS.
+func operator(c *gin.Context) string {
+	if op := c.GetHeader("X-Operator"); op != "" {
+		return op
+	}
+	return c.ClientIP()
+}
+
+// ptzError writes the response for a PTZ usecase error and reports whether
+// there was one. A locked camera answers 423 with the current lock.
+func (h *Handler) ptzError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, usecase.ErrPTZLocked):
+		body := gin.H{"error": err.Error()}
+		if status, serr := h.ptz.Status(c.Param("id")); serr == nil && status.Lock != nil {
+			body["lock"] = status.Lock
+		}
+		c.JSON(http.StatusLocked, body)
+	case errors.Is(err, usecase.ErrPatrolTourNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, usecase.ErrInvalidPTZCommand), errors.Is(err, usecase.ErrInvalidPatrolTour),
+		errors.Is(err, usecase.ErrPTZUnsupported):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		return onvifError(c, err)
+	}
+	return true
+}
@@ -15,7 +15,7 @@ func SetupRouter(h *Handler) *gin.Engine {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:8080", "http://localhost:5173"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Operator"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 	}))
@@ -23,6 +23,7 @@ func SetupRouter(h *Handler) *gin.Engine {
 	// Serve static files from data directory
 	r.StaticFS("/data", gin.Dir("data", true))
 	r.StaticFS("/stream_hls", gin.Dir("data/streams", true))
+	r.StaticFS("/recordings", gin.Dir("data/recordings", false))
 
 	// Health check
 	r.GET("/health", h.Health)
@@ -35,14 +36,97 @@ func SetupRouter(h *Handler) *gin.Engine {
 		api.POST("/cameras", h.CreateCamera)
 		api.PUT("/cameras/:id", h.UpdateCamera)
 		api.DELETE("/cameras/:id", h.DeleteCamera)
+		api.GET("/cameras/:id/snapshot", h.Snapshot)
+		api.POST("/cameras/:id/stream/ack", h.AcknowledgeStreamChange)
+		api.PUT("/cameras/:id/maintenance", h.StartMaintenance)
+		api.DELETE("/cameras/:id/maintenance", h.EndMaintenance)
+		api.POST("/cameras/:id/motion", h.ReportMotion)
 
 		// Recording routes
 		api.GET("/recordings", h.Recordings)
 		api.GET("/recordings/active", h.ActiveRecordings)
 		api.GET("/timeline", h.Timeline)
 		api.GET("/playback/video", h.PlaybackVideo)
+		api.GET("/playback/:cameraId/index.m3u8", h.PlaybackRangePlaylist)
+		api.GET("/playback/:cameraId/seek", h.PlaybackSeek)
+		api.GET("/playback/:cameraId/:date/index.m3u8", h.PlaybackDayPlaylist)
 		api.POST("/cameras/:id/start-recording", h.StartRecording)
 		api.POST("/cameras/:id/stop-recording", h.StopRecording)
+		api.POST("/export", h.ExportClip)
+
+		// Bookmark routes
+		api.GET("/bookmarks", h.ListBookmarks)
+		api.POST("/bookmarks", h.CreateBookmark)
+		api.GET("/bookmarks/:id", h.GetBookmark)
+		api.PUT("/bookmarks/:id", h.UpdateBookmark)
+		api.DELETE("/bookmarks/:id", h.DeleteBookmark)
+
+		// Event routes
+		api.GET("/events", h.ListEvents)
+		api.GET("/events/stream", h.StreamEvents)
+
+		// Webhook routes
+		api.GET("/webhooks", h.ListWebhooks)
+		api.POST("/webhooks", h.CreateWebhook)
+		api.GET("/webhooks/:id", h.GetWebhook)
+		api.PUT("/webhooks/:id", h.UpdateWebhook)
+		api.DELETE("/webhooks/:id", h.DeleteWebhook)
+		api.GET("/webhooks/:id/deliveries", h.WebhookDeliveries)
+		api.POST("/webhooks/:id/deliveries/:deliveryId/retry", h.RedeliverWebhook)
+		api.POST("/webhooks/:id/test", h.TestWebhook)
+
+		// Alert routes
+		api.GET("/alerts", h.Alerts)
+		api.GET("/alerts/settings", h.AlertSettings)
+		api.PUT("/alerts/settings", h.UpdateAlertSettings)
+		api.GET("/alerts/mutes", h.ListAlertMutes)
+		api.POST("/alerts/mutes", h.CreateAlertMute)
+		api.DELETE("/alerts/mutes/:id", h.DeleteAlertMute)
+
+		// Email recipient group routes
+		api.GET("/email-groups", h.ListEmailGroups)
+		api.POST("/email-groups", h.CreateEmailGroup)
+		api.GET("/email-groups/:id", h.GetEmailGroup)
+		api.PUT("/email-groups/:id", h.UpdateEmailGroup)
+		api.DELETE("/email-groups/:id", h.DeleteEmailGroup)
+		api.POST("/email-groups/:id/test", h.TestEmailGroup)
+		api.POST("/email-groups/:id/digest", h.SendEmailDigest)
+
+		// Availability routes
+		api.GET("/cameras/:id/status-history", h.StatusHistory)
+		api.GET("/cameras/:id/availability", h.CameraAvailability)
+		api.GET("/reports/availability", h.AvailabilityReport)
+
+		// Camera discovery and ONVIF device routes
+		api.GET("/discovery/onvif", h.DiscoverONVIF)
+		api.POST("/onvif/device", h.InspectONVIFDevice)
+		api.POST("/onvif/cameras", h.AddONVIFCamera)
+		api.POST("/cameras/:id/onvif-device/refresh", h.RefreshCameraHardware)
+
+		// PTZ control and patrol tour routes
+		api.GET("/cameras/:id/ptz", h.PTZStatus)
+		api.POST("/cameras/:id/ptz/lock", h.PTZLock)
+		api.DELETE("/cameras/:id/ptz/lock", h.PTZUnlock)
+		api.POST("/cameras/:id/ptz/move", h.PTZMove)
+		api.POST("/cameras/:id/ptz/zoom", h.PTZZoom)
+		api.POST("/cameras/:id/ptz/stop", h.PTZStop)
+		api.GET("/cameras/:id/ptz/presets", h.ListPTZPresets)
+		api.POST("/cameras/:id/ptz/presets", h.SavePTZPreset)
+		api.POST("/cameras/:id/ptz/presets/:token/goto", h.GotoPTZPreset)
+		api.DELETE("/cameras/:id/ptz/presets/:token", h.RemovePTZPreset)
+		api.GET("/patrol-tours", h.ListPatrolTours)
+		api.POST("/patrol-tours", h.CreatePatrolTour)
+		api.GET("/patrol-tours/:id", h.GetPatrolTour)
+		api.PUT("/patrol-tours/:id", h.UpdatePatrolTour)
+		api.DELETE("/patrol-tours/:id", h.DeletePatrolTour)
+
+		// Multi-channel device (NVR/DVR) routes
+		api.GET("/devices", h.ListDevices)
+		api.POST("/devices", h.CreateDevice)
+		api.GET("/devices/:id", h.GetDevice)
+		api.PUT("/devices/:id", h.UpdateDevice)
+		api.DELETE("/devices/:id", h.DeleteDevice)
+		api.POST("/devices/:id/channels", h.AddDeviceChannels)
 
 		// Streaming routes
 		api.GET("/stream/:id", h.Stream)
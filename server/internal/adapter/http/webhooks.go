@@ -0,0 +1,165 @@
+package httpadapter
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type webhookPayload struct {
+	Name       string   `json:"name"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	CameraIDs  []string `json:"camera_ids"`
+	Secret     string   `json:"secret"`
+	Enabled    *bool    `json:"enabled"`
+}
+
+// createdWebhook is the create response, the only one that carries the
+// signing secret.
+type createdWebhook struct {
+	*domain.Webhook
+	Secret string `json:"secret"`
+}
+
+func (p *webhookPayload) toDTO() *usecase.WebhookDTO {
+	return &usecase.WebhookDTO{
+		Name:       p.Name,
+		URL:        p.URL,
+		EventTypes: p.EventTypes,
+		CameraIDs:  p.CameraIDs,
+		Secret:     p.Secret,
+		Enabled:    p.Enabled,
+	}
+}
+
+// ListWebhooks handles GET /api/webhooks
+func (h *Handler) ListWebhooks(c *gin.Context) {
+	hooks, err := h.webhooks.ListWebhooks()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	c.JSON(http.StatusOK, hooks)
+}
+
+// GetWebhook handles GET /api/webhooks/{id}
+func (h *Handler) GetWebhook(c *gin.Context) {
+	w, err := h.webhooks.GetWebhook(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, w)
+}
+
+// CreateWebhook handles POST /api/webhooks. A signing secret is generated
+// when the body does not give one; the response is the only place it is
+// returned.
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	var payload webhookPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+		return
+	}
+	created, err := h.webhooks.CreateWebhook(payload.toDTO())
+	switch {
+	case errors.Is(err, usecase.ErrInvalidWebhook):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create"})
+		return
+	}
+	c.JSON(http.StatusCreated, createdWebhook{Webhook: created, Secret: created.Secret})
+}
+
+// UpdateWebhook handles PUT /api/webhooks/{id}
+func (h *Handler) UpdateWebhook(c *gin.Context) {
+	var payload webhookPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+		return
+	}
+	dto := payload.toDTO()
+	dto.ID = c.Param("id")
+	updated, err := h.webhooks.UpdateWebhook(dto)
+	switch {
+	case errors.Is(err, usecase.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	case errors.Is(err, usecase.ErrInvalidWebhook):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update"})
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteWebhook handles DELETE /api/webhooks/{id}
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	if err := h.webhooks.DeleteWebhook(c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// WebhookDeliveries handles GET /api/webhooks/{id}/deliveries. Optional
+// filters: status (pending, retrying, succeeded, dead) and limit.
+func (h *Handler) WebhookDeliveries(c *gin.Context) {
+	limit := 0
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		limit = n
+	}
+	deliveries, err := h.webhooks.ListDeliveries(c.Param("id"), c.Query("status"), limit)
+	if errors.Is(err, usecase.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// TestWebhook handles POST /api/webhooks/{id}/test. It sends a webhook.test
+// event synchronously and returns the delivery with its outcome.
+func (h *Handler) TestWebhook(c *gin.Context) {
+	d, err := h.webhooks.TestWebhook(c.Param("id"))
+	if errors.Is(err, usecase.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send test event"})
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// RedeliverWebhook handles POST /api/webhooks/{id}/deliveries/{deliveryId}/retry
+func (h *Handler) RedeliverWebhook(c *gin.Context) {
+	d, err := h.webhooks.Redeliver(c.Param("id"), c.Param("deliveryId"))
+	if errors.Is(err, usecase.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to requeue delivery"})
+		return
+	}
+	c.JSON(http.StatusAccepted, d)
+}
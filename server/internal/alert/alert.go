@@ -0,0 +1,321 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/events"
+	"github.com/boytur/cctv-recording-center/server/internal/repository"
+)
+
+// Alert kinds.
+const (
+	KindOffline   = "offline"
+	KindRecovered = "recovered"
+)
+
+// notifyTimeout bounds a single notifier call.
+const notifyTimeout = 20 * time.Second
+
+// Alert is a notification about a camera outage. For an offline alert At is
+// when the grace period ran out; for a recovery it is when the camera came
+// back.
+type Alert struct {
+	Kind       string
+	CameraID   string
+	CameraName string
+	Location   string
+	Since      time.Time
+	At         time.Time
+}
+
+// Duration returns how long the camera has been, or was, offline.
+func (a Alert) Duration() time.Duration {
+	return a.At.Sub(a.Since).Round(time.Second)
+}
+
+// Subject returns a one-line summary of the alert.
+func (a Alert) Subject() string {
+	if a.Kind == KindRecovered {
+		return fmt.Sprintf("Camera %q is back online", a.CameraName)
+	}
+	return fmt.Sprintf("Camera %q is offline", a.CameraName)
+}
+
+// Message returns a human readable description of the alert.
+func (a Alert) Message() string {
+	where := ""
+	if a.Location != "" {
+		where = " (" + a.Location + ")"
+	}
+	if a.Kind == KindRecovered {
+		return fmt.Sprintf("Camera %q%s is back online after %s offline (since %s).",
+			a.CameraName, where, a.Duration(), a.Since.Local().Format("2006-01-02 15:04:05"))
+	}
+	return fmt.Sprintf("Camera %q%s has been offline since %s (%s).",
+		a.CameraName, where, a.Since.Local().Format("2006-01-02 15:04:05"), a.Duration())
+}
+
+// Notifier delivers alerts to people, e.g. by chat message or email.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, a Alert) error
+}
+
+// EventHistory is the part of the event store used to restore outages that
+// were in progress when the server stopped.
+type EventHistory interface {
+	List(f domain.EventFilter) ([]*domain.Event, int64, error)
+}
+
+// Outage is a camera currently offline as tracked by the Alerter.
+type Outage struct {
+	CameraID  string     `json:"camera_id"`
+	Since     time.Time  `json:"since"`
+	Alerted   bool       `json:"alerted"`
+	AlertedAt *time.Time `json:"alerted_at,omitempty"`
+}
+
+// Alerter watches camera status events and raises an alert once a camera
+// has stayed offline for the configured grace period, then a recovery notice
+// when it comes back. Cameras inside a mute window are not alerted.
+type Alerter struct {
+	cameras   repository.CameraRepository
+	repo      repository.AlertRepository
+	history   EventHistory
+	notifiers []Notifier
+	interval  time.Duration
+
+	mu      sync.Mutex
+	outages map[string]*Outage
+
+	stopChan chan struct{}
+}
+
+// NewAlerter creates an alerter delivering through the given notifiers.
+func NewAlerter(cameras repository.CameraRepository, repo repository.AlertRepository, history EventHistory, notifiers ...Notifier) *Alerter {
+	return &Alerter{
+		cameras:   cameras,
+		repo:      repo,
+		history:   history,
+		notifiers: notifiers,
+		interval:  15 * time.Second,
+		outages:   make(map[string]*Outage),
+		stopChan:  make(chan struct{}),
+	}
+}
+
+// Start restores in-progress outages and begins watching the event bus.
+func (a *Alerter) Start() {
+	ch, cancel := events.Subscribe(64, events.OfTypes(events.CameraOnline, events.CameraOffline, events.CameraOutOfService, events.CameraDeleted))
+	a.restore()
+	go a.run(ch, cancel)
+	names := make([]string, 0, len(a.notifiers))
+	for _, n := range a.notifiers {
+		names = append(names, n.Name())
+	}
+	log.Printf("alert: offline alerting started with notifiers %v", names)
+}
+
+// Stop stops the alerter.
+func (a *Alerter) Stop() {
+	close(a.stopChan)
+}
+
+// Outages returns the cameras currently offline, longest outage first.
+func (a *Alerter) Outages() []Outage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	res := make([]Outage, 0, len(a.outages))
+	for _, o := range a.outages {
+		res = append(res, *o)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Since.Before(res[j].Since) })
+	return res
+}
+
+func (a *Alerter) run(ch <-chan domain.Event, cancel func()) {
+	defer cancel()
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			a.handle(e)
+		case <-ticker.C:
+			a.check(time.Now())
+		case <-a.stopChan:
+			return
+		}
+	}
+}
+
+func (a *Alerter) handle(e domain.Event) {
+	var recovered *Outage
+	a.mu.Lock()
+	switch e.Type {
+	case events.CameraOffline:
+		if _, ok := a.outages[e.CameraID]; !ok {
+			a.outages[e.CameraID] = &Outage{CameraID: e.CameraID, Since: e.Time}
+		}
+	case events.CameraOnline:
+		if o, ok := a.outages[e.CameraID]; ok {
+			delete(a.outages, e.CameraID)
+			if o.Alerted {
+				recovered = o
+			}
+		}
+	case events.CameraOutOfService, events.CameraDeleted:
+		// a camera taken out of service is down on purpose
+		delete(a.outages, e.CameraID)
+	}
+	a.mu.Unlock()
+
+	if recovered != nil {
+		a.raise(KindRecovered, *recovered, e.Time)
+	}
+}
+
+// check raises alerts for outages that outlasted the grace period. The lock
+// is only held to read and update outages; mutes are loaded and alerts
+// raised after releasing it so Outages isn't blocked on the database or the
+// bus. handle runs on the same goroutine, so outages can't recover between
+// the two.
+func (a *Alerter) check(now time.Time) {
+	settings, err := a.repo.GetSettings()
+	if err != nil {
+		log.Printf("alert: failed to load settings: %v", err)
+		return
+	}
+	if !settings.Enabled {
+		return
+	}
+
+	a.mu.Lock()
+	var due []string
+	for _, o := range a.outages {
+		if !o.Alerted && now.Sub(o.Since) >= settings.GracePeriod {
+			due = append(due, o.CameraID)
+		}
+	}
+	a.mu.Unlock()
+
+	var alerts []Outage
+	for _, id := range due {
+		if a.muted(id, now) {
+			continue
+		}
+		a.mu.Lock()
+		if o, ok := a.outages[id]; ok && !o.Alerted {
+			o.Alerted = true
+			at := now
+			o.AlertedAt = &at
+			alerts = append(alerts, *o)
+		}
+		a.mu.Unlock()
+	}
+
+	for _, o := range alerts {
+		a.raise(KindOffline, o, now)
+	}
+}
+
+func (a *Alerter) muted(cameraID string, now time.Time) bool {
+	mutes, err := a.repo.ListMutes(cameraID, now)
+	if err != nil {
+		log.Printf("alert: failed to load mutes for camera %s: %v", cameraID, err)
+		return false
+	}
+	for _, m := range mutes {
+		if m.Active(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// raise publishes the alert on the event bus and sends it to every notifier
+// in the background.
+func (a *Alerter) raise(kind string, o Outage, at time.Time) {
+	al := Alert{Kind: kind, CameraID: o.CameraID, CameraName: o.CameraID, Since: o.Since, At: at}
+	if cam, err := a.cameras.GetByID(o.CameraID); err == nil {
+		al.CameraName = cam.Name
+		al.Location = cam.Location
+	}
+
+	e := domain.Event{
+		Type:     events.AlertRaised,
+		CameraID: al.CameraID,
+		Severity: domain.SeverityCritical,
+		Time:     at,
+		Payload: map[string]interface{}{
+			"name":             al.CameraName,
+			"since":            al.Since.Format(time.RFC3339),
+			"duration_seconds": int(al.Duration().Seconds()),
+			"message":          al.Message(),
+		},
+	}
+	if kind == KindRecovered {
+		e.Type = events.AlertResolved
+		e.Severity = domain.SeverityInfo
+	}
+	events.Publish(e)
+	log.Printf("alert: %s", al.Message())
+
+	for _, n := range a.notifiers {
+		go func(n Notifier) {
+			ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+			defer cancel()
+			if err := n.Notify(ctx, al); err != nil {
+				log.Printf("alert: %s notifier failed for camera %s: %v", n.Name(), al.CameraID, err)
+			}
+		}(n)
+	}
+}
+
+// restore rebuilds outages for cameras that are offline at startup from the
+// event history so a restart neither forgets an outage nor pages twice.
+func (a *Alerter) restore() {
+	cams, err := a.cameras.List()
+	if err != nil {
+		log.Printf("alert: failed to list cameras: %v", err)
+		return
+	}
+
+	restored := make(map[string]*Outage)
+	for _, c := range cams {
+		switch c.Status {
+		case domain.CameraStatusOnline, domain.CameraStatusUnknown, domain.CameraStatusDisabled, domain.CameraStatusMaintenance:
+			continue
+		}
+		o := &Outage{CameraID: c.ID, Since: time.Now()}
+		if a.history != nil {
+			last, _, err := a.history.List(domain.EventFilter{CameraID: c.ID, Types: []string{events.CameraOffline}, Limit: 1})
+			if err == nil && len(last) == 1 {
+				o.Since = last[0].Time
+				raised, _, err := a.history.List(domain.EventFilter{CameraID: c.ID, Types: []string{events.AlertRaised}, AfterID: last[0].ID, Limit: 1})
+				if err == nil && len(raised) == 1 {
+					o.Alerted = true
+					at := raised[0].Time
+					o.AlertedAt = &at
+				}
+			}
+		}
+		restored[c.ID] = o
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for id, o := range restored {
+		a.outages[id] = o
+	}
+}
@@ -0,0 +1,120 @@
+package alert
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/events"
+	"github.com/boytur/cctv-recording-center/server/internal/repository"
+)
+
+// fakeAlertRepo returns fixed settings and mutes. onList, if set, is called
+// when mutes are loaded.
+type fakeAlertRepo struct {
+	repository.AlertRepository
+	settings domain.AlertSettings
+	mutes    []*domain.AlertMute
+	onList   func()
+}
+
+func (f *fakeAlertRepo) GetSettings() (*domain.AlertSettings, error) { return &f.settings, nil }
+
+func (f *fakeAlertRepo) ListMutes(cameraID string, _ time.Time) ([]*domain.AlertMute, error) {
+	if f.onList != nil {
+		f.onList()
+	}
+	var res []*domain.AlertMute
+	for _, m := range f.mutes {
+		if m.CameraID == cameraID {
+			res = append(res, m)
+		}
+	}
+	return res, nil
+}
+
+// recordingNotifier passes alerts to a channel.
+type recordingNotifier chan Alert
+
+func (n recordingNotifier) Name() string { return "test" }
+
+func (n recordingNotifier) Notify(_ context.Context, a Alert) error {
+	n <- a
+	return nil
+}
+
+func TestAlerterCheck(t *testing.T) {
+	t0 := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	repo := &fakeAlertRepo{
+		settings: domain.AlertSettings{Enabled: true, GracePeriod: 5 * time.Minute},
+		mutes:    []*domain.AlertMute{{CameraID: "yard", Start: t0, End: t0.Add(time.Hour)}},
+	}
+	cameras := &fakeCameras{cameras: map[string]*domain.Camera{"lobby": {ID: "lobby", Name: "Lobby", Location: "Ground floor"}}}
+	notified := make(recordingNotifier, 4)
+	a := NewAlerter(cameras, repo, nil, notified)
+
+	// the repositories must be called without the lock held
+	unlocked := func() {
+		if !a.mu.TryLock() {
+			t.Error("repository called with the alerter locked")
+			return
+		}
+		a.mu.Unlock()
+	}
+	repo.onList, cameras.onGet = unlocked, unlocked
+
+	ch, cancel := events.Subscribe(8, events.OfTypes(events.AlertRaised, events.AlertResolved))
+	defer cancel()
+	published := func() []domain.Event {
+		var es []domain.Event
+		for {
+			select {
+			case e := <-ch:
+				es = append(es, e)
+			default:
+				return es
+			}
+		}
+	}
+
+	a.handle(domain.Event{Type: events.CameraOffline, CameraID: "lobby", Time: t0})
+	a.handle(domain.Event{Type: events.CameraOffline, CameraID: "yard", Time: t0})
+
+	a.check(t0.Add(time.Minute))
+	if es := published(); len(es) != 0 {
+		t.Fatalf("alerted inside the grace period: %+v", es)
+	}
+
+	a.check(t0.Add(6 * time.Minute))
+	es := published()
+	if len(es) != 1 || es[0].Type != events.AlertRaised || es[0].CameraID != "lobby" {
+		t.Fatalf("published %+v, want one alert.raised for lobby", es)
+	}
+	if al := <-notified; al.Kind != KindOffline || al.CameraName != "Lobby" || al.Location != "Ground floor" {
+		t.Errorf("notified %+v", al)
+	}
+	for _, o := range a.Outages() {
+		if o.Alerted != (o.CameraID == "lobby") {
+			t.Errorf("outage %s Alerted = %v", o.CameraID, o.Alerted)
+		}
+	}
+
+	a.check(t0.Add(7 * time.Minute))
+	if es := published(); len(es) != 0 {
+		t.Errorf("alerted twice: %+v", es)
+	}
+
+	a.handle(domain.Event{Type: events.CameraOnline, CameraID: "lobby", Time: t0.Add(10 * time.Minute)})
+	a.handle(domain.Event{Type: events.CameraOnline, CameraID: "yard", Time: t0.Add(10 * time.Minute)})
+	es = published()
+	if len(es) != 1 || es[0].Type != events.AlertResolved || es[0].CameraID != "lobby" {
+		t.Fatalf("published %+v, want one alert.resolved for lobby", es)
+	}
+	if al := <-notified; al.Kind != KindRecovered || al.Duration() != 10*time.Minute {
+		t.Errorf("notified %+v", al)
+	}
+	if o := a.Outages(); len(o) != 0 {
+		t.Errorf("outages left after recovery: %+v", o)
+	}
+}
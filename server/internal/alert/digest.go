@@ -0,0 +1,138 @@
+package alert
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/events"
+	"github.com/boytur/cctv-recording-center/server/internal/segment"
+)
+
+// Digest summarises camera health and recording coverage over a window.
+type Digest struct {
+	From    time.Time
+	To      time.Time
+	Cameras []CameraDigest
+}
+
+// CameraDigest is one camera's line in a Digest.
+type CameraDigest struct {
+	Camera       *domain.Camera
+	Availability *domain.Availability
+	Recorded     time.Duration
+	Gaps         int
+	LongestGap   time.Duration
+	Storage      int64
+	Alerts       int64
+}
+
+// Availability computes a camera's uptime over a range. It is the same
+// calculation as the availability reports, so the digest agrees with them.
+type Availability interface {
+	CameraAvailability(cameraID string, from, to time.Time) (*domain.Availability, error)
+}
+
+// BuildDigest collects the digest for cameras over [from, to). Uptime comes
+// from the status history, coverage from the continuous recordings on disk.
+func BuildDigest(cams []*domain.Camera, history EventHistory, availability Availability, from, to time.Time) (*Digest, error) {
+	d := &Digest{From: from, To: to}
+	for _, c := range cams {
+		cd := CameraDigest{Camera: c}
+
+		a, err := availability.CameraAvailability(c.ID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		cd.Availability = a
+
+		segs, err := segment.Range(c.ID, from, to, segment.KindContinuous)
+		if err != nil {
+			return nil, err
+		}
+		spans := segment.Clip(segment.Merge(segs, 2*time.Second), from, to)
+		for _, s := range spans {
+			cd.Recorded += s.Duration()
+		}
+		for _, g := range segment.Gaps(spans, from, to) {
+			cd.Gaps++
+			if g.Duration() > cd.LongestGap {
+				cd.LongestGap = g.Duration()
+			}
+		}
+
+		if cd.Storage, err = segment.Usage(c.ID); err != nil {
+			return nil, err
+		}
+
+		_, alerts, err := history.List(domain.EventFilter{CameraID: c.ID, Types: []string{events.AlertRaised}, From: from, To: to, Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+		cd.Alerts = alerts
+
+		d.Cameras = append(d.Cameras, cd)
+	}
+	sort.Slice(d.Cameras, func(i, j int) bool { return d.Cameras[i].Camera.Name < d.Cameras[j].Camera.Name })
+	return d, nil
+}
+
+// Text renders the digest as a plain text email body.
+func (d *Digest) Text() string {
+	window := d.To.Sub(d.From)
+	var b strings.Builder
+	fmt.Fprintf(&b, "Daily digest for %s to %s\n\n",
+		d.From.Local().Format("2006-01-02 15:04"), d.To.Local().Format("2006-01-02 15:04"))
+	if len(d.Cameras) == 0 {
+		b.WriteString("No cameras.\n")
+		return b.String()
+	}
+
+	var storage int64
+	var alerts int64
+	for _, c := range d.Cameras {
+		storage += c.Storage
+		alerts += c.Alerts
+	}
+	fmt.Fprintf(&b, "Cameras: %d   Alerts: %d   Storage used: %s\n\n", len(d.Cameras), alerts, formatBytes(storage))
+
+	for _, c := range d.Cameras {
+		fmt.Fprintf(&b, "%s", c.Camera.Name)
+		if c.Camera.Location != "" {
+			fmt.Fprintf(&b, " (%s)", c.Camera.Location)
+		}
+		b.WriteString("\n")
+		if a := c.Availability; a.MonitoredSeconds > 0 {
+			fmt.Fprintf(&b, "  Uptime:    %.1f%% (offline %s", a.UptimePercent, time.Duration(a.DowntimeSeconds)*time.Second)
+			if a.OutageCount > 0 {
+				fmt.Fprintf(&b, ", %d outage(s)", a.OutageCount)
+			}
+			b.WriteString(")\n")
+		} else {
+			b.WriteString("  Uptime:    not monitored\n")
+		}
+		fmt.Fprintf(&b, "  Recorded:  %s of %s", c.Recorded.Round(time.Second), window.Round(time.Second))
+		if c.Gaps > 0 {
+			fmt.Fprintf(&b, ", %d gap(s), longest %s", c.Gaps, c.LongestGap.Round(time.Second))
+		}
+		b.WriteString("\n")
+		fmt.Fprintf(&b, "  Storage:   %s\n", formatBytes(c.Storage))
+		fmt.Fprintf(&b, "  Alerts:    %d\n\n", c.Alerts)
+	}
+	return b.String()
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
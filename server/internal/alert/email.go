@@ -0,0 +1,219 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/mail"
+	"github.com/boytur/cctv-recording-center/server/internal/repository"
+	"github.com/boytur/cctv-recording-center/server/internal/snapshot"
+)
+
+// subjectPrefix starts every email subject so mail can be filtered.
+const subjectPrefix = "[CCTV] "
+
+// latestSnapshot takes the frame attached to alert emails; tests replace it.
+var latestSnapshot = snapshot.Latest
+
+// EmailNotifier emails alerts to the recipient groups that want them and
+// sends each digest group a daily summary.
+type EmailNotifier struct {
+	config       mail.Config
+	groups       repository.EmailGroupRepository
+	cameras      repository.CameraRepository
+	history      EventHistory
+	availability Availability
+
+	// digestAt is the local time of day the digest is sent, as an offset
+	// from midnight.
+	digestAt time.Duration
+	stopChan chan struct{}
+}
+
+// NewEmailNotifier creates an email notifier. The digest is sent daily at
+// the time given by SMTP_DIGEST_TIME ("15:04", default 08:00).
+func NewEmailNotifier(cfg mail.Config, groups repository.EmailGroupRepository, cameras repository.CameraRepository, history EventHistory, availability Availability) *EmailNotifier {
+	n := &EmailNotifier{
+		config:       cfg,
+		groups:       groups,
+		cameras:      cameras,
+		history:      history,
+		availability: availability,
+		digestAt:     8 * time.Hour,
+		stopChan:     make(chan struct{}),
+	}
+	if v := os.Getenv("SMTP_DIGEST_TIME"); v != "" {
+		if t, err := time.Parse("15:04", v); err == nil {
+			n.digestAt = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+		} else {
+			log.Printf("alert: invalid SMTP_DIGEST_TIME %q, using 08:00", v)
+		}
+	}
+	return n
+}
+
+// Enabled reports whether SMTP is configured.
+func (n *EmailNotifier) Enabled() bool {
+	return n.config.Enabled()
+}
+
+func (n *EmailNotifier) Name() string { return "email" }
+
+// Notify emails the alert to every group with alerts enabled that covers the
+// camera. Groups asking for it get the latest snapshot attached.
+func (n *EmailNotifier) Notify(ctx context.Context, a Alert) error {
+	groups, err := n.groups.List()
+	if err != nil {
+		return err
+	}
+
+	var snap []byte
+	snapLoaded := false
+	var errs []error
+	for _, g := range groups {
+		if !g.Alerts || !g.Covers(a.CameraID) || len(g.Recipients) == 0 {
+			continue
+		}
+		msg := mail.Message{
+			To:      g.Recipients,
+			Subject: subjectPrefix + a.Subject(),
+			Text:    alertText(a),
+		}
+		if g.AttachSnapshot {
+			if !snapLoaded {
+				snap = n.snapshot(ctx, a.CameraID)
+				snapLoaded = true
+			}
+			if snap != nil {
+				msg.Attachments = []mail.Attachment{{
+					Name:        fmt.Sprintf("%s_%s.jpg", a.CameraID, a.At.Local().Format("20060102_150405")),
+					ContentType: "image/jpeg",
+					Data:        snap,
+				}}
+			}
+		}
+		if err := mail.Send(ctx, n.config, msg); err != nil {
+			errs = append(errs, fmt.Errorf("group %s: %w", g.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// snapshot returns the latest frame for a camera, or nil if none could be
+// taken. An offline camera yields the last recorded frame.
+func (n *EmailNotifier) snapshot(ctx context.Context, cameraID string) []byte {
+	cam, err := n.cameras.GetByID(cameraID)
+	if err != nil {
+		return nil
+	}
+	snap, err := latestSnapshot(ctx, cam.ID, cam.RTSPURL, cam.Username, cam.Password)
+	if err != nil {
+		log.Printf("alert: no snapshot for camera %s: %v", cameraID, err)
+		return nil
+	}
+	return snap.Data
+}
+
+// SendTest sends a short test message to a group.
+func (n *EmailNotifier) SendTest(ctx context.Context, g *domain.EmailGroup) error {
+	return mail.Send(ctx, n.config, mail.Message{
+		To:      g.Recipients,
+		Subject: subjectPrefix + "Test email",
+		Text: fmt.Sprintf("This is a test email for the recipient group %q.\n\nSent %s.\n",
+			g.Name, time.Now().Format("2006-01-02 15:04:05")),
+	})
+}
+
+// SendDigest emails a group the digest for the 24 hours up to now.
+func (n *EmailNotifier) SendDigest(ctx context.Context, g *domain.EmailGroup) error {
+	cams, err := n.cameras.List()
+	if err != nil {
+		return err
+	}
+	var covered []*domain.Camera
+	for _, c := range cams {
+		if g.Covers(c.ID) {
+			covered = append(covered, c)
+		}
+	}
+	to := time.Now()
+	d, err := BuildDigest(covered, n.history, n.availability, to.Add(-24*time.Hour), to)
+	if err != nil {
+		return err
+	}
+	return mail.Send(ctx, n.config, mail.Message{
+		To:      g.Recipients,
+		Subject: subjectPrefix + "Daily digest " + to.Format("2006-01-02"),
+		Text:    d.Text(),
+	})
+}
+
+// StartDigest sends the digest to every digest group once a day.
+func (n *EmailNotifier) StartDigest() {
+	go func() {
+		for {
+			timer := time.NewTimer(time.Until(n.nextDigest(time.Now())))
+			select {
+			case <-timer.C:
+				n.sendDigests()
+			case <-n.stopChan:
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the digest schedule.
+func (n *EmailNotifier) Stop() {
+	close(n.stopChan)
+}
+
+func (n *EmailNotifier) nextDigest(now time.Time) time.Time {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	next := midnight.Add(n.digestAt)
+	if !next.After(now) {
+		next = midnight.AddDate(0, 0, 1).Add(n.digestAt)
+	}
+	return next
+}
+
+func (n *EmailNotifier) sendDigests() {
+	groups, err := n.groups.List()
+	if err != nil {
+		log.Printf("alert: failed to list email groups: %v", err)
+		return
+	}
+	for _, g := range groups {
+		if !g.Digest || len(g.Recipients) == 0 {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		if err := n.SendDigest(ctx, g); err != nil {
+			log.Printf("alert: failed to send digest to group %s: %v", g.Name, err)
+		}
+		cancel()
+	}
+}
+
+func alertText(a Alert) string {
+	var b strings.Builder
+	b.WriteString(a.Message())
+	b.WriteString("\n\n")
+	fmt.Fprintf(&b, "Camera:   %s\n", a.CameraName)
+	if a.Location != "" {
+		fmt.Fprintf(&b, "Location: %s\n", a.Location)
+	}
+	fmt.Fprintf(&b, "Offline:  %s\n", a.Since.Local().Format("2006-01-02 15:04:05"))
+	if a.Kind == KindRecovered {
+		fmt.Fprintf(&b, "Online:   %s\n", a.At.Local().Format("2006-01-02 15:04:05"))
+	}
+	fmt.Fprintf(&b, "Duration: %s\n", a.Duration())
+	return b.String()
+}
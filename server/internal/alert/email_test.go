@@ -0,0 +1,162 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	cctvmail "github.com/boytur/cctv-recording-center/server/internal/mail"
+	"github.com/boytur/cctv-recording-center/server/internal/mail/mailtest"
+	"github.com/boytur/cctv-recording-center/server/internal/repository"
+	"github.com/boytur/cctv-recording-center/server/internal/snapshot"
+)
+
+// fakeGroups serves a fixed list of email groups.
+type fakeGroups struct {
+	repository.EmailGroupRepository
+	groups []*domain.EmailGroup
+}
+
+func (f *fakeGroups) List() ([]*domain.EmailGroup, error) { return f.groups, nil }
+
+// fakeCameras knows the cameras in the map. onGet, if set, is called on
+// every lookup.
+type fakeCameras struct {
+	repository.CameraRepository
+	cameras map[string]*domain.Camera
+	onGet   func()
+}
+
+func (f *fakeCameras) GetByID(id string) (*domain.Camera, error) {
+	if f.onGet != nil {
+		f.onGet()
+	}
+	if c, ok := f.cameras[id]; ok {
+		return c, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func TestEmailNotifierNotify(t *testing.T) {
+	srv, err := mailtest.NewServer(mailtest.Options{})
+	if err != nil {
+		t.Fatalf("mailtest.NewServer: %v", err)
+	}
+	defer srv.Close()
+
+	frame := []byte{0xff, 0xd8, 0xff, 0xd9}
+	var grabs []string
+	latestSnapshot = func(_ context.Context, cameraID, _, _, _ string) (*snapshot.Snapshot, error) {
+		grabs = append(grabs, cameraID)
+		return &snapshot.Snapshot{CameraID: cameraID, Data: frame}, nil
+	}
+	defer func() { latestSnapshot = snapshot.Latest }()
+
+	groups := &fakeGroups{groups: []*domain.EmailGroup{
+		{Name: "everyone", Recipients: []string{"ops@example.com", "boss@example.com"}, Alerts: true},
+		{Name: "lobby with snapshot", Recipients: []string{"lobby@example.com"}, CameraIDs: []string{"lobby"}, Alerts: true, AttachSnapshot: true},
+		{Name: "lobby again", Recipients: []string{"guard@example.com"}, CameraIDs: []string{"yard", "lobby"}, Alerts: true, AttachSnapshot: true},
+		{Name: "yard only", Recipients: []string{"yard@example.com"}, CameraIDs: []string{"yard"}, Alerts: true, AttachSnapshot: true},
+		{Name: "digest only", Recipients: []string{"digest@example.com"}, Digest: true},
+		{Name: "no recipients", Alerts: true},
+	}}
+	cameras := &fakeCameras{cameras: map[string]*domain.Camera{"lobby": {ID: "lobby", Name: "Lobby"}}}
+	cfg := cctvmail.Config{Host: srv.Host, Port: srv.Port, From: "cctv@example.com", TLS: cctvmail.TLSNone}
+	n := NewEmailNotifier(cfg, groups, cameras, nil, nil)
+
+	since := time.Date(2026, 10, 19, 8, 0, 0, 0, time.Local)
+	a := Alert{Kind: KindOffline, CameraID: "lobby", CameraName: "Lobby", Since: since, At: since.Add(5 * time.Minute)}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := n.Notify(ctx, a); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	if !reflect.DeepEqual(grabs, []string{"lobby"}) {
+		t.Errorf("snapshots taken = %v, want one of lobby shared by both groups", grabs)
+	}
+
+	type sent struct {
+		to          string
+		attachments []string
+	}
+	var got []sent
+	for _, m := range srv.Messages() {
+		msg, err := mail.ReadMessage(strings.NewReader(m.Data))
+		if err != nil {
+			t.Fatalf("ReadMessage: %v", err)
+		}
+		if s := msg.Header.Get("Subject"); s != `[CCTV] Camera "Lobby" is offline` {
+			t.Errorf("Subject = %q", s)
+		}
+		got = append(got, sent{strings.Join(m.To, ","), attachments(t, msg)})
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i].to < got[j].to })
+
+	want := []sent{
+		{"guard@example.com", []string{"lobby_20261019_080500.jpg"}},
+		{"lobby@example.com", []string{"lobby_20261019_080500.jpg"}},
+		{"ops@example.com,boss@example.com", nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sent =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestEmailNotifierNotifyErrors(t *testing.T) {
+	srv, err := mailtest.NewServer(mailtest.Options{Reject: []string{"gone@example.com"}})
+	if err != nil {
+		t.Fatalf("mailtest.NewServer: %v", err)
+	}
+	defer srv.Close()
+
+	groups := &fakeGroups{groups: []*domain.EmailGroup{
+		{Name: "broken", Recipients: []string{"gone@example.com"}, Alerts: true},
+		{Name: "ops", Recipients: []string{"ops@example.com"}, Alerts: true},
+	}}
+	cfg := cctvmail.Config{Host: srv.Host, Port: srv.Port, From: "cctv@example.com", TLS: cctvmail.TLSNone}
+	n := NewEmailNotifier(cfg, groups, &fakeCameras{}, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = n.Notify(ctx, Alert{Kind: KindRecovered, CameraID: "lobby", CameraName: "Lobby", Since: time.Now().Add(-time.Hour), At: time.Now()})
+	if err == nil || !strings.Contains(err.Error(), "group broken") {
+		t.Errorf("Notify = %v, want an error for group broken", err)
+	}
+	// a failing group doesn't stop the others
+	if msgs := srv.Messages(); len(msgs) != 1 || msgs[0].To[0] != "ops@example.com" {
+		t.Errorf("sent %+v, want one message to ops@example.com", msgs)
+	}
+}
+
+// attachments returns the file names attached to a message.
+func attachments(t *testing.T, msg *mail.Message) []string {
+	t.Helper()
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		return nil
+	}
+	var names []string
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return names
+		}
+		if err != nil {
+			t.Fatalf("NextPart: %v", err)
+		}
+		if name := p.FileName(); name != "" {
+			names = append(names, name)
+		}
+	}
+}
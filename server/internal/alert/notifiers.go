@@ -0,0 +1,124 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// NotifiersFromEnv builds the notifiers configured through environment
+// variables:
+//
+//	ALERT_WEBHOOK_URL                               JSON POST of the alert
+//	ALERT_SLACK_WEBHOOK_URL                         Slack-compatible incoming webhook
+//	ALERT_TELEGRAM_BOT_TOKEN, ALERT_TELEGRAM_CHAT_ID Telegram bot message
+func NotifiersFromEnv() []Notifier {
+	var ns []Notifier
+	if u := os.Getenv("ALERT_WEBHOOK_URL"); u != "" {
+		ns = append(ns, &WebhookNotifier{URL: u})
+	}
+	if u := os.Getenv("ALERT_SLACK_WEBHOOK_URL"); u != "" {
+		ns = append(ns, &SlackNotifier{WebhookURL: u})
+	}
+	token, chat := os.Getenv("ALERT_TELEGRAM_BOT_TOKEN"), os.Getenv("ALERT_TELEGRAM_CHAT_ID")
+	if token != "" && chat != "" {
+		ns = append(ns, &TelegramNotifier{Token: token, ChatID: chat})
+	}
+	return ns
+}
+
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+// WebhookNotifier posts the alert as JSON to a URL.
+type WebhookNotifier struct {
+	URL string
+}
+
+func (n *WebhookNotifier) Name() string { return "webhook" }
+
+func (n *WebhookNotifier) Notify(ctx context.Context, a Alert) error {
+	return postJSON(ctx, n.URL, map[string]interface{}{
+		"kind":             a.Kind,
+		"camera_id":        a.CameraID,
+		"camera_name":      a.CameraName,
+		"location":         a.Location,
+		"since":            a.Since.Format(time.RFC3339),
+		"at":               a.At.Format(time.RFC3339),
+		"duration_seconds": int(a.Duration().Seconds()),
+		"subject":          a.Subject(),
+		"message":          a.Message(),
+	})
+}
+
+// SlackNotifier posts to a Slack incoming webhook. Mattermost, Rocket.Chat
+// and Discord (with a /slack suffix) accept the same payload.
+type SlackNotifier struct {
+	WebhookURL string
+}
+
+func (n *SlackNotifier) Name() string { return "slack" }
+
+func (n *SlackNotifier) Notify(ctx context.Context, a Alert) error {
+	icon := ":red_circle:"
+	if a.Kind == KindRecovered {
+		icon = ":large_green_circle:"
+	}
+	return postJSON(ctx, n.WebhookURL, map[string]string{
+		"text": fmt.Sprintf("%s *%s*\n%s", icon, a.Subject(), a.Message()),
+	})
+}
+
+// TelegramNotifier sends a message through a Telegram bot.
+type TelegramNotifier struct {
+	Token  string
+	ChatID string
+}
+
+func (n *TelegramNotifier) Name() string { return "telegram" }
+
+func (n *TelegramNotifier) Notify(ctx context.Context, a Alert) error {
+	form := url.Values{
+		"chat_id": {n.ChatID},
+		"text":    {a.Subject() + "\n" + a.Message()},
+	}
+	endpoint := "https://api.telegram.org/bot" + n.Token + "/sendMessage"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return do(req)
+}
+
+func postJSON(ctx context.Context, url string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return do(req)
+}
+
+func do(req *http.Request) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(snippet))
+	}
+	return nil
+}
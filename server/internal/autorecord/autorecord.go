@@ -2,111 +2,226 @@ package autorecord
 
 import (
 	"log"
+	"sync"
 	"time"
 
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/events"
 	"github.com/boytur/cctv-recording-center/server/internal/recorder"
 	"github.com/boytur/cctv-recording-center/server/internal/repository"
 )
 
-// Manager handles automatic recording for online cameras
+// restartDelay gives ffmpeg time to finalise the last segment before a
+// session is started again for the same camera.
+const restartDelay = 2 * time.Second
+
+// Manager keeps a continuous recording running for every online camera. It
+// reconciles a camera as soon as its status or settings change and resyncs
+// all cameras every interval to catch anything missed. Cameras are
+// reconciled independently, so a slow start or stop on one camera does not
+// hold up the others.
 type Manager struct {
 	repo     repository.CameraRepository
 	interval time.Duration
 	stopChan chan struct{}
+	done     chan struct{}
+
+	mu      sync.Mutex
+	running map[string]bool
+	pending map[string]bool
+	wg      sync.WaitGroup
 }
 
-// NewManager creates a new auto-record manager
-func NewManager(repo repository.CameraRepository, checkInterval time.Duration) *Manager {
+// NewManager creates a new auto-record manager. resyncInterval is the time
+// between full passes over all cameras.
+func NewManager(repo repository.CameraRepository, resyncInterval time.Duration) *Manager {
 	return &Manager{
 		repo:     repo,
-		interval: checkInterval,
+		interval: resyncInterval,
 		stopChan: make(chan struct{}),
+		done:     make(chan struct{}),
+		running:  make(map[string]bool),
+		pending:  make(map[string]bool),
 	}
 }
 
-// Start begins the automatic recording monitor
+// Start begins reconciling recordings.
 func (m *Manager) Start() {
-	go m.monitor()
+	ch, cancel := events.Subscribe(64, events.OfTypes(
+		events.CameraOnline, events.CameraOffline, events.CameraOutOfService,
+		events.CameraCreated, events.CameraUpdated, events.CameraDeleted,
+	))
+	go m.run(ch, cancel)
 }
 
-// Stop stops the automatic recording monitor
+// Stop stops reconciling and waits for starts and stops in progress.
+// Running recordings are left alone.
 func (m *Manager) Stop() {
 	close(m.stopChan)
+	<-m.done
+	m.wg.Wait()
 }
 
-func (m *Manager) monitor() {
+func (m *Manager) run(ch <-chan domain.Event, cancel func()) {
+	defer close(m.done)
+	defer cancel()
 	ticker := time.NewTicker(m.interval)
 	defer ticker.Stop()
 
 	// Run immediately on start
-	m.checkAndRecord()
+	m.resync()
 
 	for {
 		select {
+		case e := <-ch:
+			m.trigger(e.CameraID)
 		case <-ticker.C:
-			m.checkAndRecord()
+			m.resync()
 		case <-m.stopChan:
 			return
 		}
 	}
 }
 
-func (m *Manager) checkAndRecord() {
+// resync reconciles every camera, and every recording whose camera is gone.
+func (m *Manager) resync() {
 	cameras, err := m.repo.List()
 	if err != nil {
 		log.Printf("auto-record: failed to list cameras: %v", err)
 		return
 	}
-
+	known := make(map[string]bool, len(cameras))
 	for _, cam := range cameras {
-		// Only record if camera is online
-		if cam.Status != "online" {
-			// If camera is offline and was recording, stop it
-			if recorder.IsRecording(cam.ID) {
-				log.Printf("auto-record: camera %s (%s) went offline, stopping recording", cam.ID, cam.Name)
-				if err := recorder.StopRecording(cam.ID); err != nil {
-					log.Printf("auto-record: failed to stop recording for %s: %v", cam.ID, err)
-				}
-			}
-			continue
+		known[cam.ID] = true
+		m.trigger(cam.ID)
+	}
+	for _, session := range recorder.GetActiveRecordings() {
+		if !known[session.CameraID] {
+			m.trigger(session.CameraID)
 		}
+	}
+}
 
-		// Check if already recording
-		if recorder.IsRecording(cam.ID) {
-			// Check if we need to restart for new day
-			sessions := recorder.GetActiveRecordings()
-			for _, session := range sessions {
-				if session.CameraID == cam.ID {
-					// Check if recording started on a different day
-					if !isSameDay(session.StartTime, time.Now()) {
-						log.Printf("auto-record: new day detected for camera %s (%s), restarting recording", cam.ID, cam.Name)
-						// Stop current recording
-						if err := recorder.StopRecording(cam.ID); err != nil {
-							log.Printf("auto-record: failed to stop recording for %s: %v", cam.ID, err)
-							continue
-						}
-						// Wait a moment before restarting
-						time.Sleep(2 * time.Second)
-						// Start new recording
-						if err := recorder.StartRecording(cam.ID, cam.Name, cam.RTSPURL, cam.Username, cam.Password); err != nil {
-							log.Printf("auto-record: failed to restart recording for %s: %v", cam.ID, err)
-						} else {
-							log.Printf("auto-record: restarted recording for camera %s (%s) for new day", cam.ID, cam.Name)
-						}
-					}
-					break
-				}
+// trigger reconciles a camera in the background. If the camera is already
+// being reconciled it is done once more afterwards, so the latest change is
+// never lost and a camera is never started or stopped twice at once.
+func (m *Manager) trigger(cameraID string) {
+	if cameraID == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.running[cameraID] {
+		m.pending[cameraID] = true
+		return
+	}
+	m.running[cameraID] = true
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		for {
+			m.reconcile(cameraID)
+			m.mu.Lock()
+			if m.pending[cameraID] {
+				delete(m.pending, cameraID)
+				m.mu.Unlock()
+				continue
 			}
-			continue
+			delete(m.running, cameraID)
+			m.mu.Unlock()
+			return
+		}
+	}()
+}
+
+// reconcile starts, stops or restarts a camera's recording to match its
+// current status and settings.
+func (m *Manager) reconcile(cameraID string) {
+	cam, err := m.repo.GetByID(cameraID)
+	if err != nil {
+		if !m.deleted(cameraID) {
+			log.Printf("auto-record: failed to load camera %s: %v", cameraID, err)
+			return
 		}
+		cam = nil
+	}
+	session, recording := activeSession(cameraID)
 
-		// Camera is online but not recording, start recording
+	switch {
+	case cam == nil:
+		if recording {
+			log.Printf("auto-record: camera %s was deleted, stopping recording", cameraID)
+			stop(cameraID)
+		}
+	case cam.ServiceStatus(time.Now()) != "":
+		if recording {
+			log.Printf("auto-record: camera %s (%s) is out of service, stopping recording", cam.ID, cam.Name)
+			stop(cam.ID)
+		}
+	case cam.Status != domain.CameraStatusOnline:
+		if recording {
+			log.Printf("auto-record: camera %s (%s) went %s, stopping recording", cam.ID, cam.Name, cam.Status)
+			stop(cam.ID)
+		}
+	case !recording:
 		log.Printf("auto-record: starting automatic recording for camera %s (%s)", cam.ID, cam.Name)
 		if err := recorder.StartRecording(cam.ID, cam.Name, cam.RTSPURL, cam.Username, cam.Password); err != nil {
 			log.Printf("auto-record: failed to start recording for %s: %v", cam.ID, err)
 		} else {
 			log.Printf("auto-record: successfully started recording for camera %s (%s)", cam.ID, cam.Name)
 		}
+	case session.RTSPURL != cam.RTSPURL:
+		log.Printf("auto-record: stream URL of camera %s (%s) changed, restarting recording", cam.ID, cam.Name)
+		restart(cam)
+	case !isSameDay(session.StartTime, time.Now()):
+		log.Printf("auto-record: new day detected for camera %s (%s), restarting recording", cam.ID, cam.Name)
+		restart(cam)
+	}
+}
+
+// deleted reports whether a camera that failed to load is really gone
+// rather than the lookup having failed.
+func (m *Manager) deleted(cameraID string) bool {
+	cameras, err := m.repo.List()
+	if err != nil {
+		return false
+	}
+	for _, c := range cameras {
+		if c.ID == cameraID {
+			return false
+		}
+	}
+	return true
+}
+
+func activeSession(cameraID string) (recorder.RecordingSession, bool) {
+	if !recorder.IsRecording(cameraID) {
+		return recorder.RecordingSession{}, false
+	}
+	for _, session := range recorder.GetActiveRecordings() {
+		if session.CameraID == cameraID {
+			return session, true
+		}
+	}
+	return recorder.RecordingSession{}, false
+}
+
+func stop(cameraID string) {
+	if err := recorder.StopRecording(cameraID); err != nil {
+		log.Printf("auto-record: failed to stop recording for %s: %v", cameraID, err)
+	}
+}
+
+func restart(cam *domain.Camera) {
+	if err := recorder.StopRecording(cam.ID); err != nil {
+		log.Printf("auto-record: failed to stop recording for %s: %v", cam.ID, err)
+		return
+	}
+	time.Sleep(restartDelay)
+	if err := recorder.StartRecording(cam.ID, cam.Name, cam.RTSPURL, cam.Username, cam.Password); err != nil {
+		log.Printf("auto-record: failed to restart recording for %s: %v", cam.ID, err)
+	} else {
+		log.Printf("auto-record: restarted recording for camera %s (%s)", cam.ID, cam.Name)
 	}
 }
 
@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// DefaultAlertGracePeriod is how long a camera must stay offline before an
+// alert is raised when no setting has been saved.
+const DefaultAlertGracePeriod = 3 * time.Minute
+
+// AlertSettings are the global offline alerting settings.
+type AlertSettings struct {
+	Enabled     bool          `json:"enabled"`
+	GracePeriod time.Duration `json:"-"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// AlertMute silences alerts for a camera between Start and End, e.g. during
+// planned maintenance.
+type AlertMute struct {
+	ID        string    `json:"id"`
+	CameraID  string    `json:"camera_id"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Active reports whether the mute window covers t.
+func (m *AlertMute) Active(t time.Time) bool {
+	return !t.Before(m.Start) && t.Before(m.End)
+}
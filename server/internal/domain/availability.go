@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// StatusChange is a recorded camera status transition.
+type StatusChange struct {
+	ID       uint64 `json:"id"`
+	CameraID string `json:"camera_id"`
+	Status   string `json:"status"`
+	Previous string `json:"previous_status"`
+	// Reason explains a status other than online, e.g. the probe error.
+	Reason string    `json:"reason,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// OutageInterval is a period a camera was not online. Status is the status
+// the outage started with.
+type OutageInterval struct {
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	Status          string    `json:"status"`
+	DurationSeconds int64     `json:"duration_seconds"`
+	// Ongoing is set when the camera was still down at the end of the
+	// range; End is then the end of the range, or now.
+	Ongoing bool `json:"ongoing"`
+}
+
+// Availability summarises a camera's status history over a time range.
+// Periods before the camera was first probed are not counted.
+type Availability struct {
+	CameraID         string    `json:"camera_id"`
+	CameraName       string    `json:"camera_name"`
+	From             time.Time `json:"from"`
+	To               time.Time `json:"to"`
+	MonitoredSeconds int64     `json:"monitored_seconds"`
+	DowntimeSeconds  int64     `json:"downtime_seconds"`
+	UptimePercent    float64   `json:"uptime_percent"`
+	OutageCount      int       `json:"outage_count"`
+	// MTTRSeconds is the mean time to recovery of the outages that ended
+	// within the range.
+	MTTRSeconds int64            `json:"mttr_seconds"`
+	Outages     []OutageInterval `json:"outages"`
+	// SLA fields are set when a report is checked against a target.
+	SLATarget *float64 `json:"sla_target,omitempty"`
+	Compliant *bool    `json:"compliant,omitempty"`
+}
@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// Bookmark marks a moment, or a span when EndTime is set, on a camera's
+// recording timeline.
+type Bookmark struct {
+	ID          string     `json:"id"`
+	CameraID    string     `json:"camera_id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Tags        []string   `json:"tags"`
+	Time        time.Time  `json:"time"`
+	EndTime     *time.Time `json:"end_time,omitempty"`
+	CreatedBy   string     `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// BookmarkFilter narrows a bookmark search. Zero fields are ignored.
+type BookmarkFilter struct {
+	CameraID string
+	From     time.Time
+	To       time.Time
+	// Query matches title or description.
+	Query string
+	Tag   string
+	Limit int
+}
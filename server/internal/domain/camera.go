@@ -1,5 +1,36 @@
 package domain
 
+import (
+	"fmt"
+	"time"
+)
+
+// Camera statuses. The monitor sets everything except unknown, which is the
+// status of a camera that has not been probed yet.
+const (
+	CameraStatusUnknown = "unknown"
+	CameraStatusOnline  = "online"
+	// CameraStatusOffline is the generic offline status written by older
+	// versions; the monitor now records why a camera is not online.
+	CameraStatusOffline = "offline"
+	// CameraStatusUnreachable: no TCP connection to the RTSP port.
+	CameraStatusUnreachable = "unreachable"
+	// CameraStatusUnresponsive: the port accepts connections but no valid
+	// RTSP reply arrives in time, e.g. a hung encoder.
+	CameraStatusUnresponsive = "unresponsive"
+	// CameraStatusAuthFailed: the camera rejected the credentials.
+	CameraStatusAuthFailed = "auth_failed"
+	// CameraStatusStreamNotFound: the stream path does not exist or
+	// describes no media.
+	CameraStatusStreamNotFound = "stream_not_found"
+	// CameraStatusError: any other RTSP failure.
+	CameraStatusError = "error"
+	// CameraStatusDisabled and CameraStatusMaintenance: the camera is out of
+	// service and not probed, recorded or alerted on.
+	CameraStatusDisabled    = "disabled"
+	CameraStatusMaintenance = "maintenance"
+)
+
 // Camera represents the domain model for a camera.
 type Camera struct {
 	ID       string `json:"id"`
@@ -9,4 +40,118 @@ type Camera struct {
 	Username string `json:"username,omitempty"`
 	Password string `json:"password,omitempty"`
 	Status   string `json:"status"`
+	// Enabled is false for a camera taken out of service indefinitely.
+	Enabled bool `json:"enabled"`
+	// MaintenanceUntil takes the camera out of service until then, e.g. for
+	// lens cleaning, with MaintenanceReason saying why.
+	MaintenanceUntil  *time.Time `json:"maintenance_until,omitempty"`
+	MaintenanceReason string     `json:"maintenance_reason,omitempty"`
+	// ProbeIntervalSeconds and ProbeTimeoutSeconds override the monitor
+	// defaults for this camera when non-zero.
+	ProbeIntervalSeconds int `json:"probe_interval_seconds"`
+	ProbeTimeoutSeconds  int `json:"probe_timeout_seconds"`
+	// LastProbeAt is when the camera was last probed. ProbeLatencyMs is how
+	// long that probe took to get the stream described, nil if the camera
+	// did not answer.
+	LastProbeAt    *time.Time `json:"last_probe_at,omitempty"`
+	ProbeLatencyMs *int64     `json:"probe_latency_ms,omitempty"`
+	// Hardware is the inventory information the camera reported over
+	// ONVIF, nil for cameras added by RTSP URL.
+	Hardware *DeviceInfo `json:"onvif_device,omitempty"`
+	// DeviceID is set for a channel of a multi-channel Device, whose
+	// Channel number, URL template and credentials make up the camera's
+	// RTSP URL and credentials.
+	DeviceID string `json:"device_id,omitempty"`
+	Channel  int    `json:"channel,omitempty"`
+	// Stream is what the camera was last seen sending, nil until it has
+	// been probed online.
+	Stream *StreamInfo `json:"stream,omitempty"`
+	// StreamChange describes the last unacknowledged change to the stream
+	// parameters, e.g. a drop in resolution.
+	StreamChange *StreamChange `json:"stream_change,omitempty"`
+}
+
+// InMaintenance reports whether a maintenance window is open at now.
+func (c *Camera) InMaintenance(now time.Time) bool {
+	return c.MaintenanceUntil != nil && now.Before(*c.MaintenanceUntil)
+}
+
+// ServiceStatus returns CameraStatusDisabled or CameraStatusMaintenance if
+// the camera is out of service at now, and "" if it is in service.
+func (c *Camera) ServiceStatus(now time.Time) string {
+	switch {
+	case !c.Enabled:
+		return CameraStatusDisabled
+	case c.InMaintenance(now):
+		return CameraStatusMaintenance
+	}
+	return ""
+}
+
+// DeviceInfo identifies the hardware behind a camera, as reported by its
+// ONVIF device service.
+type DeviceInfo struct {
+	Manufacturer    string `json:"manufacturer,omitempty"`
+	Model           string `json:"model,omitempty"`
+	FirmwareVersion string `json:"firmware_version,omitempty"`
+	SerialNumber    string `json:"serial_number,omitempty"`
+	HardwareID      string `json:"hardware_id,omitempty"`
+	// ONVIFAddress is the device service URL and ProfileToken the media
+	// profile the camera's RTSP URL was taken from.
+	ONVIFAddress string    `json:"onvif_address,omitempty"`
+	ProfileToken string    `json:"profile_token,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// StreamInfo describes the media a camera streams, as discovered from its
+// RTSP session description and recordings. Zero fields are unknown.
+type StreamInfo struct {
+	VideoCodec   string  `json:"video_codec,omitempty"`
+	VideoProfile string  `json:"video_profile,omitempty"`
+	Width        int     `json:"width,omitempty"`
+	Height       int     `json:"height,omitempty"`
+	FrameRate    float64 `json:"frame_rate,omitempty"`
+	// Bitrate is an estimate in kbit/s from the advertised bandwidth or
+	// the size of recent recordings.
+	Bitrate         int       `json:"bitrate_kbps,omitempty"`
+	AudioCodec      string    `json:"audio_codec,omitempty"`
+	AudioSampleRate int       `json:"audio_sample_rate,omitempty"`
+	ProbedAt        time.Time `json:"probed_at"`
+}
+
+// Resolution returns e.g. "1920x1080", or "" if unknown.
+func (s StreamInfo) Resolution() string {
+	if s.Width == 0 || s.Height == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%dx%d", s.Width, s.Height)
+}
+
+// Changes lists the parameters that differ from prev, ignoring fields
+// unknown on either side and the bitrate, which varies with the scene.
+func (s StreamInfo) Changes(prev StreamInfo) []string {
+	var changes []string
+	diff := func(name, old, cur string) {
+		if old != "" && cur != "" && old != cur {
+			changes = append(changes, fmt.Sprintf("%s %s -> %s", name, old, cur))
+		}
+	}
+	diff("video codec", prev.VideoCodec, s.VideoCodec)
+	diff("profile", prev.VideoProfile, s.VideoProfile)
+	diff("resolution", prev.Resolution(), s.Resolution())
+	if prev.FrameRate > 0 && s.FrameRate > 0 && prev.FrameRate != s.FrameRate {
+		changes = append(changes, fmt.Sprintf("frame rate %g -> %g", prev.FrameRate, s.FrameRate))
+	}
+	diff("audio codec", prev.AudioCodec, s.AudioCodec)
+	if prev.AudioSampleRate > 0 && s.AudioSampleRate > 0 && prev.AudioSampleRate != s.AudioSampleRate {
+		changes = append(changes, fmt.Sprintf("audio sample rate %d -> %d", prev.AudioSampleRate, s.AudioSampleRate))
+	}
+	return changes
+}
+
+// StreamChange records a change in what a camera streams.
+type StreamChange struct {
+	At       time.Time  `json:"at"`
+	Changes  []string   `json:"changes"`
+	Previous StreamInfo `json:"previous"`
 }
@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// Capture states.
+const (
+	// CaptureStarting: saved, ffmpeg not running yet.
+	CaptureStarting    = "starting"
+	CaptureRecording   = "recording"
+	CaptureCompleted   = "completed"
+	CaptureStopped     = "stopped"
+	CaptureFailed      = "failed"
+	CaptureInterrupted = "interrupted"
+)
+
+// Capture is a manual recording started by an operator, stored next to the
+// continuous recordings as a capture_*.mp4 file.
+type Capture struct {
+	ID        string    `json:"id"`
+	CameraID  string    `json:"camera_id"`
+	FileName  string    `json:"file_name"`
+	Operator  string    `json:"operator"`
+	Note      string    `json:"note"`
+	StartedAt time.Time `json:"started_at"`
+	// Duration is the requested length in seconds; 0 records until stopped.
+	Duration int `json:"duration_seconds"`
+	// StopAt is the scheduled stop time of a timed capture. It survives
+	// restarts so an interrupted capture can be resumed until then.
+	StopAt    *time.Time `json:"stop_at,omitempty"`
+	StoppedAt *time.Time `json:"stopped_at,omitempty"`
+	Status    string     `json:"status"`
+	// ResumedFrom links a capture restarted after a server restart to the
+	// capture it continues.
+	ResumedFrom string `json:"resumed_from,omitempty"`
+}
@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Device vendors with a built-in channel URL template.
+const (
+	VendorHikvision = "hikvision"
+	VendorDahua     = "dahua"
+	// VendorGeneric devices need their own URL template.
+	VendorGeneric = "generic"
+)
+
+// DeviceURLTemplates are the channel URL templates used when a device of a
+// known vendor has none of its own.
+var DeviceURLTemplates = map[string]string{
+	VendorHikvision: "rtsp://{host}/Streaming/Channels/{channel}0{stream}",
+	VendorDahua:     "rtsp://{host}/cam/realmonitor?channel={channel}&subtype={subtype}",
+}
+
+// Device streams.
+const (
+	DeviceStreamMain = "main"
+	DeviceStreamSub  = "sub"
+)
+
+// Device health statuses.
+const (
+	DeviceStatusUnknown = "unknown"
+	// DeviceStatusOnline: the device answers and every channel in service
+	// is online.
+	DeviceStatusOnline = "online"
+	// DeviceStatusDegraded: the device answers but some channels are not
+	// online.
+	DeviceStatusDegraded = "degraded"
+	// DeviceStatusOffline: the device answers but no channel is online.
+	DeviceStatusOffline = "offline"
+	// DeviceStatusUnreachable: no connection to the device's RTSP port.
+	DeviceStatusUnreachable = "unreachable"
+)
+
+// Device is a multi-channel recorder such as an NVR or DVR. Its channels are
+// cameras whose stream URL is generated from URLTemplate and whose
+// credentials are the device's.
+type Device struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Location string `json:"location"`
+	Vendor   string `json:"vendor"`
+	// Host is the device's address, with the RTSP port if not 554.
+	Host     string `json:"host"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// URLTemplate builds a channel's RTSP URL from {host}, {channel}, and
+	// the stream as {stream} (1 main, 2 sub) or {subtype} (0 main, 1 sub).
+	// Empty uses the vendor's template.
+	URLTemplate string `json:"url_template,omitempty"`
+	// Stream is the stream the channels use, main or sub.
+	Stream    string       `json:"stream"`
+	Health    DeviceHealth `json:"health"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// DeviceHealth is the state of a device as a whole: whether it can be
+// reached and how many of its channels in service are online.
+type DeviceHealth struct {
+	Status         string     `json:"status"`
+	Reason         string     `json:"reason,omitempty"`
+	ChannelsOnline int        `json:"channels_online"`
+	ChannelsTotal  int        `json:"channels_total"`
+	CheckedAt      *time.Time `json:"checked_at,omitempty"`
+}
+
+// Template returns the URL template in use, "" if there is none.
+func (d *Device) Template() string {
+	if d.URLTemplate != "" {
+		return d.URLTemplate
+	}
+	return DeviceURLTemplates[d.Vendor]
+}
+
+// ChannelURL returns the RTSP URL of a channel, without credentials.
+func (d *Device) ChannelURL(channel int) string {
+	stream, subtype := "1", "0"
+	if d.Stream == DeviceStreamSub {
+		stream, subtype = "2", "1"
+	}
+	return strings.NewReplacer(
+		"{host}", d.Host,
+		"{channel}", strconv.Itoa(channel),
+		"{stream}", stream,
+		"{subtype}", subtype,
+	).Replace(d.Template())
+}
+
+// ChannelName is the name a new channel camera gets.
+func (d *Device) ChannelName(channel int) string {
+	return d.Name + " CH" + strconv.Itoa(channel)
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// EmailGroup is a set of recipients for alert emails and the daily digest.
+// Empty CameraIDs means every camera.
+type EmailGroup struct {
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	Recipients     []string  `json:"recipients"`
+	CameraIDs      []string  `json:"camera_ids"`
+	Alerts         bool      `json:"alerts"`
+	Digest         bool      `json:"digest"`
+	AttachSnapshot bool      `json:"attach_snapshot"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Covers reports whether the group wants mail about the camera.
+func (g *EmailGroup) Covers(cameraID string) bool {
+	return matchList(g.CameraIDs, cameraID)
+}
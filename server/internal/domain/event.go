@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// Event severities.
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Event is something that happened in the system: a camera changing state,
+// a recording starting or failing, an operator action.
+type Event struct {
+	ID       uint64                 `json:"id"`
+	Type     string                 `json:"type"`
+	CameraID string                 `json:"camera_id,omitempty"`
+	Severity string                 `json:"severity"`
+	Time     time.Time              `json:"time"`
+	Payload  map[string]interface{} `json:"payload,omitempty"`
+}
+
+// EventFilter narrows an event query. Zero fields are ignored.
+type EventFilter struct {
+	CameraID string
+	// CameraIDs returns only events of one of the given cameras.
+	CameraIDs []string
+	Types     []string
+	Severity  string
+	From      time.Time
+	To        time.Time
+	// AfterID returns only events newer than the given id.
+	AfterID uint64
+	Limit   int
+	Offset  int
+}
@@ -0,0 +1,82 @@
+package domain
+
+import "time"
+
+// PTZLock gives one operator control of a PTZ camera so two operators don't
+// move it at the same time. It lapses at ExpiresAt unless renewed.
+type PTZLock struct {
+	CameraID   string    `json:"camera_id"`
+	Operator   string    `json:"operator"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Held reports whether the lock is still in force at t.
+func (l *PTZLock) Held(t time.Time) bool {
+	return l != nil && t.Before(l.ExpiresAt)
+}
+
+// PatrolTour moves a PTZ camera through a list of presets in a loop,
+// staying at each for its dwell time. An enabled tour runs whenever its
+// schedule allows and no operator holds the camera's control lock.
+type PatrolTour struct {
+	ID       string       `json:"id"`
+	CameraID string       `json:"camera_id"`
+	Name     string       `json:"name"`
+	Steps    []PatrolStep `json:"steps"`
+	Enabled  bool         `json:"enabled"`
+	// Days limits the tour to weekdays, 0 being Sunday. Empty means every
+	// day.
+	Days []int `json:"days"`
+	// StartTime and EndTime ("15:04", server local time) limit the tour to
+	// a daily window. Empty means all day; a window that ends before it
+	// starts runs overnight and belongs to the day it starts on.
+	StartTime string    `json:"start_time,omitempty"`
+	EndTime   string    `json:"end_time,omitempty"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PatrolStep is a preset a tour visits.
+type PatrolStep struct {
+	PresetToken  string `json:"preset_token"`
+	DwellSeconds int    `json:"dwell_seconds"`
+	// Speed from 0 to 1; nil uses the camera's default.
+	Speed *float64 `json:"speed,omitempty"`
+}
+
+// ActiveAt reports whether the tour's schedule allows it to run at t. It
+// does not look at Enabled.
+func (p *PatrolTour) ActiveAt(t time.Time) bool {
+	day := t
+	if p.StartTime != "" && p.EndTime != "" {
+		start, err1 := time.Parse("15:04", p.StartTime)
+		end, err2 := time.Parse("15:04", p.EndTime)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		now := t.Hour()*60 + t.Minute()
+		from, to := start.Hour()*60+start.Minute(), end.Hour()*60+end.Minute()
+		switch {
+		case from <= to:
+			if now < from || now >= to {
+				return false
+			}
+		case now < to:
+			// early morning part of a window that started yesterday
+			day = t.AddDate(0, 0, -1)
+		case now < from:
+			return false
+		}
+	}
+	if len(p.Days) == 0 {
+		return true
+	}
+	for _, d := range p.Days {
+		if time.Weekday(d) == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
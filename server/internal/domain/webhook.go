@@ -0,0 +1,62 @@
+package domain
+
+import "time"
+
+// Webhook delivery states. A failed attempt leaves the delivery retrying
+// until it succeeds or runs out of attempts and becomes dead.
+const (
+	DeliveryPending   = "pending"
+	DeliveryRetrying  = "retrying"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead"
+)
+
+// Webhook is an outbound HTTP subscription to the event bus. Empty
+// EventTypes or CameraIDs match every event type or camera. Secret is never
+// serialised; it is shown once when the webhook is created.
+type Webhook struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	CameraIDs  []string  `json:"camera_ids"`
+	Secret     string    `json:"-"`
+	Enabled    bool      `json:"enabled"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Matches reports whether an event should be delivered to the webhook.
+func (w *Webhook) Matches(e Event) bool {
+	return w.Enabled && matchList(w.EventTypes, e.Type) && matchList(w.CameraIDs, e.CameraID)
+}
+
+func matchList(list []string, v string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event sent, or being sent, to a webhook. Body is
+// the exact signed request body so retries are byte-identical.
+type WebhookDelivery struct {
+	ID            string     `json:"id"`
+	WebhookID     string     `json:"webhook_id"`
+	EventID       uint64     `json:"event_id,omitempty"`
+	EventType     string     `json:"event_type"`
+	Body          string     `json:"body"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	StatusCode    int        `json:"status_code,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,178 @@
+package events
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+)
+
+// Event types published on the bus.
+const (
+	CameraOnline  = "camera.online"
+	CameraOffline = "camera.offline"
+	CameraCreated = "camera.created"
+	CameraUpdated = "camera.updated"
+	CameraDeleted = "camera.deleted"
+	// CameraStreamChanged reports a change in codec, resolution or frame
+	// rate of a camera's stream.
+	CameraStreamChanged = "camera.stream_changed"
+	// CameraOutOfService reports a camera being disabled or put into
+	// maintenance. It comes back with camera.online or camera.offline.
+	CameraOutOfService = "camera.out_of_service"
+
+	DeviceCreated = "device.created"
+	DeviceUpdated = "device.updated"
+	DeviceDeleted = "device.deleted"
+	// DeviceOnline, DeviceDegraded and DeviceOffline report a change in the
+	// health of a multi-channel device as a whole. DeviceOffline covers a
+	// device that can't be reached and one with no channel online.
+	DeviceOnline   = "device.online"
+	DeviceDegraded = "device.degraded"
+	DeviceOffline  = "device.offline"
+
+	RecordingStarted = "recording.started"
+	RecordingStopped = "recording.stopped"
+	RecordingFailed  = "recording.failed"
+
+	CaptureStarted = "capture.started"
+	CaptureStopped = "capture.stopped"
+	CaptureFailed  = "capture.failed"
+
+	// MotionDetected is published for motion reported through
+	// POST /api/cameras/{id}/motion.
+	MotionDetected = "motion.detected"
+
+	BookmarkCreated = "bookmark.created"
+
+	AlertRaised   = "alert.raised"
+	AlertResolved = "alert.resolved"
+
+	// PTZ events make up the audit trail of PTZ control. PTZMoved is
+	// published for every command sent to a camera, by an operator or a
+	// patrol tour, with the outcome.
+	PTZMoved         = "ptz.moved"
+	PTZPresetSaved   = "ptz.preset_saved"
+	PTZPresetRemoved = "ptz.preset_removed"
+	PTZLocked        = "ptz.locked"
+	PTZUnlocked      = "ptz.unlocked"
+
+	PatrolStarted = "patrol.started"
+	PatrolStopped = "patrol.stopped"
+)
+
+// Store persists events before they are fanned out so that every delivered
+// event carries its stored ID.
+type Store interface {
+	Append(e *domain.Event) error
+}
+
+// Bus is an in-process publish/subscribe hub. Publishing never blocks on a
+// slow subscriber: if its buffer is full the event is dropped for that
+// subscriber only.
+type Bus struct {
+	mu     sync.RWMutex
+	store  Store
+	subs   map[int]*subscription
+	nextID int
+}
+
+type subscription struct {
+	ch     chan domain.Event
+	filter func(domain.Event) bool
+}
+
+var defaultBus = NewBus()
+
+// NewBus creates an empty bus.
+func NewBus() *Bus {
+	return &Bus{subs: make(map[int]*subscription)}
+}
+
+// SetStore sets the store events are persisted to on the default bus.
+func SetStore(s Store) {
+	defaultBus.SetStore(s)
+}
+
+// Publish publishes an event on the default bus.
+func Publish(e domain.Event) {
+	defaultBus.Publish(e)
+}
+
+// Subscribe subscribes to the default bus.
+func Subscribe(buffer int, filter func(domain.Event) bool) (<-chan domain.Event, func()) {
+	return defaultBus.Subscribe(buffer, filter)
+}
+
+// SetStore sets the store events are persisted to.
+func (b *Bus) SetStore(s Store) {
+	b.mu.Lock()
+	b.store = s
+	b.mu.Unlock()
+}
+
+// Publish stores the event, if a store is set, and delivers it to every
+// matching subscriber. Time and Severity default to now and info.
+func (b *Bus) Publish(e domain.Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Severity == "" {
+		e.Severity = domain.SeverityInfo
+	}
+
+	b.mu.RLock()
+	store := b.store
+	b.mu.RUnlock()
+	if store != nil {
+		if err := store.Append(&e); err != nil {
+			log.Printf("events: failed to store %s event: %v", e.Type, err)
+		}
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for id, sub := range b.subs {
+		if sub.filter != nil && !sub.filter(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			log.Printf("events: subscriber %d is full, dropping %s event", id, e.Type)
+		}
+	}
+}
+
+// Subscribe returns a channel receiving events accepted by filter (all events
+// if filter is nil) and a function that cancels the subscription and closes
+// the channel.
+func (b *Bus) Subscribe(buffer int, filter func(domain.Event) bool) (<-chan domain.Event, func()) {
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	sub := &subscription{ch: make(chan domain.Event, buffer), filter: filter}
+	b.subs[id] = sub
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			b.mu.Unlock()
+			close(sub.ch)
+		})
+	}
+	return sub.ch, cancel
+}
+
+// OfTypes returns a subscription filter accepting only the given event types.
+func OfTypes(types ...string) func(domain.Event) bool {
+	set := make(map[string]bool, len(types))
+	for _, t := range types {
+		set[t] = true
+	}
+	return func(e domain.Event) bool { return set[e.Type] }
+}
@@ -0,0 +1,156 @@
+package events
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultRetentionDays is how long events are kept when
+	// EVENT_RETENTION_DAYS is not set.
+	defaultRetentionDays = 90
+	// defaultAuditRetentionDays is how long the PTZ audit trail is kept when
+	// PTZ_AUDIT_RETENTION_DAYS is not set.
+	defaultAuditRetentionDays = 365
+	// pruneInterval is how often expired events are deleted.
+	pruneInterval = time.Hour
+)
+
+// AuditPrefixes are the type prefixes of the events making up the PTZ audit
+// trail, which is kept for its own retention period.
+var AuditPrefixes = []string{"ptz.", "patrol."}
+
+// Pruner deletes stored events older than a point in time.
+type Pruner interface {
+	// DeleteBefore deletes events older than t except those whose type
+	// starts with one of except.
+	DeleteBefore(t time.Time, except []string) (int64, error)
+	// DeleteTypesBefore deletes events older than t whose type starts with
+	// one of prefixes.
+	DeleteTypesBefore(t time.Time, prefixes []string) (int64, error)
+}
+
+// Retention deletes stored events once they are older than the retention
+// period, so the event log does not grow without bound.
+type Retention struct {
+	store Pruner
+	keep  time.Duration
+	// classes keep events of the given type prefixes for their own period
+	// instead of keep.
+	classes  []retentionClass
+	stopChan chan struct{}
+}
+
+type retentionClass struct {
+	prefixes []string
+	keep     time.Duration
+}
+
+func (c retentionClass) String() string {
+	return strings.Join(c.prefixes, "*, ") + "*"
+}
+
+// NewRetention creates a retention policy keeping events for keep. Zero keeps
+// events forever.
+func NewRetention(store Pruner, keep time.Duration) *Retention {
+	return &Retention{store: store, keep: keep, stopChan: make(chan struct{})}
+}
+
+// Keep keeps events whose type starts with one of prefixes for keep instead
+// of the retention period. Zero keeps them forever. It must be called before
+// Start.
+func (r *Retention) Keep(keep time.Duration, prefixes ...string) {
+	r.classes = append(r.classes, retentionClass{prefixes: prefixes, keep: keep})
+}
+
+// RetentionFromEnv reads EVENT_RETENTION_DAYS, 90 days by default. 0 keeps
+// events forever.
+func RetentionFromEnv() time.Duration {
+	return daysFromEnv("EVENT_RETENTION_DAYS", defaultRetentionDays)
+}
+
+// AuditRetentionFromEnv reads PTZ_AUDIT_RETENTION_DAYS, how long the PTZ
+// audit trail is kept, 365 days by default. 0 keeps it forever.
+func AuditRetentionFromEnv() time.Duration {
+	return daysFromEnv("PTZ_AUDIT_RETENTION_DAYS", defaultAuditRetentionDays)
+}
+
+func daysFromEnv(name string, days int) time.Duration {
+	if v := os.Getenv(name); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			days = n
+		} else {
+			log.Printf("events: invalid %s %q, using %d days", name, v, days)
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// Start deletes expired events now and then every hour.
+func (r *Retention) Start() {
+	pruning := r.keep > 0
+	if r.keep > 0 {
+		log.Printf("events: keeping events for %s", r.keep)
+	} else {
+		log.Println("events: keeping events forever")
+	}
+	for _, c := range r.classes {
+		if c.keep > 0 {
+			pruning = true
+			log.Printf("events: keeping %s events for %s", c, c.keep)
+		} else {
+			log.Printf("events: keeping %s events forever", c)
+		}
+	}
+	if pruning {
+		go r.run()
+	}
+}
+
+// Stop stops deleting expired events.
+func (r *Retention) Stop() {
+	close(r.stopChan)
+}
+
+func (r *Retention) run() {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		r.prune()
+		select {
+		case <-ticker.C:
+		case <-r.stopChan:
+			return
+		}
+	}
+}
+
+func (r *Retention) prune() {
+	now := time.Now()
+	if r.keep > 0 {
+		var except []string
+		for _, c := range r.classes {
+			except = append(except, c.prefixes...)
+		}
+		n, err := r.store.DeleteBefore(now.Add(-r.keep), except)
+		if err != nil {
+			log.Printf("events: failed to delete expired events: %v", err)
+		} else if n > 0 {
+			log.Printf("events: deleted %d events older than %s", n, r.keep)
+		}
+	}
+	for _, c := range r.classes {
+		if c.keep <= 0 {
+			continue
+		}
+		n, err := r.store.DeleteTypesBefore(now.Add(-c.keep), c.prefixes)
+		if err != nil {
+			log.Printf("events: failed to delete expired %s events: %v", c, err)
+		} else if n > 0 {
+			log.Printf("events: deleted %d %s events older than %s", n, c, c.keep)
+		}
+	}
+}
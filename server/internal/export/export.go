@@ -0,0 +1,166 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/recorder"
+	"github.com/boytur/cctv-recording-center/server/internal/segment"
+)
+
+var (
+	// ErrNothingRecorded is returned when no recording overlaps the requested range.
+	ErrNothingRecorded = errors.New("no recordings in range")
+	// ErrInvalidRange is returned for an empty or overly long range.
+	ErrInvalidRange = errors.New("invalid range")
+)
+
+// MaxClipLength bounds a single export so one request can't tie up ffmpeg
+// for hours.
+const MaxClipLength = 2 * time.Hour
+
+// outputDir is where exported clips are written; it is served under /data.
+var outputDir = filepath.Join("data", "exports")
+
+// Clip is an exported MP4 file.
+type Clip struct {
+	CameraID string
+	From     time.Time
+	To       time.Time
+	Path     string
+	Size     int64
+}
+
+// URL returns the public URL the clip is served under.
+func (c Clip) URL() string {
+	return "/" + filepath.ToSlash(c.Path)
+}
+
+// Export cuts [from, to) out of a camera's continuous recordings into a
+// single MP4 without re-encoding. Existing exports of the same range are
+// reused once its recordings are finished.
+func Export(ctx context.Context, cameraID string, from, to time.Time) (*Clip, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("%w: to must be after from", ErrInvalidRange)
+	}
+	if to.Sub(from) > MaxClipLength {
+		return nil, fmt.Errorf("%w: clip longer than %s", ErrInvalidRange, MaxClipLength)
+	}
+
+	segs, err := segment.Range(cameraID, from, to, segment.KindContinuous)
+	if err != nil {
+		return nil, err
+	}
+	if len(segs) == 0 {
+		return nil, ErrNothingRecorded
+	}
+
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("%s_%s_%s", cameraID, from.In(time.Local).Format("20060102_150405"), to.In(time.Local).Format("20060102_150405"))
+	cacheable := finished(cameraID, segs, to)
+	if cacheable {
+		name += ".mp4"
+	} else {
+		// the range is still being recorded, so a later export of it will
+		// be longer; keep this one apart from the reusable name
+		name += "_partial.mp4"
+	}
+	outPath := filepath.Join(outputDir, name)
+	if info, err := os.Stat(outPath); cacheable && err == nil && info.Size() > 0 {
+		return &Clip{CameraID: cameraID, From: from, To: to, Path: outPath, Size: info.Size()}, nil
+	}
+
+	// concat demuxer list with per-file in/out points
+	var list strings.Builder
+	for _, s := range segs {
+		abs, err := filepath.Abs(s.Path)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&list, "file '%s'\n", strings.ReplaceAll(abs, "'", `'\''`))
+		if from.After(s.Start) {
+			fmt.Fprintf(&list, "inpoint %s\n", seconds(from.Sub(s.Start)))
+		}
+		if to.Before(s.End) {
+			fmt.Fprintf(&list, "outpoint %s\n", seconds(to.Sub(s.Start)))
+		}
+	}
+	listFile, err := os.CreateTemp(outputDir, "concat-*.txt")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(listFile.Name())
+	if _, err := listFile.WriteString(list.String()); err != nil {
+		listFile.Close()
+		return nil, err
+	}
+	listFile.Close()
+
+	// a unique temporary file so identical exports running at the same
+	// time don't write over each other; the last rename wins
+	tmp, err := os.CreateTemp(outputDir, name+".*.part")
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+	tmpPath := tmp.Name()
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-hide_banner", "-loglevel", "error", "-y",
+		"-f", "concat",
+		"-safe", "0",
+		"-i", listFile.Name(),
+		"-c", "copy",
+		"-movflags", "+faststart",
+		"-f", "mp4",
+		tmpPath,
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("ffmpeg failed: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	if err := os.Rename(tmpPath, outPath); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(outPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Clip{CameraID: cameraID, From: from, To: to, Path: outPath, Size: info.Size()}, nil
+}
+
+// finished reports whether the recordings of a clip ending at to will no
+// longer change: every source segment is complete, and the recorder, if
+// running, has moved past to. The segment it is appending to can parse as
+// complete between two fragments.
+func finished(cameraID string, segs []segment.Segment, to time.Time) bool {
+	for _, s := range segs {
+		if s.Media == nil || !s.Media.Complete {
+			return false
+		}
+	}
+	if !recorder.IsRecording(cameraID) {
+		return true
+	}
+	// the newest segment is the one being written
+	later, err := segment.Range(cameraID, to, time.Now(), segment.KindContinuous)
+	if err != nil || len(later) == 0 {
+		return false
+	}
+	return !to.After(later[len(later)-1].Start)
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
@@ -0,0 +1,263 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/mail/mailtest"
+)
+
+func startServer(t *testing.T, opts mailtest.Options) (*mailtest.Server, Config) {
+	t.Helper()
+	srv, err := mailtest.NewServer(opts)
+	if err != nil {
+		t.Fatalf("mailtest.NewServer: %v", err)
+	}
+	t.Cleanup(srv.Close)
+	cfg := Config{Host: srv.Host, Port: srv.Port, From: "CCTV <cctv@example.com>", TLS: TLSNone}
+	return srv, cfg
+}
+
+func send(t *testing.T, cfg Config, msg Message) error {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return Send(ctx, cfg, msg)
+}
+
+// onlyMessage returns the one message the server received.
+func onlyMessage(t *testing.T, srv *mailtest.Server) mailtest.Message {
+	t.Helper()
+	msgs := srv.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("server received %d messages, want 1", len(msgs))
+	}
+	return msgs[0]
+}
+
+func parse(t *testing.T, data string) *netmail.Message {
+	t.Helper()
+	m, err := netmail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	return m
+}
+
+func TestSendPlain(t *testing.T) {
+	srv, cfg := startServer(t, mailtest.Options{})
+	err := send(t, cfg, Message{To: []string{"ops@example.com"}, Subject: "Camera “Lobby” is offline", Text: "Lobby has been offline since 08:00.\n"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	got := onlyMessage(t, srv)
+	if got.From != "cctv@example.com" || !reflect.DeepEqual(got.To, []string{"ops@example.com"}) {
+		t.Errorf("envelope = %s -> %v, want cctv@example.com -> [ops@example.com]", got.From, got.To)
+	}
+	if got.TLS || got.User != "" {
+		t.Errorf("TLS = %v, User = %q, want a plain unauthenticated session", got.TLS, got.User)
+	}
+
+	m := parse(t, got.Data)
+	if s, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject")); err != nil || s != "Camera “Lobby” is offline" {
+		t.Errorf("Subject = %q, %v", s, err)
+	}
+	if ct := m.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q, want text/plain", ct)
+	}
+	if id := m.Header.Get("Message-ID"); !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("Message-ID = %q, want the sender's domain", id)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(m.Body))
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	if string(body) != "Lobby has been offline since 08:00.\n" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestSendStartTLS(t *testing.T) {
+	srv, cfg := startServer(t, mailtest.Options{StartTLS: true})
+	cfg.TLS = TLSStartTLS
+	cfg.InsecureSkipVerify = true
+	if err := send(t, cfg, Message{To: []string{"ops@example.com"}, Subject: "s", Text: "t"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got := onlyMessage(t, srv); !got.TLS {
+		t.Error("message was not sent over TLS")
+	}
+
+	// the self-signed certificate fails verification
+	cfg.InsecureSkipVerify = false
+	if err := send(t, cfg, Message{To: []string{"ops@example.com"}, Subject: "s", Text: "t"}); err == nil {
+		t.Error("Send accepted an unverified certificate")
+	}
+}
+
+func TestSendStartTLSUnsupported(t *testing.T) {
+	srv, cfg := startServer(t, mailtest.Options{})
+	cfg.TLS = TLSStartTLS
+	if err := send(t, cfg, Message{To: []string{"ops@example.com"}, Subject: "s", Text: "t"}); err == nil {
+		t.Error("Send fell back to plain text without STARTTLS")
+	}
+	if n := len(srv.Messages()); n != 0 {
+		t.Errorf("server received %d messages, want 0", n)
+	}
+}
+
+func TestSendImplicitTLS(t *testing.T) {
+	srv, cfg := startServer(t, mailtest.Options{ImplicitTLS: true})
+	cfg.TLS = TLSImplicit
+	cfg.InsecureSkipVerify = true
+	if err := send(t, cfg, Message{To: []string{"ops@example.com"}, Subject: "s", Text: "t"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got := onlyMessage(t, srv); !got.TLS {
+		t.Error("message was not sent over TLS")
+	}
+}
+
+func TestSendAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		startTLS bool
+		password string
+		wantErr  bool
+	}{
+		{"over STARTTLS", true, "secret", false},
+		{"plain to localhost", false, "secret", false},
+		{"wrong password", true, "wrong", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, cfg := startServer(t, mailtest.Options{StartTLS: tt.startTLS, Username: "cctv", Password: "secret"})
+			if tt.startTLS {
+				cfg.TLS = TLSStartTLS
+				cfg.InsecureSkipVerify = true
+			}
+			cfg.Username, cfg.Password = "cctv", tt.password
+			err := send(t, cfg, Message{To: []string{"ops@example.com"}, Subject: "s", Text: "t"})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Send succeeded with the wrong password")
+				}
+				if n := len(srv.Messages()); n != 0 {
+					t.Errorf("server received %d messages, want 0", n)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+			if got := onlyMessage(t, srv); got.User != "cctv" {
+				t.Errorf("User = %q, want cctv", got.User)
+			}
+		})
+	}
+}
+
+func TestSendRecipients(t *testing.T) {
+	srv, cfg := startServer(t, mailtest.Options{Reject: []string{"gone@example.com"}})
+	to := []string{"Ops <ops@example.com>", "guard@example.com", "Night Shift <night@example.com>"}
+	if err := send(t, cfg, Message{To: to, Subject: "s", Text: "t"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	got := onlyMessage(t, srv)
+	if want := []string{"ops@example.com", "guard@example.com", "night@example.com"}; !reflect.DeepEqual(got.To, want) {
+		t.Errorf("envelope recipients = %v, want %v", got.To, want)
+	}
+	if h := parse(t, got.Data).Header.Get("To"); h != strings.Join(to, ", ") {
+		t.Errorf("To = %q", h)
+	}
+
+	err := send(t, cfg, Message{To: []string{"ops@example.com", "gone@example.com"}, Subject: "s", Text: "t"})
+	if err == nil || !strings.Contains(err.Error(), "gone@example.com") {
+		t.Errorf("Send to a refused recipient = %v, want an error naming it", err)
+	}
+	if n := len(srv.Messages()); n != 1 {
+		t.Errorf("server received %d messages, want only the first", n)
+	}
+
+	if err := send(t, cfg, Message{Subject: "s", Text: "t"}); err == nil {
+		t.Error("Send accepted a message without recipients")
+	}
+}
+
+func TestSendAttachment(t *testing.T) {
+	srv, cfg := startServer(t, mailtest.Options{})
+	// long enough to be wrapped over several base64 lines
+	jpeg := bytes.Repeat([]byte{0xff, 0xd8, 0xff, 0xe0, 0x00, 0x10}, 40)
+	err := send(t, cfg, Message{
+		To:      []string{"ops@example.com"},
+		Subject: "s",
+		Text:    "See the attached snapshot.",
+		Attachments: []Attachment{
+			{Name: "cam1_20261019_080000.jpg", ContentType: "image/jpeg", Data: jpeg},
+			{Name: "notes.bin", Data: []byte{1, 2, 3}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	m := parse(t, onlyMessage(t, srv).Data)
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %q, %v, want multipart/mixed", m.Header.Get("Content-Type"), err)
+	}
+	mr := multipart.NewReader(m.Body, params["boundary"])
+
+	type part struct {
+		contentType, filename string
+		data                  []byte
+	}
+	var parts []part
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextPart: %v", err)
+		}
+		var data []byte
+		if p.Header.Get("Content-Transfer-Encoding") == "base64" {
+			data, err = io.ReadAll(base64.NewDecoder(base64.StdEncoding, p))
+		} else {
+			// multipart decodes quoted-printable itself
+			data, err = io.ReadAll(p)
+		}
+		if err != nil {
+			t.Fatalf("reading part: %v", err)
+		}
+		ct, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		parts = append(parts, part{ct, p.FileName(), data})
+	}
+
+	want := []part{
+		{"text/plain", "", []byte("See the attached snapshot.")},
+		{"image/jpeg", "cam1_20261019_080000.jpg", jpeg},
+		{"application/octet-stream", "notes.bin", []byte{1, 2, 3}},
+	}
+	if !reflect.DeepEqual(parts, want) {
+		t.Errorf("parts =\n%+v\nwant\n%+v", parts, want)
+	}
+}
+
+func TestSendNotConfigured(t *testing.T) {
+	if err := send(t, Config{}, Message{To: []string{"ops@example.com"}}); err == nil {
+		t.Error("Send without a host succeeded")
+	}
+}
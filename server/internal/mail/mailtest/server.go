@@ -0,0 +1,255 @@
+// Package mailtest provides an in-process SMTP server for testing code that
+// sends mail.
+package mailtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options configures a Server.
+type Options struct {
+	// StartTLS advertises STARTTLS.
+	StartTLS bool
+	// ImplicitTLS serves TLS from the start of the connection.
+	ImplicitTLS bool
+	// Username and Password, when Username is set, are required with
+	// AUTH PLAIN before mail is accepted.
+	Username string
+	Password string
+	// Reject lists recipients refused at RCPT TO.
+	Reject []string
+}
+
+// Message is a message accepted by the server.
+type Message struct {
+	From string
+	To   []string
+	// Data is the message as sent after DATA, with CRLF line endings
+	// turned into LF.
+	Data string
+	// TLS reports whether the message came over an encrypted connection.
+	TLS bool
+	// User is the authenticated username, if any.
+	User string
+}
+
+// Server is an SMTP server listening on a loopback port. It implements just
+// enough of the protocol for net/smtp clients.
+type Server struct {
+	// Host and Port are where the server listens.
+	Host string
+	Port int
+
+	opts Options
+	tls  *tls.Config
+	ln   net.Listener
+	wg   sync.WaitGroup
+
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewServer starts a server. TLS uses a self-signed certificate, so clients
+// must skip verification. Close it when done.
+func NewServer(opts Options) (*Server, error) {
+	cert, err := selfSigned()
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{opts: opts, tls: &tls.Config{Certificates: []tls.Certificate{cert}}}
+
+	s.ln, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	if opts.ImplicitTLS {
+		s.ln = tls.NewListener(s.ln, s.tls)
+	}
+	addr := s.ln.Addr().(*net.TCPAddr)
+	s.Host, s.Port = addr.IP.String(), addr.Port
+
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+// Messages returns the messages accepted so far.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Close stops the server and waits for open sessions to end.
+func (s *Server) Close() {
+	s.ln.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serve(conn)
+		}()
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	tp := textproto.NewConn(conn)
+	encrypted := s.opts.ImplicitTLS
+	var user string
+	var m Message
+
+	tp.PrintfLine("220 mailtest ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			lines := []string{"mailtest"}
+			if s.opts.StartTLS && !encrypted {
+				lines = append(lines, "STARTTLS")
+			}
+			if s.opts.Username != "" {
+				lines = append(lines, "AUTH PLAIN")
+			}
+			for i, l := range lines {
+				sep := "-"
+				if i == len(lines)-1 {
+					sep = " "
+				}
+				tp.PrintfLine("250%s%s", sep, l)
+			}
+		case "STARTTLS":
+			if !s.opts.StartTLS || encrypted {
+				tp.PrintfLine("502 not supported")
+				continue
+			}
+			tp.PrintfLine("220 ready to start TLS")
+			tc := tls.Server(conn, s.tls)
+			if err := tc.Handshake(); err != nil {
+				return
+			}
+			conn, tp, encrypted = tc, textproto.NewConn(tc), true
+			m = Message{}
+		case "AUTH":
+			// AUTH PLAIN <base64("\x00user\x00password")>
+			mech, resp, _ := strings.Cut(arg, " ")
+			b, _ := base64.StdEncoding.DecodeString(resp)
+			parts := strings.Split(string(b), "\x00")
+			if !strings.EqualFold(mech, "PLAIN") || len(parts) != 3 ||
+				parts[1] != s.opts.Username || parts[2] != s.opts.Password {
+				tp.PrintfLine("535 authentication failed")
+				continue
+			}
+			user = parts[1]
+			tp.PrintfLine("235 authenticated")
+		case "MAIL":
+			if s.opts.Username != "" && user == "" {
+				tp.PrintfLine("530 authentication required")
+				continue
+			}
+			m = Message{From: trimPath(arg, "FROM:")}
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			to := trimPath(arg, "TO:")
+			if contains(s.opts.Reject, to) {
+				tp.PrintfLine("550 no such user")
+				continue
+			}
+			m.To = append(m.To, to)
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			if m.From == "" || len(m.To) == 0 {
+				tp.PrintfLine("503 need MAIL and RCPT first")
+				continue
+			}
+			tp.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			m.Data, m.TLS, m.User = string(data), encrypted, user
+			s.mu.Lock()
+			s.messages = append(s.messages, m)
+			s.mu.Unlock()
+			m = Message{}
+			tp.PrintfLine("250 queued")
+		case "RSET":
+			m = Message{}
+			tp.PrintfLine("250 ok")
+		case "NOOP":
+			tp.PrintfLine("250 ok")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 command not implemented")
+		}
+	}
+}
+
+// trimPath returns the address in "FROM:<a@b>" or "TO:<a@b>".
+func trimPath(arg, prefix string) string {
+	if len(arg) >= len(prefix) && strings.EqualFold(arg[:len(prefix)], prefix) {
+		arg = arg[len(prefix):]
+	}
+	arg, _, _ = strings.Cut(strings.TrimSpace(arg), " ")
+	return strings.Trim(arg, "<>")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// selfSigned creates a certificate for 127.0.0.1 and localhost.
+func selfSigned() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "mailtest"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
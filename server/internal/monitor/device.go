@@ -0,0 +1,243 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/events"
+	"github.com/boytur/cctv-recording-center/server/internal/repository"
+)
+
+const (
+	// deviceDialTimeout bounds the connection attempt to a device's RTSP
+	// port.
+	deviceDialTimeout = 5 * time.Second
+	// deviceCheckDelay collects the status changes of a device's channels,
+	// e.g. all of them going offline with the device, into one check.
+	deviceCheckDelay = 2 * time.Second
+	// deviceCheckWorkers is the number of devices checked at the same time.
+	deviceCheckWorkers = 8
+)
+
+// DeviceMonitor keeps the health of multi-channel devices up to date. A
+// device is checked by connecting to its RTSP port and counting which of its
+// channels in service the camera monitor found online, so one NVR going down
+// shows as a single unreachable device rather than only as many offline
+// cameras.
+type DeviceMonitor struct {
+	devices  repository.DeviceRepository
+	cameras  repository.CameraRepository
+	interval time.Duration
+
+	mu sync.Mutex
+	// checking holds the devices with a check running; again those asked
+	// for another check while it ran.
+	checking map[string]bool
+	again    map[string]bool
+	workers  chan struct{}
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewDeviceMonitor creates a device monitor checking every device at
+// interval, one minute if zero.
+func NewDeviceMonitor(devices repository.DeviceRepository, cameras repository.CameraRepository, interval time.Duration) *DeviceMonitor {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &DeviceMonitor{
+		devices:  devices,
+		cameras:  cameras,
+		interval: interval,
+		checking: make(map[string]bool),
+		again:    make(map[string]bool),
+		workers:  make(chan struct{}, deviceCheckWorkers),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start checks every device once and then at the interval. A device is also
+// checked shortly after it is created or updated or its channels change
+// status.
+func (m *DeviceMonitor) Start() {
+	ch, cancel := events.Subscribe(256, events.OfTypes(events.DeviceCreated, events.DeviceUpdated,
+		events.CameraOnline, events.CameraOffline, events.CameraOutOfService))
+	go m.run(ch, cancel)
+	log.Printf("monitor: checking devices every %s", m.interval)
+}
+
+// Stop stops the device monitor and waits for running checks to finish.
+func (m *DeviceMonitor) Stop() {
+	close(m.stopChan)
+	m.wg.Wait()
+}
+
+func (m *DeviceMonitor) run(ch <-chan domain.Event, cancel func()) {
+	defer cancel()
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	pending := make(map[string]bool)
+	var due <-chan time.Time
+	m.checkAll()
+	for {
+		select {
+		case <-ticker.C:
+			m.checkAll()
+		case e := <-ch:
+			id, _ := e.Payload["device_id"].(string)
+			if id == "" && e.CameraID != "" {
+				if c, err := m.cameras.GetByID(e.CameraID); err == nil {
+					id = c.DeviceID
+				}
+			}
+			if id == "" {
+				continue
+			}
+			pending[id] = true
+			if due == nil {
+				due = time.After(deviceCheckDelay)
+			}
+		case <-due:
+			for id := range pending {
+				m.checkAsync(id)
+			}
+			pending = make(map[string]bool)
+			due = nil
+		case <-m.stopChan:
+			return
+		}
+	}
+}
+
+func (m *DeviceMonitor) checkAll() {
+	devices, err := m.devices.List()
+	if err != nil {
+		log.Printf("monitor: failed to list devices: %v", err)
+		return
+	}
+	for _, d := range devices {
+		m.checkAsync(d.ID)
+	}
+}
+
+// checkAsync checks a device in the background. A device already being
+// checked is checked again once that check ends.
+func (m *DeviceMonitor) checkAsync(id string) {
+	m.mu.Lock()
+	if m.checking[id] {
+		m.again[id] = true
+		m.mu.Unlock()
+		return
+	}
+	m.checking[id] = true
+	m.mu.Unlock()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		for {
+			select {
+			case m.workers <- struct{}{}:
+			case <-m.stopChan:
+				return
+			}
+			// reloaded so the previous status is the one the last check
+			// stored
+			if d, err := m.devices.GetByID(id); err == nil {
+				m.check(d)
+			}
+			<-m.workers
+
+			m.mu.Lock()
+			if !m.again[id] {
+				delete(m.checking, id)
+				m.mu.Unlock()
+				return
+			}
+			delete(m.again, id)
+			m.mu.Unlock()
+		}
+	}()
+}
+
+// check works out a device's health, stores it and announces a change of
+// status.
+func (m *DeviceMonitor) check(d *domain.Device) {
+	cams, err := m.cameras.List()
+	if err != nil {
+		log.Printf("monitor: failed to list cameras: %v", err)
+		return
+	}
+	now := time.Now()
+	h := domain.DeviceHealth{CheckedAt: &now}
+	pending := 0
+	for _, c := range cams {
+		if c.DeviceID != d.ID || c.ServiceStatus(now) != "" {
+			continue
+		}
+		if c.Status == "" || c.Status == domain.CameraStatusUnknown {
+			// not probed yet
+			pending++
+			continue
+		}
+		h.ChannelsTotal++
+		if c.Status == domain.CameraStatusOnline {
+			h.ChannelsOnline++
+		}
+	}
+	switch err := dialDevice(d.Host); {
+	case err != nil:
+		h.Status, h.Reason = domain.DeviceStatusUnreachable, err.Error()
+	case pending > 0 && h.ChannelsTotal == 0:
+		h.Status = domain.DeviceStatusUnknown
+	case h.ChannelsOnline == h.ChannelsTotal:
+		h.Status = domain.DeviceStatusOnline
+	case h.ChannelsOnline == 0:
+		h.Status, h.Reason = domain.DeviceStatusOffline, "no channel is online"
+	default:
+		h.Status = domain.DeviceStatusDegraded
+		h.Reason = fmt.Sprintf("%d of %d channels offline", h.ChannelsTotal-h.ChannelsOnline, h.ChannelsTotal)
+	}
+	if err := m.devices.UpdateHealth(d.ID, h); err != nil {
+		log.Printf("monitor: failed to update device %s health: %v", d.ID, err)
+		return
+	}
+	if h.Status == d.Health.Status || h.Status == domain.DeviceStatusUnknown {
+		return
+	}
+	log.Printf("monitor: device %s is %s", d.ID, h.Status)
+	e := domain.Event{
+		Type:     events.DeviceOnline,
+		Severity: domain.SeverityInfo,
+		Payload: map[string]interface{}{"device_id": d.ID, "name": d.Name, "previous_status": d.Health.Status, "status": h.Status,
+			"channels_online": h.ChannelsOnline, "channels_total": h.ChannelsTotal},
+	}
+	switch h.Status {
+	case domain.DeviceStatusDegraded:
+		e.Type, e.Severity = events.DeviceDegraded, domain.SeverityWarning
+	case domain.DeviceStatusOffline, domain.DeviceStatusUnreachable:
+		e.Type, e.Severity = events.DeviceOffline, domain.SeverityCritical
+	}
+	if h.Reason != "" {
+		e.Payload["reason"] = h.Reason
+	}
+	events.Publish(e)
+}
+
+// dialDevice connects to a device's RTSP port, 554 unless the host has one.
+func dialDevice(host string) error {
+	addr := host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		addr = net.JoinHostPort(host, "554")
+	}
+	conn, err := net.DialTimeout("tcp", addr, deviceDialTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
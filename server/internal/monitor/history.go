@@ -0,0 +1,51 @@
+package monitor
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/repository"
+)
+
+// statusHistory writes status transitions to the history repository.
+// Cameras with no history yet, e.g. added before history was kept, get their
+// first probed status recorded even when it did not change, so availability
+// can be computed from then on.
+type statusHistory struct {
+	repo   repository.StatusHistoryRepository
+	mu     sync.Mutex
+	seeded map[string]bool
+}
+
+func newStatusHistory(repo repository.StatusHistoryRepository) *statusHistory {
+	return &statusHistory{repo: repo, seeded: make(map[string]bool)}
+}
+
+func (h *statusHistory) record(cameraID, previous, status, reason string, changed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	if !changed {
+		if h.seeded[cameraID] {
+			return
+		}
+		last, err := h.repo.Last(cameraID, now.Add(time.Second))
+		if err != nil {
+			log.Printf("monitor: failed to read status history of camera %s: %v", cameraID, err)
+			return
+		}
+		if last != nil {
+			h.seeded[cameraID] = true
+			return
+		}
+	}
+
+	sc := &domain.StatusChange{CameraID: cameraID, Status: status, Previous: previous, Reason: reason, At: now}
+	if err := h.repo.Append(sc); err != nil {
+		log.Printf("monitor: failed to record status of camera %s: %v", cameraID, err)
+		return
+	}
+	h.seeded[cameraID] = true
+}
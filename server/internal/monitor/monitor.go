@@ -1,79 +1,340 @@
 package monitor
 
 import (
+	"context"
+	"errors"
 	"log"
-	"net"
-	"net/url"
-	"strings"
+	"sync"
 	"time"
 
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/events"
 	"github.com/boytur/cctv-recording-center/server/internal/repository"
+	"github.com/boytur/cctv-recording-center/server/internal/rtsp"
 )
 
-// StartMonitor launches a background checker that polls camera RTSP endpoints
-// every `interval` and updates their Status to "online" or "offline".
-func StartMonitor(repo repository.CameraRepository, interval time.Duration) {
-	go func() {
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-		for range ticker.C {
-			cams, err := repo.List()
-			if err != nil {
-				log.Printf("monitor: failed to list cameras: %v", err)
+const (
+	// scheduleTick is how often the scheduler looks for cameras due a probe.
+	scheduleTick = time.Second
+	// refreshInterval is how often the camera list is reloaded to pick up
+	// cameras added or removed without an event.
+	refreshInterval = 30 * time.Second
+)
+
+// Config holds the monitor defaults. Cameras can override the interval and
+// timeout individually.
+type Config struct {
+	// Interval is the time between probes of a camera.
+	Interval time.Duration
+	// Timeout bounds a single probe, from dialing to the DESCRIBE response.
+	Timeout time.Duration
+	// Workers is the number of probes run at the same time.
+	Workers int
+}
+
+// Monitor probes camera RTSP streams and updates their Status to "online"
+// or to the reason the stream is unavailable (see the domain.CameraStatus
+// values). Cameras that are disabled or in maintenance are not probed and
+// get that as their status instead. Probes run on a bounded pool of workers
+// so one slow camera does not delay the others. Every transition is recorded
+// in the status history and online cameras also get their stream parameters
+// refreshed.
+type Monitor struct {
+	repo    repository.CameraRepository
+	history *statusHistory
+	cfg     Config
+
+	jobs     chan string
+	mu       sync.Mutex
+	schedule map[string]*probeSchedule
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+type probeSchedule struct {
+	next     time.Time
+	inFlight bool
+	// rerun asks for another probe as soon as the one in flight ends,
+	// because the camera changed while it ran.
+	rerun bool
+}
+
+// NewMonitor creates a monitor. Zero config values fall back to a one minute
+// interval, a five second timeout and eight workers.
+func NewMonitor(repo repository.CameraRepository, history repository.StatusHistoryRepository, cfg Config) *Monitor {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 8
+	}
+	return &Monitor{
+		repo:     repo,
+		history:  newStatusHistory(history),
+		cfg:      cfg,
+		jobs:     make(chan string, cfg.Workers),
+		schedule: make(map[string]*probeSchedule),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start probes every camera once and then keeps probing each at its
+// interval. Created and updated cameras are probed straight away.
+func (m *Monitor) Start() {
+	ch, cancel := events.Subscribe(64, events.OfTypes(events.CameraCreated, events.CameraUpdated, events.CameraDeleted))
+	for i := 0; i < m.cfg.Workers; i++ {
+		m.wg.Add(1)
+		go m.worker()
+	}
+	go m.run(ch, cancel)
+	log.Printf("monitor: probing cameras every %s with %d workers", m.cfg.Interval, m.cfg.Workers)
+}
+
+// Stop stops scheduling probes and waits for the running ones to finish.
+func (m *Monitor) Stop() {
+	close(m.stopChan)
+	m.wg.Wait()
+}
+
+// ProbeNow schedules an immediate probe of a camera. If a probe is already
+// running, another one follows as soon as it ends.
+func (m *Monitor) ProbeNow(cameraID string) {
+	m.mu.Lock()
+	s, ok := m.schedule[cameraID]
+	if !ok {
+		s = &probeSchedule{}
+		m.schedule[cameraID] = s
+	}
+	s.next = time.Time{}
+	if s.inFlight {
+		s.rerun = true
+	}
+	m.mu.Unlock()
+	m.dispatch(time.Now())
+}
+
+func (m *Monitor) run(ch <-chan domain.Event, cancel func()) {
+	defer cancel()
+	ticker := time.NewTicker(scheduleTick)
+	defer ticker.Stop()
+
+	m.refresh()
+	lastRefresh := time.Now()
+	m.dispatch(lastRefresh)
+	for {
+		select {
+		case now := <-ticker.C:
+			if now.Sub(lastRefresh) >= refreshInterval {
+				m.refresh()
+				lastRefresh = now
+			}
+			m.dispatch(now)
+		case e := <-ch:
+			if e.Type == events.CameraDeleted {
+				m.mu.Lock()
+				delete(m.schedule, e.CameraID)
+				m.mu.Unlock()
 				continue
 			}
-			for _, c := range cams {
-				online := checkReachable(c.RTSPURL, 1*time.Second)
-				newStatus := "offline"
-				if online {
-					newStatus = "online"
-				}
-				if c.Status != newStatus {
-					c.Status = newStatus
-					if err := repo.Update(c); err != nil {
-						log.Printf("monitor: failed to update camera %s status: %v", c.ID, err)
-					}
+			m.ProbeNow(e.CameraID)
+		case <-m.stopChan:
+			return
+		}
+	}
+}
+
+// refresh adds cameras missing from the schedule, due straight away, and
+// drops the ones that no longer exist.
+func (m *Monitor) refresh() {
+	cams, err := m.repo.List()
+	if err != nil {
+		log.Printf("monitor: failed to list cameras: %v", err)
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seen := make(map[string]bool, len(cams))
+	for _, c := range cams {
+		seen[c.ID] = true
+		if _, ok := m.schedule[c.ID]; !ok {
+			m.schedule[c.ID] = &probeSchedule{}
+		}
+	}
+	for id := range m.schedule {
+		if !seen[id] {
+			delete(m.schedule, id)
+		}
+	}
+}
+
+// dispatch hands the cameras that are due to the workers. When every worker
+// is busy the rest stay due and are picked up on a later tick.
+func (m *Monitor) dispatch(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, s := range m.schedule {
+		if s.inFlight || now.Before(s.next) {
+			continue
+		}
+		select {
+		case m.jobs <- id:
+			s.inFlight = true
+		default:
+			return
+		}
+	}
+}
+
+func (m *Monitor) worker() {
+	defer m.wg.Done()
+	for {
+		select {
+		case id := <-m.jobs:
+			interval := m.probe(id)
+			m.mu.Lock()
+			if s, ok := m.schedule[id]; ok {
+				s.inFlight = false
+				if s.rerun {
+					s.rerun = false
+					s.next = time.Time{}
+				} else {
+					s.next = time.Now().Add(interval)
 				}
 			}
+			m.mu.Unlock()
+		case <-m.stopChan:
+			return
 		}
-	}()
+	}
 }
 
-// checkReachable tries to TCP connect to the host:port extracted from the RTSP URL.
-// If no port is provided in the URL, default RTSP port 554 is used.
-func checkReachable(rtsp string, timeout time.Duration) bool {
-	if rtsp == "" {
-		return false
-	}
-	// Parse URL to obtain host (may include port)
-	u, err := url.Parse(rtsp)
+// probe checks one camera and stores the outcome. It returns the camera's
+// probe interval.
+func (m *Monitor) probe(id string) time.Duration {
+	c, err := m.repo.GetByID(id)
 	if err != nil {
-		// try to fallback: if string looks like ip:port
-		host := rtsp
-		return tryDial(host, timeout)
+		// deleted since it was scheduled
+		return m.cfg.Interval
+	}
+	interval, timeout := m.cfg.Interval, m.cfg.Timeout
+	if c.ProbeIntervalSeconds > 0 {
+		interval = time.Duration(c.ProbeIntervalSeconds) * time.Second
+	}
+	if c.ProbeTimeoutSeconds > 0 {
+		timeout = time.Duration(c.ProbeTimeoutSeconds) * time.Second
+	}
+	if status := c.ServiceStatus(time.Now()); status != "" {
+		m.outOfService(c, status)
+		if status == domain.CameraStatusMaintenance {
+			// probe again as soon as the window closes
+			if left := time.Until(*c.MaintenanceUntil); left < interval {
+				interval = left
+			}
+		}
+		return interval
+	}
+
+	res := probe(c, timeout)
+	if m.changedDuringProbe(id) {
+		// the result is for the old settings; the rerun stores the new one
+		return interval
+	}
+	now := time.Now()
+	var latency *int64
+	if res.Latency > 0 {
+		ms := res.Latency.Milliseconds()
+		latency = &ms
 	}
-	host := u.Host
-	if host == "" {
-		// maybe provided without scheme, try raw
-		host = rtsp
+	if err := m.repo.UpdateProbe(c.ID, res.Status, now, latency); err != nil {
+		log.Printf("monitor: failed to update camera %s status: %v", c.ID, err)
+		return interval
 	}
-	// If host contains path (no explicit host parse), attempt to trim
-	if strings.Contains(host, "/") {
-		parts := strings.SplitN(host, "/", 2)
-		host = parts[0]
+
+	previous := c.Status
+	changed := previous != res.Status
+	c.Status, c.LastProbeAt, c.ProbeLatencyMs = res.Status, &now, latency
+	if changed {
+		if res.Err != nil {
+			log.Printf("monitor: camera %s is %s: %v", c.ID, res.Status, res.Err)
+		}
+		publishStatus(c.ID, c.Name, previous, res)
+	}
+	reason := ""
+	if res.Err != nil {
+		reason = res.Err.Error()
 	}
-	// if no port, append default 554
-	if !strings.Contains(host, ":") {
-		host = host + ":554"
+	m.history.record(c.ID, previous, res.Status, reason, changed)
+	if res.Online() {
+		recordStream(m.repo, c, res)
 	}
-	return tryDial(host, timeout)
+	return interval
 }
 
-func tryDial(host string, timeout time.Duration) bool {
-	conn, err := net.DialTimeout("tcp", host, timeout)
-	if err != nil {
-		return false
+// changedDuringProbe reports whether the camera was updated while its probe
+// was running.
+func (m *Monitor) changedDuringProbe(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.schedule[id]
+	return ok && s.rerun
+}
+
+// outOfService sets the status of a camera that is disabled or in
+// maintenance instead of probing it.
+func (m *Monitor) outOfService(c *domain.Camera, status string) {
+	if c.Status == status {
+		return
+	}
+	if err := m.repo.UpdateStatus(c.ID, status); err != nil {
+		log.Printf("monitor: failed to update camera %s status: %v", c.ID, err)
+		return
+	}
+	log.Printf("monitor: camera %s is out of service (%s)", c.ID, status)
+	e := domain.Event{
+		Type:     events.CameraOutOfService,
+		CameraID: c.ID,
+		Severity: domain.SeverityInfo,
+		Payload:  map[string]interface{}{"name": c.Name, "previous_status": c.Status, "status": status},
+	}
+	reason := ""
+	if status == domain.CameraStatusMaintenance {
+		reason = c.MaintenanceReason
+		e.Payload["until"] = c.MaintenanceUntil
+		if reason != "" {
+			e.Payload["reason"] = reason
+		}
+	}
+	events.Publish(e)
+	m.history.record(c.ID, c.Status, status, reason, true)
+}
+
+// probe checks a camera's stream with RTSP OPTIONS and DESCRIBE.
+func probe(c *domain.Camera, timeout time.Duration) *rtsp.Result {
+	if c.RTSPURL == "" {
+		return &rtsp.Result{Status: domain.CameraStatusError, Err: errors.New("no RTSP URL")}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return rtsp.Probe(ctx, c.RTSPURL, c.Username, c.Password)
+}
+
+// publishStatus announces a camera status transition on the event bus.
+func publishStatus(cameraID, name, previous string, res *rtsp.Result) {
+	e := domain.Event{
+		Type:     events.CameraOnline,
+		CameraID: cameraID,
+		Severity: domain.SeverityInfo,
+		Payload:  map[string]interface{}{"name": name, "previous_status": previous, "status": res.Status},
+	}
+	if !res.Online() {
+		e.Type = events.CameraOffline
+		e.Severity = domain.SeverityWarning
+		if res.Err != nil {
+			e.Payload["error"] = res.Err.Error()
+		}
 	}
-	_ = conn.Close()
-	return true
+	events.Publish(e)
 }
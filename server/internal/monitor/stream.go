@@ -0,0 +1,128 @@
+package monitor
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/events"
+	"github.com/boytur/cctv-recording-center/server/internal/repository"
+	"github.com/boytur/cctv-recording-center/server/internal/rtsp"
+	"github.com/boytur/cctv-recording-center/server/internal/segment"
+)
+
+// recordingWindow is how far back recordings are looked at to fill in
+// stream parameters the session description leaves out.
+const recordingWindow = 30 * time.Minute
+
+// recordStream stores the stream parameters discovered by a successful probe
+// and flags a change against what the camera sent before.
+func recordStream(repo repository.CameraRepository, c *domain.Camera, res *rtsp.Result) {
+	info := streamInfo(c.ID, res.SDP, time.Now())
+
+	change := c.StreamChange
+	if c.Stream != nil {
+		if diff := info.Changes(*c.Stream); len(diff) > 0 {
+			change = &domain.StreamChange{At: info.ProbedAt, Changes: diff, Previous: *c.Stream}
+			log.Printf("monitor: camera %s stream changed: %s", c.ID, strings.Join(diff, ", "))
+			events.Publish(domain.Event{
+				Type:     events.CameraStreamChanged,
+				CameraID: c.ID,
+				Severity: domain.SeverityWarning,
+				Payload: map[string]interface{}{
+					"name":     c.Name,
+					"changes":  diff,
+					"previous": c.Stream,
+					"current":  info,
+				},
+			})
+		}
+	}
+
+	if err := repo.UpdateStream(c.ID, &info, change); err != nil {
+		log.Printf("monitor: failed to update camera %s stream: %v", c.ID, err)
+		return
+	}
+	c.Stream, c.StreamChange = &info, change
+}
+
+// streamInfo describes the stream from its SDP, falling back to the most
+// recent complete recording for what the SDP does not say.
+func streamInfo(cameraID string, sdp *rtsp.SessionDescription, now time.Time) domain.StreamInfo {
+	info := domain.StreamInfo{ProbedAt: now}
+	if sdp != nil {
+		if v := sdp.Video(); v != nil {
+			info.VideoCodec = codecName(v.Codec)
+			info.FrameRate = v.FrameRate
+			info.Bitrate = v.Bandwidth
+			if p, ok := v.VideoParams(); ok {
+				info.VideoProfile = p.Profile
+				info.Width, info.Height = p.Width, p.Height
+				if info.FrameRate == 0 {
+					info.FrameRate = p.FrameRate
+				}
+			}
+		}
+		if a := sdp.Audio(); a != nil {
+			info.AudioCodec = codecName(a.Codec)
+			info.AudioSampleRate = a.ClockRate
+		}
+	}
+
+	seg, ok := lastRecording(cameraID, now)
+	if !ok {
+		return info
+	}
+	if info.Bitrate == 0 && seg.Duration() > 0 {
+		info.Bitrate = int(float64(seg.Size*8) / seg.Duration().Seconds() / 1000)
+	}
+	if seg.Media != nil {
+		if t, ok := seg.Media.Video(); ok {
+			if info.Width == 0 || info.Height == 0 {
+				info.Width, info.Height = t.Width, t.Height
+			}
+			if info.VideoProfile == "" {
+				info.VideoProfile = t.Profile
+			}
+		}
+		if t, ok := seg.Media.Audio(); ok && info.AudioSampleRate == 0 {
+			info.AudioSampleRate = t.SampleRate
+		}
+	}
+	return info
+}
+
+// lastRecording returns the newest finished continuous recording.
+func lastRecording(cameraID string, now time.Time) (segment.Segment, bool) {
+	segs, err := segment.Range(cameraID, now.Add(-recordingWindow), now, segment.KindContinuous)
+	if err != nil {
+		return segment.Segment{}, false
+	}
+	for i := len(segs) - 1; i >= 0; i-- {
+		s := segs[i]
+		if s.Media != nil && !s.Media.Complete {
+			// still being written
+			continue
+		}
+		if s.Probed && s.Duration() > 0 {
+			return s, true
+		}
+	}
+	return segment.Segment{}, false
+}
+
+// codecName normalises RTP encoding names, e.g. MPEG4-GENERIC to AAC.
+func codecName(enc string) string {
+	switch enc = strings.ToUpper(enc); enc {
+	case "MPEG4-GENERIC", "MP4A-LATM":
+		return "AAC"
+	case "HEVC":
+		return "H265"
+	case "PCMU":
+		return "G711U"
+	case "PCMA":
+		return "G711A"
+	}
+	return enc
+}
@@ -0,0 +1,173 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// errShortBox is returned when a box body is smaller than its fields require.
+var errShortBox = errors.New("mp4: box too short")
+
+// errBoxPastEOF is returned for a box that extends past the end of the file,
+// as the box the recorder is appending to does.
+var errBoxPastEOF = errors.New("mp4: box extends past the end of the file")
+
+// boxHeader describes a box located in the file.
+type boxHeader struct {
+	typ    string
+	offset int64 // offset of the box header in the file
+	size   int64 // total box size including the header
+	hdrLen int64 // length of the header (8, or 16 for 64-bit sizes)
+}
+
+// readHeader reads the box header at off. fileSize is used to resolve boxes
+// whose size field is 0 ("extends to end of file") and to reject boxes that
+// don't fit in the file, so a corrupt size is never allocated or added to an
+// offset.
+func readHeader(r io.ReaderAt, off, fileSize int64) (boxHeader, error) {
+	var buf [16]byte
+	if _, err := r.ReadAt(buf[:8], off); err != nil {
+		return boxHeader{}, err
+	}
+	h := boxHeader{
+		typ:    string(buf[4:8]),
+		offset: off,
+		size:   int64(binary.BigEndian.Uint32(buf[0:4])),
+		hdrLen: 8,
+	}
+	switch h.size {
+	case 0:
+		h.size = fileSize - off
+	case 1:
+		if _, err := r.ReadAt(buf[8:16], off+8); err != nil {
+			return boxHeader{}, err
+		}
+		h.size = int64(binary.BigEndian.Uint64(buf[8:16]))
+		h.hdrLen = 16
+	}
+	if h.size < h.hdrLen {
+		return boxHeader{}, fmt.Errorf("mp4: invalid size %d for box %q at %d", h.size, h.typ, off)
+	}
+	if h.size > fileSize-off {
+		return boxHeader{}, errBoxPastEOF
+	}
+	return h, nil
+}
+
+// box is an in-memory child box.
+type box struct {
+	typ  string
+	body []byte
+}
+
+// children splits an in-memory container body into its child boxes. Trailing
+// bytes that don't form a complete box are ignored.
+func children(data []byte) []box {
+	var out []box
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data[0:4]))
+		typ := string(data[4:8])
+		hdr := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return out
+			}
+			size = binary.BigEndian.Uint64(data[8:16])
+			hdr = 16
+		}
+		if size < hdr || size > uint64(len(data)) {
+			return out
+		}
+		out = append(out, box{typ: typ, body: data[hdr:size]})
+		data = data[size:]
+	}
+	return out
+}
+
+// child returns the first child box of the given type.
+func child(data []byte, typ string) (box, bool) {
+	for _, b := range children(data) {
+		if b.typ == typ {
+			return b, true
+		}
+	}
+	return box{}, false
+}
+
+// reader is a bounds-checked big-endian cursor over a box body.
+type reader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *reader) need(n int) bool {
+	if r.err != nil {
+		return false
+	}
+	if r.pos+n > len(r.data) {
+		r.err = errShortBox
+		return false
+	}
+	return true
+}
+
+func (r *reader) skip(n int) {
+	if r.need(n) {
+		r.pos += n
+	}
+}
+
+func (r *reader) u8() uint8 {
+	if !r.need(1) {
+		return 0
+	}
+	v := r.data[r.pos]
+	r.pos++
+	return v
+}
+
+func (r *reader) u16() uint16 {
+	if !r.need(2) {
+		return 0
+	}
+	v := binary.BigEndian.Uint16(r.data[r.pos:])
+	r.pos += 2
+	return v
+}
+
+func (r *reader) u32() uint32 {
+	if !r.need(4) {
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.data[r.pos:])
+	r.pos += 4
+	return v
+}
+
+func (r *reader) u64() uint64 {
+	if !r.need(8) {
+		return 0
+	}
+	v := binary.BigEndian.Uint64(r.data[r.pos:])
+	r.pos += 8
+	return v
+}
+
+// fullBox reads the version and flags of a FullBox.
+func (r *reader) fullBox() (version uint8, flags uint32) {
+	v := r.u32()
+	return uint8(v >> 24), v & 0x00ffffff
+}
+
+func (r *reader) rest() []byte {
+	if r.err != nil || r.pos > len(r.data) {
+		return nil
+	}
+	return r.data[r.pos:]
+}
@@ -0,0 +1,130 @@
+package mp4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// mkbox builds a box of the given type around the concatenated parts.
+func mkbox(typ string, parts ...[]byte) []byte {
+	body := bytes.Join(parts, nil)
+	out := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(out, uint32(8+len(body)))
+	copy(out[4:], typ)
+	return append(out, body...)
+}
+
+func u16(v uint16) []byte { return binary.BigEndian.AppendUint16(nil, v) }
+func u32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+func u64(v uint64) []byte { return binary.BigEndian.AppendUint64(nil, v) }
+
+// fullBoxHeader is the version and flags field of a FullBox.
+func fullBoxHeader(version uint8, flags uint32) []byte {
+	return u32(uint32(version)<<24 | flags)
+}
+
+func TestReadHeader(t *testing.T) {
+	large := append(append(u32(1), "mdat"...), u64(32)...)
+	tests := []struct {
+		name     string
+		data     []byte
+		off      int64
+		fileSize int64
+		want     boxHeader
+		wantErr  bool
+	}{
+		{"32-bit size", mkbox("ftyp", []byte("isom")), 0, 12, boxHeader{typ: "ftyp", size: 12, hdrLen: 8}, false},
+		{"at an offset", append(make([]byte, 4), mkbox("free")...), 4, 12, boxHeader{typ: "free", offset: 4, size: 8, hdrLen: 8}, false},
+		{"64-bit size", large, 0, 32, boxHeader{typ: "mdat", size: 32, hdrLen: 16}, false},
+		{"to end of file", append(u32(0), "mdat"...), 0, 100, boxHeader{typ: "mdat", size: 100, hdrLen: 8}, false},
+		{"size smaller than header", append(u32(4), "moov"...), 0, 8, boxHeader{}, true},
+		{"truncated header", []byte{0, 0, 0}, 0, 3, boxHeader{}, true},
+		{"truncated 64-bit size", append(u32(1), "mdat"...), 0, 8, boxHeader{}, true},
+		{"past the end of the file", mkbox("moov", make([]byte, 8)), 0, 12, boxHeader{}, true},
+		{"64-bit size past the end of the file", append(append(u32(1), "mdat"...), u64(1<<62)...), 0, 32, boxHeader{}, true},
+		{"64-bit size that overflows the offset", append(append(append(make([]byte, 8), u32(1)...), "moov"...), u64(1<<63-1)...), 8, 40, boxHeader{}, true},
+		{"64-bit size above int64", append(append(u32(1), "moov"...), u64(1<<63+16)...), 0, 32, boxHeader{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readHeader(bytes.NewReader(tt.data), tt.off, tt.fileSize)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("readHeader() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readHeader: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("readHeader() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChildren(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want []box
+	}{
+		{
+			"siblings",
+			bytes.Join([][]byte{mkbox("mvhd", []byte{1, 2}), mkbox("trak"), mkbox("mvex", []byte{3})}, nil),
+			[]box{{"mvhd", []byte{1, 2}}, {"trak", []byte{}}, {"mvex", []byte{3}}},
+		},
+		{
+			"64-bit size",
+			append(append(append(u32(1), "udta"...), u64(18)...), 9, 9),
+			[]box{{"udta", []byte{9, 9}}},
+		},
+		{
+			"last box extends to the end",
+			append(mkbox("tkhd", []byte{1}), append(append(u32(0), "mdia"...), 7, 7, 7)...),
+			[]box{{"tkhd", []byte{1}}, {"mdia", []byte{7, 7, 7}}},
+		},
+		{
+			"trailing partial box is ignored",
+			append(mkbox("tkhd", []byte{1}), append(u32(20), "mdia"...)...),
+			[]box{{"tkhd", []byte{1}}},
+		},
+		{
+			"trailing bytes shorter than a header",
+			append(mkbox("tkhd"), 0, 0, 0),
+			[]box{{"tkhd", []byte{}}},
+		},
+		{"invalid size", append(u32(3), "tkhd"...), nil},
+		{"empty", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := children(tt.data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("children() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReader(t *testing.T) {
+	r := &reader{data: append(append(fullBoxHeader(1, 0x020001), u16(7)...), u64(1<<40)...)}
+	v, flags := r.fullBox()
+	if v != 1 || flags != 0x020001 {
+		t.Errorf("fullBox() = %d, %#x, want 1, 0x20001", v, flags)
+	}
+	if got := r.u16(); got != 7 {
+		t.Errorf("u16() = %d, want 7", got)
+	}
+	if got := r.u64(); got != 1<<40 {
+		t.Errorf("u64() = %d, want %d", got, uint64(1<<40))
+	}
+	if got := r.u8(); got != 0 || r.err != errShortBox {
+		t.Errorf("u8() past the end = %d, %v, want 0, %v", got, r.err, errShortBox)
+	}
+	if got := r.rest(); got != nil {
+		t.Errorf("rest() after an error = %v, want nil", got)
+	}
+}
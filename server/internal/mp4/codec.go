@@ -0,0 +1,172 @@
+package mp4
+
+import (
+	"fmt"
+	"strings"
+)
+
+// avcProfiles names the H.264 profile_idc values cameras commonly use.
+var avcProfiles = map[uint8]string{
+	66:  "Baseline",
+	77:  "Main",
+	88:  "Extended",
+	100: "High",
+	110: "High 10",
+	122: "High 4:2:2",
+	244: "High 4:4:4",
+}
+
+// hevcProfiles names the H.265 general_profile_idc values.
+var hevcProfiles = map[uint8]string{
+	1: "Main",
+	2: "Main 10",
+	3: "Main Still Picture",
+	4: "Range Extensions",
+}
+
+// AVCProfile returns the name of an H.264 profile_idc, or "" if unknown.
+func AVCProfile(idc uint8) string {
+	return avcProfiles[idc]
+}
+
+// HEVCProfile returns the name of an H.265 general_profile_idc, or "" if
+// unknown.
+func HEVCProfile(idc uint8) string {
+	return hevcProfiles[idc]
+}
+
+// avcCodec builds the codec string (e.g. "avc1.640028") and profile name from
+// an AVCDecoderConfigurationRecord.
+func avcCodec(entry string, avcC []byte) (string, string) {
+	if len(avcC) < 4 {
+		return entry, ""
+	}
+	profile, compat, level := avcC[1], avcC[2], avcC[3]
+	return fmt.Sprintf("%s.%02x%02x%02x", entry, profile, compat, level), avcProfiles[profile]
+}
+
+// hevcCodec builds the ISO/IEC 14496-15 codec string (e.g. "hvc1.1.6.L120.B0")
+// and profile name from an HEVCDecoderConfigurationRecord.
+func hevcCodec(entry string, hvcC []byte) (string, string) {
+	if len(hvcC) < 13 {
+		return entry, ""
+	}
+	space := hvcC[1] >> 6
+	tier := (hvcC[1] >> 5) & 0x01
+	profile := hvcC[1] & 0x1f
+	compat := uint32(hvcC[2])<<24 | uint32(hvcC[3])<<16 | uint32(hvcC[4])<<8 | uint32(hvcC[5])
+	constraints := hvcC[6:12]
+	level := hvcC[12]
+
+	// compatibility flags are written in reverse bit order
+	var reversed uint32
+	for i := 0; i < 32; i++ {
+		if compat&(1<<uint(i)) != 0 {
+			reversed |= 1 << uint(31-i)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(entry)
+	b.WriteByte('.')
+	if space > 0 {
+		b.WriteByte('A' + space - 1)
+	}
+	fmt.Fprintf(&b, "%d.%x.", profile, reversed)
+	if tier == 1 {
+		b.WriteByte('H')
+	} else {
+		b.WriteByte('L')
+	}
+	fmt.Fprintf(&b, "%d", level)
+
+	last := len(constraints)
+	for last > 0 && constraints[last-1] == 0 {
+		last--
+	}
+	if last == 0 {
+		// at least one constraint byte is always written
+		last = 1
+	}
+	for _, c := range constraints[:last] {
+		fmt.Fprintf(&b, ".%X", c)
+	}
+	return b.String(), hevcProfiles[profile]
+}
+
+// aacObjectType extracts the audio object type from an esds box body, or 0 if
+// it cannot be found.
+func aacObjectType(esds []byte) int {
+	if len(esds) < 4 {
+		return 0
+	}
+	data := esds[4:] // skip version and flags
+	for len(data) > 0 {
+		tag, body, rest, ok := descriptor(data)
+		if !ok {
+			return 0
+		}
+		switch tag {
+		case 0x03: // ES_Descriptor
+			if len(body) < 3 {
+				return 0
+			}
+			flags := body[2]
+			body = body[3:]
+			if flags&0x80 != 0 && len(body) >= 2 {
+				body = body[2:]
+			}
+			if flags&0x40 != 0 && len(body) >= 1 {
+				n := int(body[0])
+				if len(body) < 1+n {
+					return 0
+				}
+				body = body[1+n:]
+			}
+			if flags&0x20 != 0 && len(body) >= 2 {
+				body = body[2:]
+			}
+			data = body
+			continue
+		case 0x04: // DecoderConfigDescriptor
+			if len(body) < 13 {
+				return 0
+			}
+			data = body[13:]
+			continue
+		case 0x05: // DecoderSpecificInfo (AudioSpecificConfig)
+			if len(body) < 1 {
+				return 0
+			}
+			aot := int(body[0] >> 3)
+			if aot == 31 && len(body) >= 2 {
+				aot = 32 + (int(body[0]&0x07)<<3 | int(body[1]>>5))
+			}
+			return aot
+		}
+		data = rest
+	}
+	return 0
+}
+
+// descriptor splits an MPEG-4 descriptor into its tag, body and the bytes
+// following it.
+func descriptor(data []byte) (byte, []byte, []byte, bool) {
+	if len(data) < 2 {
+		return 0, nil, nil, false
+	}
+	tag := data[0]
+	size := 0
+	i := 1
+	for ; i < len(data) && i <= 4; i++ {
+		size = size<<7 | int(data[i]&0x7f)
+		if data[i]&0x80 == 0 {
+			i++
+			break
+		}
+	}
+	if i+size > len(data) {
+		return 0, nil, nil, false
+	}
+	return tag, data[i : i+size], data[i+size:], true
+}
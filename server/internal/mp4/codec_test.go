@@ -0,0 +1,94 @@
+package mp4
+
+import "testing"
+
+func TestAVCCodec(t *testing.T) {
+	tests := []struct {
+		avcC        []byte
+		wantCodec   string
+		wantProfile string
+	}{
+		{[]byte{1, 0x64, 0x00, 0x28, 0xff}, "avc1.640028", "High"},
+		{[]byte{1, 0x4d, 0x40, 0x1f}, "avc1.4d401f", "Main"},
+		{[]byte{1, 0x42, 0xc0, 0x1e}, "avc1.42c01e", "Baseline"},
+		{[]byte{1, 0x07, 0x00, 0x1e}, "avc1.07001e", ""},
+		{[]byte{1, 0x64}, "avc1", ""},
+	}
+	for _, tt := range tests {
+		codec, profile := avcCodec("avc1", tt.avcC)
+		if codec != tt.wantCodec || profile != tt.wantProfile {
+			t.Errorf("avcCodec(% x) = %q, %q, want %q, %q", tt.avcC, codec, profile, tt.wantCodec, tt.wantProfile)
+		}
+	}
+}
+
+func TestHEVCCodec(t *testing.T) {
+	tests := []struct {
+		entry       string
+		hvcC        []byte
+		wantCodec   string
+		wantProfile string
+	}{
+		{
+			"hvc1",
+			[]byte{1, 0x01, 0x60, 0x00, 0x00, 0x00, 0x90, 0, 0, 0, 0, 0, 120},
+			"hvc1.1.6.L120.90", "Main",
+		},
+		{
+			// Main 10, high tier, several constraint bytes
+			"hev1",
+			[]byte{1, 0x22, 0x20, 0x00, 0x00, 0x00, 0xb0, 0x01, 0, 0, 0, 0, 153},
+			"hev1.2.4.H153.B0.1", "Main 10",
+		},
+		{
+			// profile space 1, no constraint flags
+			"hvc1",
+			[]byte{1, 0x41, 0x60, 0x00, 0x00, 0x00, 0, 0, 0, 0, 0, 0, 93},
+			"hvc1.A1.6.L93.0", "Main",
+		},
+		{"hvc1", []byte{1, 0x01, 0x60}, "hvc1", ""},
+	}
+	for _, tt := range tests {
+		codec, profile := hevcCodec(tt.entry, tt.hvcC)
+		if codec != tt.wantCodec || profile != tt.wantProfile {
+			t.Errorf("hevcCodec(% x) = %q, %q, want %q, %q", tt.hvcC, codec, profile, tt.wantCodec, tt.wantProfile)
+		}
+	}
+}
+
+func TestAACObjectType(t *testing.T) {
+	// ES_Descriptor > DecoderConfigDescriptor > DecoderSpecificInfo
+	esds := func(esFlags byte, esExtra []byte, asc ...byte) []byte {
+		dsi := append([]byte{0x05, byte(len(asc))}, asc...)
+		dcd := append([]byte{0x04, byte(13 + len(dsi)), 0x40, 0x15, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, dsi...)
+		es := append(append([]byte{0, 1, esFlags}, esExtra...), dcd...)
+		return append([]byte{0, 0, 0, 0, 0x03, byte(len(es))}, es...)
+	}
+	tests := []struct {
+		name string
+		esds []byte
+		want int
+	}{
+		{"AAC LC", esds(0, nil, 0x14, 0x08), 2},
+		{"HE-AAC", esds(0, nil, 0x2b, 0x92), 5},
+		{"escaped object type", esds(0, nil, 0xf8, 0x20), 33},
+		{"ES_Descriptor with dependsOn and URL", esds(0xc0, []byte{0, 2, 3, 'a', 'b', 'c'}, 0x12, 0x10), 2},
+		{
+			"multi-byte descriptor sizes",
+			[]byte{0, 0, 0, 0,
+				0x03, 0x80, 0x80, 0x19, 0, 1, 0,
+				0x04, 0x80, 0x80, 0x12, 0x40, 0x15, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+				0x05, 0x80, 0x02, 0x11, 0x90},
+			2,
+		},
+		{"truncated", esds(0, nil, 0x14, 0x08)[:12], 0},
+		{"too short", []byte{0, 0}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := aacObjectType(tt.esds); got != tt.want {
+				t.Errorf("aacObjectType(% x) = %d, want %d", tt.esds, got, tt.want)
+			}
+		})
+	}
+}
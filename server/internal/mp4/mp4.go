@@ -0,0 +1,573 @@
+// Package mp4 reads the structure of the fragmented MP4 files written by the
+// recorder (movflags=frag_keyframe+empty_moov+default_base_moof) without
+// decoding any media. It reports duration, codec parameters, resolution and
+// the byte range and start time of every fragment, which always begins on a
+// keyframe for these files.
+package mp4
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Track kinds.
+const (
+	KindVideo = "video"
+	KindAudio = "audio"
+)
+
+// File is the parsed structure of an MP4 file.
+type File struct {
+	// Duration is the media duration, computed from the fragments for
+	// fragmented files and from the movie header otherwise.
+	Duration time.Duration
+	// InitSize is the number of bytes before the first fragment (ftyp+moov),
+	// i.e. the initialization segment.
+	InitSize  int64
+	Tracks    []Track
+	Fragments []Fragment
+	// Complete is false when the file ends in a partially written box, as is
+	// the case for the segment the recorder is currently appending to.
+	Complete bool
+}
+
+// Track describes a single media track.
+type Track struct {
+	ID        uint32
+	Kind      string
+	Codec     string // RFC 6381 codec string, e.g. "avc1.640028"
+	Profile   string
+	Timescale uint32
+	Width     int
+	Height    int
+	// SampleRate and Channels are set for audio tracks.
+	SampleRate int
+	Channels   int
+	Duration   time.Duration
+}
+
+// Fragment is a moof+mdat pair.
+type Fragment struct {
+	Sequence uint32
+	// Offset and Size give the byte range of the moof and its mdat.
+	Offset int64
+	Size   int64
+	// Start and Duration are relative to the start of the file's timeline,
+	// measured on the video track when there is one.
+	Start    time.Duration
+	Duration time.Duration
+	// Keyframe reports whether the fragment starts with a sync sample.
+	Keyframe bool
+}
+
+// Keyframe is a seekable position in the file.
+type Keyframe struct {
+	Time   time.Duration
+	Offset int64
+}
+
+// Video returns the first video track.
+func (f *File) Video() (Track, bool) {
+	for _, t := range f.Tracks {
+		if t.Kind == KindVideo {
+			return t, true
+		}
+	}
+	return Track{}, false
+}
+
+// Audio returns the first audio track.
+func (f *File) Audio() (Track, bool) {
+	for _, t := range f.Tracks {
+		if t.Kind == KindAudio {
+			return t, true
+		}
+	}
+	return Track{}, false
+}
+
+// Keyframes returns the start of every fragment that begins with a keyframe.
+func (f *File) Keyframes() []Keyframe {
+	out := make([]Keyframe, 0, len(f.Fragments))
+	for _, fr := range f.Fragments {
+		if fr.Keyframe {
+			out = append(out, Keyframe{Time: fr.Start, Offset: fr.Offset})
+		}
+	}
+	return out
+}
+
+// KeyframeAt returns the last keyframe at or before t, or the first keyframe
+// if t precedes all of them.
+func (f *File) KeyframeAt(t time.Duration) (Keyframe, bool) {
+	kfs := f.Keyframes()
+	if len(kfs) == 0 {
+		return Keyframe{}, false
+	}
+	best := kfs[0]
+	for _, kf := range kfs[1:] {
+		if kf.Time > t {
+			break
+		}
+		best = kf
+	}
+	return best, true
+}
+
+// ParseFile opens and parses the MP4 file at path.
+func ParseFile(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return Parse(f, info.Size())
+}
+
+// trackState carries per-track values needed while walking fragments.
+type trackState struct {
+	track       *Track
+	trex        trex
+	firstDecode uint64
+	endDecode   uint64
+	seen        bool
+}
+
+type trex struct {
+	duration uint32
+	size     uint32
+	flags    uint32
+}
+
+// Parse walks the top-level boxes of an MP4 file of the given size.
+func Parse(r io.ReaderAt, size int64) (*File, error) {
+	file := &File{Complete: true}
+	var (
+		movieTimescale uint32
+		movieDuration  uint64
+		states         = map[uint32]*trackState{}
+		videoID        uint32
+		haveMoov       bool
+		pending        *Fragment
+		// track states from before the pending fragment's moof, restored
+		// if its mdat is never written
+		before map[uint32]trackState
+	)
+
+	for off := int64(0); off < size; {
+		h, err := readHeader(r, off, size)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, errBoxPastEOF) {
+				// box still being written
+				file.Complete = false
+				break
+			}
+			return nil, err
+		}
+
+		switch h.typ {
+		case "moov":
+			body, err := readBody(r, h)
+			if err != nil {
+				return nil, err
+			}
+			movieTimescale, movieDuration, err = parseMoov(body, file, states)
+			if err != nil {
+				return nil, err
+			}
+			for _, st := range states {
+				if st.track.Kind == KindVideo && videoID == 0 {
+					videoID = st.track.ID
+				}
+			}
+			if videoID == 0 && len(file.Tracks) > 0 {
+				videoID = file.Tracks[0].ID
+			}
+			haveMoov = true
+		case "moof":
+			if !haveMoov {
+				return nil, errors.New("mp4: moof before moov")
+			}
+			if file.InitSize == 0 {
+				file.InitSize = off
+			}
+			body, err := readBody(r, h)
+			if err != nil {
+				return nil, err
+			}
+			before = make(map[uint32]trackState, len(states))
+			for id, st := range states {
+				before[id] = *st
+			}
+			fr, err := parseMoof(body, states, videoID)
+			if err != nil {
+				return nil, fmt.Errorf("mp4: fragment at %d: %w", off, err)
+			}
+			fr.Offset = off
+			fr.Size = h.size
+			pending = &fr
+		case "mdat":
+			if pending != nil {
+				pending.Size += h.size
+				file.Fragments = append(file.Fragments, *pending)
+				pending = nil
+			}
+		}
+		off += h.size
+	}
+
+	if !haveMoov {
+		return nil, errors.New("mp4: no moov box")
+	}
+	if pending != nil {
+		// moof without its mdat yet: its samples aren't in the file
+		file.Complete = false
+		for id, st := range before {
+			*states[id] = st
+		}
+	}
+
+	// Fragment times are relative to the first decode time so files whose
+	// timestamps don't start at zero still report offsets from their start.
+	if st, ok := states[videoID]; ok && st.seen {
+		base := ticks(st.firstDecode, st.track.Timescale)
+		for i := range file.Fragments {
+			file.Fragments[i].Start -= base
+		}
+	}
+	for _, st := range states {
+		if st.seen {
+			st.track.Duration = ticks(st.endDecode-st.firstDecode, st.track.Timescale)
+		}
+	}
+
+	if st, ok := states[videoID]; ok && st.seen {
+		file.Duration = st.track.Duration
+	} else if movieTimescale > 0 {
+		file.Duration = ticks(movieDuration, movieTimescale)
+	}
+	if file.InitSize == 0 {
+		file.InitSize = size
+	}
+	return file, nil
+}
+
+func readBody(r io.ReaderAt, h boxHeader) ([]byte, error) {
+	body := make([]byte, h.size-h.hdrLen)
+	if _, err := r.ReadAt(body, h.offset+h.hdrLen); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func ticks(v uint64, timescale uint32) time.Duration {
+	if timescale == 0 {
+		return 0
+	}
+	sec := v / uint64(timescale)
+	rem := v % uint64(timescale)
+	return time.Duration(sec)*time.Second + time.Duration(rem)*time.Second/time.Duration(timescale)
+}
+
+// parseMoov fills in the track list and returns the movie timescale and
+// duration from mvhd.
+func parseMoov(body []byte, file *File, states map[uint32]*trackState) (uint32, uint64, error) {
+	var timescale uint32
+	var duration uint64
+	if mvhd, ok := child(body, "mvhd"); ok {
+		r := &reader{data: mvhd.body}
+		v, _ := r.fullBox()
+		if v == 1 {
+			r.skip(16)
+			timescale = r.u32()
+			duration = r.u64()
+		} else {
+			r.skip(8)
+			timescale = r.u32()
+			duration = uint64(r.u32())
+		}
+		if r.err != nil {
+			return 0, 0, fmt.Errorf("mp4: mvhd: %w", r.err)
+		}
+	}
+
+	for _, b := range children(body) {
+		if b.typ != "trak" {
+			continue
+		}
+		t, err := parseTrak(b.body)
+		if err != nil {
+			return 0, 0, err
+		}
+		if t.Kind == "" {
+			continue
+		}
+		file.Tracks = append(file.Tracks, t)
+	}
+	for i := range file.Tracks {
+		t := &file.Tracks[i]
+		states[t.ID] = &trackState{track: t}
+	}
+
+	if mvex, ok := child(body, "mvex"); ok {
+		for _, b := range children(mvex.body) {
+			if b.typ != "trex" {
+				continue
+			}
+			r := &reader{data: b.body}
+			r.fullBox()
+			id := r.u32()
+			r.skip(4) // default_sample_description_index
+			tx := trex{duration: r.u32(), size: r.u32(), flags: r.u32()}
+			if r.err != nil {
+				return 0, 0, fmt.Errorf("mp4: trex: %w", r.err)
+			}
+			if st, ok := states[id]; ok {
+				st.trex = tx
+			}
+		}
+	}
+	return timescale, duration, nil
+}
+
+func parseTrak(body []byte) (Track, error) {
+	var t Track
+	if tkhd, ok := child(body, "tkhd"); ok {
+		r := &reader{data: tkhd.body}
+		v, _ := r.fullBox()
+		if v == 1 {
+			r.skip(16)
+		} else {
+			r.skip(8)
+		}
+		t.ID = r.u32()
+		if r.err != nil {
+			return t, fmt.Errorf("mp4: tkhd: %w", r.err)
+		}
+	}
+	mdia, ok := child(body, "mdia")
+	if !ok {
+		return t, nil
+	}
+	if mdhd, ok := child(mdia.body, "mdhd"); ok {
+		r := &reader{data: mdhd.body}
+		v, _ := r.fullBox()
+		if v == 1 {
+			r.skip(16)
+		} else {
+			r.skip(8)
+		}
+		t.Timescale = r.u32()
+		if r.err != nil {
+			return t, fmt.Errorf("mp4: mdhd: %w", r.err)
+		}
+	}
+	if hdlr, ok := child(mdia.body, "hdlr"); ok && len(hdlr.body) >= 12 {
+		switch string(hdlr.body[8:12]) {
+		case "vide":
+			t.Kind = KindVideo
+		case "soun":
+			t.Kind = KindAudio
+		}
+	}
+	minf, ok := child(mdia.body, "minf")
+	if !ok {
+		return t, nil
+	}
+	stbl, ok := child(minf.body, "stbl")
+	if !ok {
+		return t, nil
+	}
+	if stsd, ok := child(stbl.body, "stsd"); ok && len(stsd.body) > 8 {
+		entries := children(stsd.body[8:])
+		if len(entries) > 0 {
+			parseSampleEntry(&t, entries[0])
+		}
+	}
+	return t, nil
+}
+
+// parseSampleEntry reads the resolution or audio format from the first
+// sample description and derives the codec string.
+func parseSampleEntry(t *Track, e box) {
+	t.Codec = e.typ
+	switch t.Kind {
+	case KindVideo:
+		// SampleEntry(8) + VisualSampleEntry fields up to width/height
+		r := &reader{data: e.body}
+		r.skip(24)
+		t.Width = int(r.u16())
+		t.Height = int(r.u16())
+		if len(e.body) < 78 {
+			return
+		}
+		for _, b := range children(e.body[78:]) {
+			switch b.typ {
+			case "avcC":
+				t.Codec, t.Profile = avcCodec(e.typ, b.body)
+			case "hvcC":
+				t.Codec, t.Profile = hevcCodec(e.typ, b.body)
+			}
+		}
+	case KindAudio:
+		// SampleEntry(8) + reserved(8) + channelcount, samplesize,
+		// pre_defined, reserved, samplerate (16.16)
+		r := &reader{data: e.body}
+		r.skip(16)
+		t.Channels = int(r.u16())
+		r.skip(6)
+		t.SampleRate = int(r.u32() >> 16)
+		if len(e.body) < 28 {
+			return
+		}
+		if esds, ok := child(e.body[28:], "esds"); ok && e.typ == "mp4a" {
+			if aot := aacObjectType(esds.body); aot > 0 {
+				t.Codec = fmt.Sprintf("mp4a.40.%d", aot)
+			}
+		}
+	}
+}
+
+// parseMoof reads one movie fragment and returns it without its byte range.
+// Track decode-time bookkeeping in states is updated as a side effect.
+func parseMoof(body []byte, states map[uint32]*trackState, videoID uint32) (Fragment, error) {
+	var fr Fragment
+	if mfhd, ok := child(body, "mfhd"); ok {
+		r := &reader{data: mfhd.body}
+		r.fullBox()
+		fr.Sequence = r.u32()
+	}
+
+	for _, traf := range children(body) {
+		if traf.typ != "traf" {
+			continue
+		}
+		tfhd, ok := child(traf.body, "tfhd")
+		if !ok {
+			return fr, errors.New("traf without tfhd")
+		}
+		r := &reader{data: tfhd.body}
+		_, flags := r.fullBox()
+		id := r.u32()
+		st, ok := states[id]
+		if !ok {
+			continue
+		}
+		defDuration, defFlags := st.trex.duration, st.trex.flags
+		if flags&0x01 != 0 {
+			r.skip(8) // base_data_offset
+		}
+		if flags&0x02 != 0 {
+			r.skip(4) // sample_description_index
+		}
+		if flags&0x08 != 0 {
+			defDuration = r.u32()
+		}
+		if flags&0x10 != 0 {
+			r.skip(4) // default_sample_size
+		}
+		if flags&0x20 != 0 {
+			defFlags = r.u32()
+		}
+		if r.err != nil {
+			return fr, fmt.Errorf("tfhd: %w", r.err)
+		}
+
+		var decode uint64
+		if tfdt, ok := child(traf.body, "tfdt"); ok {
+			r := &reader{data: tfdt.body}
+			v, _ := r.fullBox()
+			if v == 1 {
+				decode = r.u64()
+			} else {
+				decode = uint64(r.u32())
+			}
+			if r.err != nil {
+				return fr, fmt.Errorf("tfdt: %w", r.err)
+			}
+		} else {
+			decode = st.endDecode
+		}
+		if !st.seen {
+			st.firstDecode = decode
+			st.seen = true
+		}
+
+		var total uint64
+		keyframe := false
+		first := true
+		for _, trun := range children(traf.body) {
+			if trun.typ != "trun" {
+				continue
+			}
+			dur, sync, err := parseTrun(trun.body, defDuration, defFlags)
+			if err != nil {
+				return fr, err
+			}
+			if first {
+				keyframe = sync
+				first = false
+			}
+			total += dur
+		}
+		st.endDecode = decode + total
+
+		if id == videoID {
+			fr.Start = ticks(decode, st.track.Timescale)
+			fr.Duration = ticks(total, st.track.Timescale)
+			fr.Keyframe = keyframe
+		}
+	}
+	return fr, nil
+}
+
+// parseTrun returns the summed sample duration of a track run and whether
+// its first sample is a sync sample.
+func parseTrun(body []byte, defDuration, defFlags uint32) (uint64, bool, error) {
+	r := &reader{data: body}
+	_, flags := r.fullBox()
+	count := r.u32()
+	if flags&0x01 != 0 {
+		r.skip(4) // data_offset
+	}
+	firstFlags := defFlags
+	if flags&0x04 != 0 {
+		firstFlags = r.u32()
+	}
+	var total uint64
+	for i := uint32(0); i < count; i++ {
+		d := defDuration
+		if flags&0x100 != 0 {
+			d = r.u32()
+		}
+		if flags&0x200 != 0 {
+			r.skip(4) // sample_size
+		}
+		sampleFlags := defFlags
+		if flags&0x400 != 0 {
+			sampleFlags = r.u32()
+		}
+		if flags&0x800 != 0 {
+			r.skip(4) // composition time offset
+		}
+		if i == 0 && flags&0x04 == 0 {
+			firstFlags = sampleFlags
+		}
+		total += uint64(d)
+		if r.err != nil {
+			return 0, false, fmt.Errorf("trun: %w", r.err)
+		}
+	}
+	if r.err != nil {
+		return 0, false, fmt.Errorf("trun: %w", r.err)
+	}
+	// sample_is_non_sync_sample
+	return total, firstFlags&0x00010000 == 0, nil
+}
@@ -0,0 +1,215 @@
+package mp4
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	videoTimescale = 90000
+	audioTimescale = 16000
+	// 25 fps
+	frameTicks = videoTimescale / 25
+)
+
+func trakBox(id, timescale uint32, handler string, entry []byte) []byte {
+	return mkbox("trak",
+		mkbox("tkhd", fullBoxHeader(0, 3), u32(0), u32(0), u32(id)),
+		mkbox("mdia",
+			mkbox("mdhd", fullBoxHeader(0, 0), u32(0), u32(0), u32(timescale), u32(0)),
+			mkbox("hdlr", fullBoxHeader(0, 0), u32(0), []byte(handler)),
+			mkbox("minf", mkbox("stbl", mkbox("stsd", fullBoxHeader(0, 0), u32(1), entry))),
+		),
+	)
+}
+
+// initSegment is the ftyp and moov of a recording with a 1920x1080 H.264
+// track 1 and an AAC track 2, as written with empty_moov.
+func initSegment() []byte {
+	avc1 := mkbox("avc1",
+		make([]byte, 24), u16(1920), u16(1080), make([]byte, 50),
+		mkbox("avcC", []byte{1, 0x64, 0x00, 0x28, 0xff}),
+	)
+	mp4a := mkbox("mp4a",
+		make([]byte, 16), u16(1), make([]byte, 6), u32(audioTimescale<<16),
+		mkbox("esds", fullBoxHeader(0, 0), []byte{
+			0x03, 22, 0, 1, 0,
+			0x04, 17, 0x40, 0x15, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+			0x05, 2, 0x14, 0x08,
+		}),
+	)
+	return append(
+		mkbox("ftyp", []byte("isom"), u32(512)),
+		mkbox("moov",
+			mkbox("mvhd", fullBoxHeader(0, 0), u32(0), u32(0), u32(1000), u32(0)),
+			trakBox(1, videoTimescale, "vide", avc1),
+			trakBox(2, audioTimescale, "soun", mp4a),
+			// a metadata track is left out of Tracks
+			trakBox(3, 1000, "meta", mkbox("mett")),
+			mkbox("mvex",
+				mkbox("trex", fullBoxHeader(0, 0), u32(1), u32(1), u32(frameTicks), u32(0), u32(0x00010000)),
+				mkbox("trex", fullBoxHeader(0, 0), u32(2), u32(1), u32(1024), u32(0), u32(0)),
+			),
+		)...,
+	)
+}
+
+// fragment is a one second moof+mdat. The video run takes the trex defaults
+// and marks the first sample as sync with first_sample_flags when keyframe is
+// set; the audio run has explicit durations and no tfdt.
+func fragment(seq uint32, decode uint64, keyframe bool) []byte {
+	firstFlags := uint32(0x00010000)
+	if keyframe {
+		firstFlags = 0x02000000
+	}
+	return append(
+		mkbox("moof",
+			mkbox("mfhd", fullBoxHeader(0, 0), u32(seq)),
+			mkbox("traf",
+				mkbox("tfhd", fullBoxHeader(0, 0x020000), u32(1)),
+				mkbox("tfdt", fullBoxHeader(1, 0), u64(decode)),
+				mkbox("trun", fullBoxHeader(0, 0x000005), u32(25), u32(0), u32(firstFlags)),
+			),
+			mkbox("traf",
+				mkbox("tfhd", fullBoxHeader(0, 0x020000), u32(2)),
+				mkbox("trun", fullBoxHeader(0, 0x000100), u32(2), u32(audioTimescale/2), u32(audioTimescale/2)),
+			),
+		),
+		mkbox("mdat", make([]byte, 100))...,
+	)
+}
+
+// recording is an init segment followed by three fragments whose video
+// decode times start at two seconds; the second doesn't begin on a keyframe.
+func recording() (data []byte, initSize int64, fragSizes []int64) {
+	data = initSegment()
+	initSize = int64(len(data))
+	for i, kf := range []bool{true, false, true} {
+		fr := fragment(uint32(i+1), uint64(2+i)*videoTimescale, kf)
+		fragSizes = append(fragSizes, int64(len(fr)))
+		data = append(data, fr...)
+	}
+	return data, initSize, fragSizes
+}
+
+func TestParse(t *testing.T) {
+	data, initSize, sizes := recording()
+	f, err := Parse(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := &File{
+		Duration: 3 * time.Second,
+		InitSize: initSize,
+		Tracks: []Track{
+			{ID: 1, Kind: KindVideo, Codec: "avc1.640028", Profile: "High", Timescale: videoTimescale, Width: 1920, Height: 1080, Duration: 3 * time.Second},
+			{ID: 2, Kind: KindAudio, Codec: "mp4a.40.2", Timescale: audioTimescale, SampleRate: audioTimescale, Channels: 1, Duration: 3 * time.Second},
+		},
+		Fragments: []Fragment{
+			{Sequence: 1, Offset: initSize, Size: sizes[0], Start: 0, Duration: time.Second, Keyframe: true},
+			{Sequence: 2, Offset: initSize + sizes[0], Size: sizes[1], Start: time.Second, Duration: time.Second},
+			{Sequence: 3, Offset: initSize + sizes[0] + sizes[1], Size: sizes[2], Start: 2 * time.Second, Duration: time.Second, Keyframe: true},
+		},
+		Complete: true,
+	}
+	if !reflect.DeepEqual(f, want) {
+		t.Errorf("Parse() =\n%+v\nwant\n%+v", f, want)
+	}
+}
+
+func TestParseIncomplete(t *testing.T) {
+	data, initSize, sizes := recording()
+	whole := initSize + sizes[0] + sizes[1]
+	tests := []struct {
+		name      string
+		size      int64
+		fragments int
+	}{
+		{"mdat being written", int64(len(data)) - 10, 2},
+		{"moof without mdat", whole + sizes[2] - 108, 2},
+		{"partial box header", whole + 4, 2},
+		{"init segment only", initSize, 0},
+	}
+	// a moof claiming a size that would overflow the file offset
+	huge := append(append(append(append([]byte{}, data[:whole]...), u32(1)...), "moof"...), u64(1<<63-1)...)
+	f, err := Parse(bytes.NewReader(huge), int64(len(huge)))
+	if err != nil || f.Complete || len(f.Fragments) != 2 {
+		t.Errorf("Parse(huge moof) = %+v, %v, want the 2 fragments before it and Complete = false", f, err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse(bytes.NewReader(data[:tt.size]), tt.size)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if tt.fragments > 0 && f.Complete {
+				t.Error("Complete = true for a truncated file")
+			}
+			if len(f.Fragments) != tt.fragments {
+				t.Errorf("got %d fragments, want %d", len(f.Fragments), tt.fragments)
+			}
+			if got := time.Duration(tt.fragments) * time.Second; f.Duration != got {
+				t.Errorf("Duration = %s, want %s", f.Duration, got)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	init := initSegment()
+	ftyp := init[:16]
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"no moov", ftyp},
+		{"moof before moov", append(append([]byte{}, ftyp...), fragment(1, 0, true)...)},
+		{"invalid box size", append(append([]byte{}, ftyp...), 0, 0, 0, 2, 'm', 'o', 'o', 'v')},
+		{"short mvhd", append(append([]byte{}, ftyp...), mkbox("moov", mkbox("mvhd", fullBoxHeader(0, 0)))...)},
+		{"traf without tfhd", append(append([]byte{}, init...), mkbox("moof", mkbox("traf"))...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if f, err := Parse(bytes.NewReader(tt.data), int64(len(tt.data))); err == nil {
+				t.Errorf("Parse() = %+v, want error", f)
+			}
+		})
+	}
+}
+
+func TestKeyframeAt(t *testing.T) {
+	data, initSize, sizes := recording()
+	f, err := Parse(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	first := Keyframe{Time: 0, Offset: initSize}
+	third := Keyframe{Time: 2 * time.Second, Offset: initSize + sizes[0] + sizes[1]}
+	if got := f.Keyframes(); !reflect.DeepEqual(got, []Keyframe{first, third}) {
+		t.Errorf("Keyframes() = %+v", got)
+	}
+
+	tests := []struct {
+		at   time.Duration
+		want Keyframe
+	}{
+		{-time.Second, first},
+		{0, first},
+		{1500 * time.Millisecond, first},
+		{2 * time.Second, third},
+		{time.Hour, third},
+	}
+	for _, tt := range tests {
+		if got, ok := f.KeyframeAt(tt.at); !ok || got != tt.want {
+			t.Errorf("KeyframeAt(%s) = %+v, %v, want %+v", tt.at, got, ok, tt.want)
+		}
+	}
+
+	if _, ok := (&File{}).KeyframeAt(0); ok {
+		t.Error("KeyframeAt found a keyframe in a file without fragments")
+	}
+}
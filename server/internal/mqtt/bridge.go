@@ -0,0 +1,363 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/events"
+	"github.com/boytur/cctv-recording-center/server/internal/recorder"
+	"github.com/boytur/cctv-recording-center/server/internal/repository"
+	"github.com/boytur/cctv-recording-center/server/internal/usecase"
+)
+
+// motionHold is how long the motion sensor stays ON after a motion event.
+const motionHold = 30 * time.Second
+
+// publishTimeout bounds waiting for the broker to acknowledge a publish.
+const publishTimeout = 10 * time.Second
+
+// Config describes the broker and topic layout.
+type Config struct {
+	Broker          string
+	Username        string
+	Password        string
+	ClientID        string
+	TopicPrefix     string
+	DiscoveryPrefix string
+}
+
+// Enabled reports whether a broker is configured.
+func (c Config) Enabled() bool {
+	return c.Broker != ""
+}
+
+// ConfigFromEnv reads the MQTT settings:
+//
+//	MQTT_BROKER (e.g. tcp://192.168.1.10:1883), MQTT_USERNAME, MQTT_PASSWORD,
+//	MQTT_CLIENT_ID (default cctv-recording-center),
+//	MQTT_TOPIC_PREFIX (default cctv),
+//	MQTT_DISCOVERY_PREFIX (default homeassistant)
+func ConfigFromEnv() Config {
+	c := Config{
+		Broker:          os.Getenv("MQTT_BROKER"),
+		Username:        os.Getenv("MQTT_USERNAME"),
+		Password:        os.Getenv("MQTT_PASSWORD"),
+		ClientID:        os.Getenv("MQTT_CLIENT_ID"),
+		TopicPrefix:     strings.Trim(os.Getenv("MQTT_TOPIC_PREFIX"), "/"),
+		DiscoveryPrefix: strings.Trim(os.Getenv("MQTT_DISCOVERY_PREFIX"), "/"),
+	}
+	if c.ClientID == "" {
+		c.ClientID = "cctv-recording-center"
+	}
+	if c.TopicPrefix == "" {
+		c.TopicPrefix = "cctv"
+	}
+	if c.DiscoveryPrefix == "" {
+		c.DiscoveryPrefix = "homeassistant"
+	}
+	return c
+}
+
+// Capturer starts and stops manual recordings on command.
+type Capturer interface {
+	StartCapture(req usecase.CaptureRequest) (*domain.Capture, error)
+	StopCapture(cameraID string) error
+}
+
+// Bridge mirrors camera state and events to an MQTT broker and accepts
+// recording commands from it. Topics, under the configured prefix:
+//
+//	<prefix>/status                        online/offline (retained, LWT)
+//	<prefix>/camera/<id>/status            online/offline (retained)
+//	<prefix>/camera/<id>/recording         ON/OFF (retained)
+//	<prefix>/camera/<id>/motion            ON/OFF (retained)
+//	<prefix>/events/<type>                 every bus event as JSON
+//	<prefix>/camera/<id>/recording/set     command: ON/OFF
+//	<prefix>/camera/<id>/command           command: start_recording, stop_recording
+//	                                       or {"action": ..., "duration_seconds": n}
+//
+// Home Assistant discovery configs are published under the discovery prefix
+// so every camera appears as a device with online, recording and motion
+// binary sensors. Motion comes from the motion.detected events published for
+// POST /api/cameras/{id}/motion.
+type Bridge struct {
+	config   Config
+	cameras  repository.CameraRepository
+	captures Capturer
+	client   paho.Client
+
+	mu     sync.Mutex
+	motion map[string]*time.Timer
+
+	stopChan chan struct{}
+}
+
+// NewBridge creates a bridge for the given broker configuration.
+func NewBridge(cfg Config, cameras repository.CameraRepository, captures Capturer) *Bridge {
+	b := &Bridge{
+		config:   cfg,
+		cameras:  cameras,
+		captures: captures,
+		motion:   make(map[string]*time.Timer),
+		stopChan: make(chan struct{}),
+	}
+
+	opts := paho.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(cfg.ClientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(10*time.Second).
+		SetMaxReconnectInterval(time.Minute).
+		SetWill(b.availabilityTopic(), "offline", 1, true).
+		SetOnConnectHandler(b.onConnect).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			log.Printf("mqtt: connection lost: %v", err)
+		})
+	b.client = paho.NewClient(opts)
+	return b
+}
+
+// Start connects to the broker and begins publishing. Connecting is retried
+// in the background, so Start does not fail when the broker is down.
+func (b *Bridge) Start() {
+	b.client.Connect()
+	ch, cancel := events.Subscribe(256, nil)
+	go b.run(ch, cancel)
+	log.Printf("mqtt: bridging to %s with topic prefix %q", b.config.Broker, b.config.TopicPrefix)
+}
+
+// Stop marks the bridge offline and disconnects.
+func (b *Bridge) Stop() {
+	close(b.stopChan)
+	b.mu.Lock()
+	for _, t := range b.motion {
+		t.Stop()
+	}
+	b.mu.Unlock()
+	if b.client.IsConnected() {
+		b.publish(b.availabilityTopic(), true, "offline")
+	}
+	b.client.Disconnect(250)
+}
+
+func (b *Bridge) run(ch <-chan domain.Event, cancel func()) {
+	defer cancel()
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			b.handle(e)
+		case <-b.stopChan:
+			return
+		}
+	}
+}
+
+// onConnect runs on every (re)connect: the broker may have lost retained
+// state, so everything is published again before listening for commands.
+func (b *Bridge) onConnect(c paho.Client) {
+	log.Printf("mqtt: connected to %s", b.config.Broker)
+	b.publish(b.availabilityTopic(), true, "online")
+
+	cams, err := b.cameras.List()
+	if err != nil {
+		log.Printf("mqtt: failed to list cameras: %v", err)
+	}
+	for _, cam := range cams {
+		b.publishDiscovery(cam)
+		b.publishState(cam)
+	}
+
+	filters := map[string]byte{
+		b.topic("camera", "+", "recording", "set"): 1,
+		b.topic("camera", "+", "command"):          1,
+	}
+	if t := c.SubscribeMultiple(filters, b.onCommand); t.WaitTimeout(publishTimeout) && t.Error() != nil {
+		log.Printf("mqtt: failed to subscribe to command topics: %v", t.Error())
+	}
+}
+
+func (b *Bridge) handle(e domain.Event) {
+	if !b.client.IsConnected() {
+		return
+	}
+	if data, err := json.Marshal(e); err == nil {
+		b.publish(b.topic("events", e.Type), false, data)
+	}
+	if e.CameraID == "" {
+		return
+	}
+
+	switch e.Type {
+	case events.CameraOnline, events.CameraOffline:
+		b.publish(b.topic("camera", e.CameraID, "status"), true, strings.TrimPrefix(e.Type, "camera."))
+	case events.CameraCreated, events.CameraUpdated:
+		if cam, err := b.cameras.GetByID(e.CameraID); err == nil {
+			b.publishDiscovery(cam)
+			b.publishState(cam)
+		}
+	case events.CameraDeleted:
+		b.clearCamera(e.CameraID)
+	case events.RecordingStarted, events.RecordingStopped, events.RecordingFailed,
+		events.CaptureStarted, events.CaptureStopped, events.CaptureFailed:
+		b.publish(b.topic("camera", e.CameraID, "recording"), true, onOff(isRecording(e.CameraID)))
+	case events.MotionDetected:
+		b.motionDetected(e.CameraID)
+	}
+}
+
+// motionDetected turns the motion sensor on and schedules it off again once
+// no further motion is reported for motionHold.
+func (b *Bridge) motionDetected(cameraID string) {
+	b.publish(b.topic("camera", cameraID, "motion"), true, "ON")
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t, ok := b.motion[cameraID]; ok {
+		t.Reset(motionHold)
+		return
+	}
+	b.motion[cameraID] = time.AfterFunc(motionHold, func() {
+		b.mu.Lock()
+		delete(b.motion, cameraID)
+		b.mu.Unlock()
+		b.publish(b.topic("camera", cameraID, "motion"), true, "OFF")
+	})
+}
+
+func (b *Bridge) publishState(cam *domain.Camera) {
+	status := "offline"
+	if cam.Status == "online" {
+		status = "online"
+	}
+	b.publish(b.topic("camera", cam.ID, "status"), true, status)
+	b.publish(b.topic("camera", cam.ID, "recording"), true, onOff(isRecording(cam.ID)))
+
+	b.mu.Lock()
+	_, moving := b.motion[cam.ID]
+	b.mu.Unlock()
+	b.publish(b.topic("camera", cam.ID, "motion"), true, onOff(moving))
+}
+
+// clearCamera removes a deleted camera's retained state and discovery
+// configs so it disappears from Home Assistant.
+func (b *Bridge) clearCamera(cameraID string) {
+	b.mu.Lock()
+	if t, ok := b.motion[cameraID]; ok {
+		t.Stop()
+		delete(b.motion, cameraID)
+	}
+	b.mu.Unlock()
+
+	for _, s := range sensors {
+		b.publish(b.discoveryTopic(cameraID, s.key), true, "")
+	}
+	for _, s := range []string{"status", "recording", "motion"} {
+		b.publish(b.topic("camera", cameraID, s), true, "")
+	}
+}
+
+// onCommand handles messages on the command topics.
+func (b *Bridge) onCommand(_ paho.Client, msg paho.Message) {
+	// <prefix>/camera/<id>/recording/set or <prefix>/camera/<id>/command
+	rest := strings.TrimPrefix(msg.Topic(), b.topic("camera")+"/")
+	cameraID, _, _ := strings.Cut(rest, "/")
+	if cameraID == "" {
+		return
+	}
+
+	action, duration, err := parseCommand(msg.Payload())
+	if err != nil {
+		log.Printf("mqtt: ignoring command on %s: %v", msg.Topic(), err)
+		return
+	}
+
+	switch action {
+	case "start_recording":
+		_, err = b.captures.StartCapture(usecase.CaptureRequest{
+			CameraID: cameraID,
+			Operator: "mqtt",
+			Duration: duration,
+		})
+	case "stop_recording":
+		err = b.captures.StopCapture(cameraID)
+	}
+	if err != nil {
+		log.Printf("mqtt: %s for camera %s failed: %v", action, cameraID, err)
+		// republish the actual state so a switch bounces back
+		b.publish(b.topic("camera", cameraID, "recording"), true, onOff(isRecording(cameraID)))
+		return
+	}
+	log.Printf("mqtt: %s for camera %s", action, cameraID)
+}
+
+// parseCommand accepts ON/OFF, start_recording/stop_recording, or a JSON
+// object {"action": "start_recording", "duration_seconds": 60}.
+func parseCommand(payload []byte) (string, time.Duration, error) {
+	s := strings.TrimSpace(string(payload))
+	if strings.HasPrefix(s, "{") {
+		var cmd struct {
+			Action          string `json:"action"`
+			DurationSeconds int    `json:"duration_seconds"`
+		}
+		if err := json.Unmarshal(payload, &cmd); err != nil {
+			return "", 0, err
+		}
+		if cmd.DurationSeconds < 0 {
+			return "", 0, fmt.Errorf("invalid duration %d", cmd.DurationSeconds)
+		}
+		s = cmd.Action
+		action, _, err := parseCommand([]byte(s))
+		return action, time.Duration(cmd.DurationSeconds) * time.Second, err
+	}
+	switch strings.ToLower(s) {
+	case "on", "start", "start_recording":
+		return "start_recording", 0, nil
+	case "off", "stop", "stop_recording":
+		return "stop_recording", 0, nil
+	}
+	return "", 0, fmt.Errorf("unknown command %q", s)
+}
+
+// publish sends a payload and logs, rather than returns, failures: the broker
+// state is rebuilt on the next reconnect anyway.
+func (b *Bridge) publish(topic string, retained bool, payload interface{}) {
+	t := b.client.Publish(topic, 1, retained, payload)
+	go func() {
+		if t.WaitTimeout(publishTimeout) && t.Error() != nil {
+			log.Printf("mqtt: failed to publish to %s: %v", topic, t.Error())
+		}
+	}()
+}
+
+func (b *Bridge) topic(parts ...string) string {
+	return b.config.TopicPrefix + "/" + strings.Join(parts, "/")
+}
+
+func (b *Bridge) availabilityTopic() string {
+	return b.topic("status")
+}
+
+func isRecording(cameraID string) bool {
+	return recorder.IsRecording(cameraID) || recorder.IsCapturing(cameraID)
+}
+
+func onOff(v bool) string {
+	if v {
+		return "ON"
+	}
+	return "OFF"
+}
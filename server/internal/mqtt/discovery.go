@@ -0,0 +1,90 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+)
+
+// sensor is a Home Assistant binary_sensor exposed for every camera.
+type sensor struct {
+	key         string
+	name        string
+	deviceClass string
+	payloadOn   string
+	payloadOff  string
+	icon        string
+}
+
+var sensors = []sensor{
+	{key: "online", name: "Online", deviceClass: "connectivity", payloadOn: "online", payloadOff: "offline"},
+	{key: "recording", name: "Recording", payloadOn: "ON", payloadOff: "OFF", icon: "mdi:record-rec"},
+	{key: "motion", name: "Motion", deviceClass: "motion", payloadOn: "ON", payloadOff: "OFF"},
+}
+
+// stateKey maps a sensor to the per-camera topic holding its state.
+func (s sensor) stateKey() string {
+	if s.key == "online" {
+		return "status"
+	}
+	return s.key
+}
+
+// discoveryTopic is <discovery prefix>/binary_sensor/<node>/<sensor>/config.
+func (b *Bridge) discoveryTopic(cameraID, key string) string {
+	return b.config.DiscoveryPrefix + "/binary_sensor/" + b.nodeID(cameraID) + "/" + key + "/config"
+}
+
+// nodeID is the discovery node for a camera. Home Assistant only allows
+// [a-zA-Z0-9_-] there.
+func (b *Bridge) nodeID(cameraID string) string {
+	id := []byte(b.config.TopicPrefix + "_" + cameraID)
+	for i, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			id[i] = '_'
+		}
+	}
+	return string(id)
+}
+
+// publishDiscovery publishes the retained discovery configs for a camera so
+// Home Assistant groups its sensors under one device.
+func (b *Bridge) publishDiscovery(cam *domain.Camera) {
+	device := map[string]interface{}{
+		"identifiers":  []string{b.nodeID(cam.ID)},
+		"name":         cam.Name,
+		"manufacturer": "CCTV Recording Center",
+		"model":        "IP camera",
+	}
+	if cam.Location != "" {
+		device["suggested_area"] = cam.Location
+	}
+
+	for _, s := range sensors {
+		cfg := map[string]interface{}{
+			"name":                  s.name,
+			"unique_id":             b.nodeID(cam.ID) + "_" + s.key,
+			"object_id":             b.nodeID(cam.ID) + "_" + s.key,
+			"state_topic":           b.topic("camera", cam.ID, s.stateKey()),
+			"payload_on":            s.payloadOn,
+			"payload_off":           s.payloadOff,
+			"availability_topic":    b.availabilityTopic(),
+			"payload_available":     "online",
+			"payload_not_available": "offline",
+			"device":                device,
+		}
+		if s.deviceClass != "" {
+			cfg["device_class"] = s.deviceClass
+		}
+		if s.icon != "" {
+			cfg["icon"] = s.icon
+		}
+		data, err := json.Marshal(cfg)
+		if err != nil {
+			log.Printf("mqtt: failed to encode discovery for camera %s: %v", cam.ID, err)
+			continue
+		}
+		b.publish(b.discoveryTopic(cam.ID, s.key), true, data)
+	}
+}
@@ -0,0 +1,450 @@
+package onvif
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnauthorized is returned when the device rejects the credentials.
+	ErrUnauthorized = errors.New("onvif: not authorized")
+	// ErrNoService is returned when the device does not offer the media or
+	// PTZ service a call needs.
+	ErrNoService = errors.New("onvif: service not offered")
+)
+
+// requestTimeout bounds a single SOAP request when ctx has no deadline.
+const requestTimeout = 10 * time.Second
+
+// DeviceInformation is what GetDeviceInformation reports.
+type DeviceInformation struct {
+	Manufacturer    string `xml:"Manufacturer"`
+	Model           string `xml:"Model"`
+	FirmwareVersion string `xml:"FirmwareVersion"`
+	SerialNumber    string `xml:"SerialNumber"`
+	HardwareID      string `xml:"HardwareId"`
+}
+
+// Capabilities holds the service addresses GetCapabilities reports. Empty
+// means the device does not offer the service.
+type Capabilities struct {
+	Media string
+	PTZ   string
+}
+
+// Profile is a media profile and the video encoder it uses.
+type Profile struct {
+	Token       string
+	Name        string
+	Encoding    string
+	Width       int
+	Height      int
+	FrameRate   int
+	BitrateKbps int
+	// PTZ reports whether the profile has a PTZ configuration.
+	PTZ bool
+}
+
+// Stream is a profile together with its RTSP URI.
+type Stream struct {
+	ProfileToken string `json:"profile_token"`
+	Name         string `json:"name"`
+	// Kind is "main" for the highest resolution stream and "sub" for the
+	// others.
+	Kind        string `json:"kind"`
+	Encoding    string `json:"encoding,omitempty"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+	FrameRate   int    `json:"frame_rate,omitempty"`
+	BitrateKbps int    `json:"bitrate_kbps,omitempty"`
+	URI         string `json:"uri"`
+}
+
+// Client calls the ONVIF device, media and PTZ services of one device. It is
+// safe for concurrent use.
+type Client struct {
+	// XAddr is the device service URL.
+	XAddr    string
+	Username string
+	Password string
+	HTTP     *http.Client
+
+	mu        sync.Mutex
+	offset    time.Duration
+	synced    bool
+	mediaAddr string
+	ptzAddr   string
+}
+
+// NewClient creates a client for the device at addr, which is a device
+// service URL or just a host with an optional port.
+func NewClient(addr, username, password string) (*Client, error) {
+	xaddr, err := DeviceServiceURL(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{XAddr: xaddr, Username: username, Password: password, HTTP: http.DefaultClient}, nil
+}
+
+// DeviceServiceURL turns a host, host:port or URL into the device service
+// URL, http://host/onvif/device_service unless a path is given.
+func DeviceServiceURL(addr string) (string, error) {
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		return "", errors.New("onvif: empty device address")
+	}
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	u, err := url.Parse(addr)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("onvif: invalid device address %q", addr)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/onvif/device_service"
+	}
+	return u.String(), nil
+}
+
+// DeviceInformation calls GetDeviceInformation.
+func (c *Client) DeviceInformation(ctx context.Context) (*DeviceInformation, error) {
+	var resp struct {
+		Info DeviceInformation `xml:"Body>GetDeviceInformationResponse"`
+	}
+	if err := c.call(ctx, c.XAddr, `<GetDeviceInformation xmlns="http://www.onvif.org/ver10/device/wsdl"/>`, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Info, nil
+}
+
+// Capabilities calls GetCapabilities and remembers the service addresses
+// for later media and PTZ calls.
+func (c *Client) Capabilities(ctx context.Context) (*Capabilities, error) {
+	var resp struct {
+		Media string `xml:"Body>GetCapabilitiesResponse>Capabilities>Media>XAddr"`
+		PTZ   string `xml:"Body>GetCapabilitiesResponse>Capabilities>PTZ>XAddr"`
+	}
+	body := `<GetCapabilities xmlns="http://www.onvif.org/ver10/device/wsdl"><Category>All</Category></GetCapabilities>`
+	if err := c.call(ctx, c.XAddr, body, &resp); err != nil {
+		return nil, err
+	}
+	caps := &Capabilities{Media: c.sameHost(resp.Media), PTZ: c.sameHost(resp.PTZ)}
+	c.mu.Lock()
+	c.mediaAddr, c.ptzAddr = caps.Media, caps.PTZ
+	c.mu.Unlock()
+	return caps, nil
+}
+
+// Profiles calls GetProfiles on the media service.
+func (c *Client) Profiles(ctx context.Context) ([]Profile, error) {
+	media, err := c.media(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Profiles []struct {
+			Token   string `xml:"token,attr"`
+			Name    string `xml:"Name"`
+			Encoder struct {
+				Encoding   string `xml:"Encoding"`
+				Resolution struct {
+					Width  int `xml:"Width"`
+					Height int `xml:"Height"`
+				} `xml:"Resolution"`
+				RateControl struct {
+					FrameRateLimit int `xml:"FrameRateLimit"`
+					BitrateLimit   int `xml:"BitrateLimit"`
+				} `xml:"RateControl"`
+			} `xml:"VideoEncoderConfiguration"`
+			PTZ *struct{} `xml:"PTZConfiguration"`
+		} `xml:"Body>GetProfilesResponse>Profiles"`
+	}
+	if err := c.call(ctx, media, `<GetProfiles xmlns="http://www.onvif.org/ver10/media/wsdl"/>`, &resp); err != nil {
+		return nil, err
+	}
+	profiles := make([]Profile, 0, len(resp.Profiles))
+	for _, p := range resp.Profiles {
+		profiles = append(profiles, Profile{
+			Token:       p.Token,
+			Name:        p.Name,
+			Encoding:    p.Encoder.Encoding,
+			Width:       p.Encoder.Resolution.Width,
+			Height:      p.Encoder.Resolution.Height,
+			FrameRate:   p.Encoder.RateControl.FrameRateLimit,
+			BitrateKbps: p.Encoder.RateControl.BitrateLimit,
+			PTZ:         p.PTZ != nil,
+		})
+	}
+	return profiles, nil
+}
+
+// StreamURI calls GetStreamUri for RTSP unicast on a profile.
+func (c *Client) StreamURI(ctx context.Context, profileToken string) (string, error) {
+	media, err := c.media(ctx)
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		URI string `xml:"Body>GetStreamUriResponse>MediaUri>Uri"`
+	}
+	body := `<GetStreamUri xmlns="http://www.onvif.org/ver10/media/wsdl">` +
+		`<StreamSetup><Stream xmlns="http://www.onvif.org/ver10/schema">RTP-Unicast</Stream>` +
+		`<Transport xmlns="http://www.onvif.org/ver10/schema"><Protocol>RTSP</Protocol></Transport></StreamSetup>` +
+		`<ProfileToken>` + html.EscapeString(profileToken) + `</ProfileToken></GetStreamUri>`
+	if err := c.call(ctx, media, body, &resp); err != nil {
+		return "", err
+	}
+	uri := strings.TrimSpace(resp.URI)
+	if uri == "" {
+		return "", fmt.Errorf("onvif: no stream URI for profile %q", profileToken)
+	}
+	return uri, nil
+}
+
+// Streams lists every profile with its RTSP URI, main stream first.
+func (c *Client) Streams(ctx context.Context) ([]Stream, error) {
+	profiles, err := c.Profiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	streams := make([]Stream, 0, len(profiles))
+	for _, p := range profiles {
+		uri, err := c.StreamURI(ctx, p.Token)
+		if err != nil {
+			return nil, err
+		}
+		streams = append(streams, Stream{
+			ProfileToken: p.Token,
+			Name:         p.Name,
+			Encoding:     p.Encoding,
+			Width:        p.Width,
+			Height:       p.Height,
+			FrameRate:    p.FrameRate,
+			BitrateKbps:  p.BitrateKbps,
+			URI:          uri,
+		})
+	}
+	sort.SliceStable(streams, func(i, j int) bool {
+		return streams[i].Width*streams[i].Height > streams[j].Width*streams[j].Height
+	})
+	for i := range streams {
+		streams[i].Kind = "sub"
+		if i == 0 {
+			streams[i].Kind = "main"
+		}
+	}
+	return streams, nil
+}
+
+// media returns the media service address, asking for the capabilities
+// the first time.
+func (c *Client) media(ctx context.Context) (string, error) {
+	return c.service(ctx, "media", func() string { return c.mediaAddr }, func(caps *Capabilities) string { return caps.Media })
+}
+
+// ptz returns the PTZ service address, asking for the capabilities the
+// first time.
+func (c *Client) ptz(ctx context.Context) (string, error) {
+	return c.service(ctx, "PTZ", func() string { return c.ptzAddr }, func(caps *Capabilities) string { return caps.PTZ })
+}
+
+func (c *Client) service(ctx context.Context, name string, cached func() string, fromCaps func(*Capabilities) string) (string, error) {
+	c.mu.Lock()
+	addr := cached()
+	c.mu.Unlock()
+	if addr != "" {
+		return addr, nil
+	}
+	caps, err := c.Capabilities(ctx)
+	if err != nil {
+		return "", err
+	}
+	if addr = fromCaps(caps); addr == "" {
+		return "", fmt.Errorf("%w: %s", ErrNoService, name)
+	}
+	return addr, nil
+}
+
+// sameHost rewrites a service address to the host the client talks to.
+// Devices behind NAT or with several interfaces often advertise an address
+// the server can't reach.
+func (c *Client) sameHost(addr string) string {
+	if addr == "" {
+		return ""
+	}
+	u, err := url.Parse(strings.TrimSpace(addr))
+	if err != nil {
+		return addr
+	}
+	base, err := url.Parse(c.XAddr)
+	if err != nil {
+		return addr
+	}
+	u.Scheme, u.Host = base.Scheme, base.Host
+	return u.String()
+}
+
+// syncClock reads the device clock once so the WS-Security timestamp is
+// within what the device accepts, even if its clock is off.
+func (c *Client) syncClock(ctx context.Context) {
+	c.mu.Lock()
+	synced := c.synced
+	c.mu.Unlock()
+	if synced {
+		return
+	}
+	var resp struct {
+		UTC struct {
+			Year   int `xml:"Date>Year"`
+			Month  int `xml:"Date>Month"`
+			Day    int `xml:"Date>Day"`
+			Hour   int `xml:"Time>Hour"`
+			Minute int `xml:"Time>Minute"`
+			Second int `xml:"Time>Second"`
+		} `xml:"Body>GetSystemDateAndTimeResponse>SystemDateAndTime>UTCDateTime"`
+	}
+	var offset time.Duration
+	err := c.post(ctx, c.XAddr, envelope("", `<GetSystemDateAndTime xmlns="http://www.onvif.org/ver10/device/wsdl"/>`), &resp)
+	if err == nil && resp.UTC.Year > 0 {
+		t := time.Date(resp.UTC.Year, time.Month(resp.UTC.Month), resp.UTC.Day, resp.UTC.Hour, resp.UTC.Minute, resp.UTC.Second, 0, time.UTC)
+		offset = time.Until(t)
+	}
+	c.mu.Lock()
+	c.offset, c.synced = offset, true
+	c.mu.Unlock()
+}
+
+// call sends an authenticated SOAP request and decodes the response
+// envelope into resp.
+func (c *Client) call(ctx context.Context, addr, body string, resp interface{}) error {
+	header := ""
+	if c.Username != "" {
+		c.syncClock(ctx)
+		c.mu.Lock()
+		offset := c.offset
+		c.mu.Unlock()
+		header = usernameToken(c.Username, c.Password, time.Now().Add(offset))
+	}
+	return c.post(ctx, addr, envelope(header, body), resp)
+}
+
+func (c *Client) post(ctx context.Context, addr string, env []byte, resp interface{}) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, requestTimeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, addr, bytes.NewReader(env))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/soap+xml; charset=utf-8")
+	httpClient := c.HTTP
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(res.Body, 4<<20))
+	if err != nil {
+		return err
+	}
+	if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
+		return ErrUnauthorized
+	}
+	if fault := parseFault(data); fault != nil {
+		return fault
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("onvif: %s", res.Status)
+	}
+	if err := xml.Unmarshal(data, resp); err != nil {
+		return fmt.Errorf("onvif: invalid response: %w", err)
+	}
+	return nil
+}
+
+func envelope(header, body string) []byte {
+	return []byte(`<?xml version="1.0" encoding="UTF-8"?>` +
+		`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope">` +
+		`<s:Header>` + header + `</s:Header>` +
+		`<s:Body>` + body + `</s:Body></s:Envelope>`)
+}
+
+// usernameToken builds a WS-Security UsernameToken header with a password
+// digest: Base64(SHA1(nonce + created + password)).
+func usernameToken(username, password string, now time.Time) string {
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	created := now.UTC().Format("2006-01-02T15:04:05.000Z")
+	h := sha1.New()
+	h.Write(nonce)
+	h.Write([]byte(created))
+	h.Write([]byte(password))
+	digest := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+	return `<Security s:mustUnderstand="1" xmlns="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd">` +
+		`<UsernameToken>` +
+		`<Username>` + html.EscapeString(username) + `</Username>` +
+		`<Password Type="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordDigest">` + digest + `</Password>` +
+		`<Nonce EncodingType="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary">` + base64.StdEncoding.EncodeToString(nonce) + `</Nonce>` +
+		`<Created xmlns="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd">` + created + `</Created>` +
+		`</UsernameToken></Security>`
+}
+
+// Fault is a SOAP fault returned by the device.
+type Fault struct {
+	Code    string
+	Subcode string
+	Reason  string
+}
+
+func (f *Fault) Error() string {
+	msg := "onvif: " + f.Code
+	if f.Subcode != "" {
+		msg += "/" + f.Subcode
+	}
+	if f.Reason != "" {
+		msg += ": " + f.Reason
+	}
+	return msg
+}
+
+// Unwrap lets errors.Is match ErrUnauthorized for authentication faults.
+func (f *Fault) Unwrap() error {
+	if strings.Contains(f.Subcode, "NotAuthorized") || strings.Contains(f.Subcode, "FailedAuthentication") {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+func parseFault(data []byte) *Fault {
+	var env struct {
+		Fault *struct {
+			Code    string `xml:"Code>Value"`
+			Subcode string `xml:"Code>Subcode>Value"`
+			Reason  string `xml:"Reason>Text"`
+		} `xml:"Body>Fault"`
+	}
+	if err := xml.Unmarshal(data, &env); err != nil || env.Fault == nil {
+		return nil
+	}
+	return &Fault{Code: strings.TrimSpace(env.Fault.Code), Subcode: strings.TrimSpace(env.Fault.Subcode), Reason: strings.TrimSpace(env.Fault.Reason)}
+}
@@ -0,0 +1,205 @@
+// Package onvif talks to ONVIF cameras: WS-Discovery to find them on the
+// local network, the device and media services to read what they are and
+// what they stream, and the PTZ service to point them.
+package onvif
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultDiscoveryAddr is the WS-Discovery multicast group and port.
+const DefaultDiscoveryAddr = "239.255.255.250:3702"
+
+// resendAfter is when the probe is sent a second time, as UDP multicast is
+// easily lost.
+const resendAfter = 500 * time.Millisecond
+
+// Device is an ONVIF device that answered a discovery probe.
+type Device struct {
+	// EndpointReference is the device's stable WS-Addressing identifier,
+	// usually a urn:uuid.
+	EndpointReference string `json:"endpoint_reference"`
+	// Address is the host the device answered from or advertises.
+	Address string `json:"address"`
+	// XAddrs are the device service URLs.
+	XAddrs       []string `json:"xaddrs"`
+	Types        []string `json:"types"`
+	Scopes       []string `json:"scopes"`
+	Name         string   `json:"name,omitempty"`
+	Manufacturer string   `json:"manufacturer,omitempty"`
+	Model        string   `json:"model,omitempty"`
+	Location     string   `json:"location,omitempty"`
+}
+
+// Discoverer sends WS-Discovery probes and collects the answers.
+type Discoverer struct {
+	// Addr is where probes are sent, DefaultDiscoveryAddr unless a single
+	// host or a stand-in responder is to be probed.
+	Addr string
+}
+
+// DiscovererFromEnv returns a discoverer probing ONVIF_DISCOVERY_ADDR, or the
+// multicast group if unset.
+func DiscovererFromEnv() *Discoverer {
+	return &Discoverer{Addr: os.Getenv("ONVIF_DISCOVERY_ADDR")}
+}
+
+// Discover probes for network video transmitters and returns the devices
+// that answered before ctx is done, one per endpoint reference.
+func (d *Discoverer) Discover(ctx context.Context) ([]Device, error) {
+	addr := d.Addr
+	if addr == "" {
+		addr = DefaultDiscoveryAddr
+	}
+	dst, err := net.ResolveUDPAddr("udp4", addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid discovery address %q: %w", addr, err)
+	}
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	messageID := "uuid:" + uuid.New().String()
+	probe := probeMessage(messageID)
+	if _, err := conn.WriteToUDP(probe, dst); err != nil {
+		return nil, fmt.Errorf("send probe: %w", err)
+	}
+	resend := time.AfterFunc(resendAfter, func() { conn.WriteToUDP(probe, dst) })
+	defer resend.Stop()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(3 * time.Second)
+	}
+	conn.SetReadDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	found := make(map[string]*Device)
+	buf := make([]byte, 64*1024)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			// deadline reached: done collecting
+			break
+		}
+		for _, dev := range parseProbeMatches(buf[:n], messageID, from) {
+			key := dev.EndpointReference
+			if key == "" {
+				key = dev.Address
+			}
+			if _, seen := found[key]; !seen {
+				found[key] = &dev
+			}
+		}
+	}
+
+	devices := make([]Device, 0, len(found))
+	for _, dev := range found {
+		devices = append(devices, *dev)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Address < devices[j].Address })
+	return devices, nil
+}
+
+func probeMessage(messageID string) []byte {
+	return []byte(`<?xml version="1.0" encoding="UTF-8"?>` +
+		`<e:Envelope xmlns:e="http://www.w3.org/2003/05/soap-envelope"` +
+		` xmlns:w="http://schemas.xmlsoap.org/ws/2004/08/addressing"` +
+		` xmlns:d="http://schemas.xmlsoap.org/ws/2005/04/discovery"` +
+		` xmlns:dn="http://www.onvif.org/ver10/network/wsdl">` +
+		`<e:Header>` +
+		`<w:MessageID>` + messageID + `</w:MessageID>` +
+		`<w:To e:mustUnderstand="true">urn:schemas-xmlsoap-org:ws:2005:04:discovery</w:To>` +
+		`<w:Action e:mustUnderstand="true">http://schemas.xmlsoap.org/ws/2005/04/discovery/Probe</w:Action>` +
+		`</e:Header>` +
+		`<e:Body><d:Probe><d:Types>dn:NetworkVideoTransmitter</d:Types></d:Probe></e:Body>` +
+		`</e:Envelope>`)
+}
+
+// probeMatches is the part of a ProbeMatches SOAP envelope we use. Elements
+// are matched by local name as devices disagree on namespace prefixes.
+type probeMatches struct {
+	RelatesTo string `xml:"Header>RelatesTo"`
+	Matches   []struct {
+		Address string `xml:"EndpointReference>Address"`
+		Types   string `xml:"Types"`
+		Scopes  string `xml:"Scopes"`
+		XAddrs  string `xml:"XAddrs"`
+	} `xml:"Body>ProbeMatches>ProbeMatch"`
+}
+
+// parseProbeMatches reads the devices from a ProbeMatches message. Answers
+// to someone else's probe and malformed packets yield nothing.
+func parseProbeMatches(data []byte, messageID string, from *net.UDPAddr) []Device {
+	var env probeMatches
+	if err := xml.Unmarshal(data, &env); err != nil {
+		return nil
+	}
+	if env.RelatesTo != "" && strings.TrimSpace(env.RelatesTo) != messageID {
+		return nil
+	}
+	devices := make([]Device, 0, len(env.Matches))
+	for _, m := range env.Matches {
+		dev := Device{
+			EndpointReference: strings.TrimSpace(m.Address),
+			XAddrs:            strings.Fields(m.XAddrs),
+			Types:             strings.Fields(m.Types),
+			Scopes:            strings.Fields(m.Scopes),
+		}
+		for _, x := range dev.XAddrs {
+			if u, err := url.Parse(x); err == nil && u.Hostname() != "" {
+				dev.Address = u.Hostname()
+				break
+			}
+		}
+		if dev.Address == "" && from != nil {
+			dev.Address = from.IP.String()
+		}
+		applyScopes(&dev)
+		devices = append(devices, dev)
+	}
+	return devices
+}
+
+// applyScopes fills in the descriptive fields from the onvif:// scopes,
+// e.g. onvif://www.onvif.org/hardware/DS-2CD2143G2-I.
+func applyScopes(dev *Device) {
+	for _, s := range dev.Scopes {
+		rest, ok := strings.CutPrefix(s, "onvif://www.onvif.org/")
+		if !ok {
+			continue
+		}
+		key, value, ok := strings.Cut(rest, "/")
+		if !ok {
+			continue
+		}
+		if v, err := url.PathUnescape(value); err == nil {
+			value = v
+		}
+		switch strings.ToLower(key) {
+		case "name":
+			dev.Name = value
+		case "hardware":
+			dev.Model = value
+		case "location":
+			if dev.Location == "" {
+				dev.Location = value
+			}
+		case "manufacturer", "mfr":
+			dev.Manufacturer = value
+		}
+	}
+}
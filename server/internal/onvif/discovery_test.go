@@ -0,0 +1,197 @@
+package onvif
+
+import (
+	"context"
+	"encoding/xml"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// probeMatchesEnvelope answers the probe relatesTo with one ProbeMatch per
+// match, each given as endpoint reference, XAddrs and scopes.
+func probeMatchesEnvelope(relatesTo string, matches ...[3]string) []byte {
+	body := ""
+	for _, m := range matches {
+		body += `<d:ProbeMatch>` +
+			`<w:EndpointReference><w:Address>` + m[0] + `</w:Address></w:EndpointReference>` +
+			`<d:Types>dn:NetworkVideoTransmitter</d:Types>` +
+			`<d:Scopes>` + m[2] + `</d:Scopes>` +
+			`<d:XAddrs>` + m[1] + `</d:XAddrs>` +
+			`<d:MetadataVersion>1</d:MetadataVersion>` +
+			`</d:ProbeMatch>`
+	}
+	return []byte(`<?xml version="1.0" encoding="UTF-8"?>` +
+		`<SOAP-ENV:Envelope xmlns:SOAP-ENV="http://www.w3.org/2003/05/soap-envelope"` +
+		` xmlns:w="http://schemas.xmlsoap.org/ws/2004/08/addressing"` +
+		` xmlns:d="http://schemas.xmlsoap.org/ws/2005/04/discovery"` +
+		` xmlns:dn="http://www.onvif.org/ver10/network/wsdl">` +
+		`<SOAP-ENV:Header>` +
+		`<w:MessageID>uuid:reply</w:MessageID>` +
+		`<w:RelatesTo>` + relatesTo + `</w:RelatesTo>` +
+		`</SOAP-ENV:Header>` +
+		`<SOAP-ENV:Body><d:ProbeMatches>` + body + `</d:ProbeMatches></SOAP-ENV:Body>` +
+		`</SOAP-ENV:Envelope>`)
+}
+
+// startResponder runs a stand-in for the devices on the LAN: every probe it
+// receives is answered with the replies built from the probe's MessageID.
+func startResponder(t *testing.T, replies func(messageID string) [][]byte) string {
+	t.Helper()
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 64*1024)
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			var probe struct {
+				MessageID string `xml:"Header>MessageID"`
+			}
+			if err := xml.Unmarshal(buf[:n], &probe); err != nil {
+				continue
+			}
+			for _, r := range replies(probe.MessageID) {
+				conn.WriteToUDP(r, from)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestDiscover(t *testing.T) {
+	const scopes = "onvif://www.onvif.org/type/video_encoder" +
+		" onvif://www.onvif.org/name/Front%20Door" +
+		" onvif://www.onvif.org/hardware/DS-2CD2143G2-I" +
+		" onvif://www.onvif.org/location/city/Bangkok" +
+		" onvif://www.onvif.org/mfr/Hikvision"
+	addr := startResponder(t, func(messageID string) [][]byte {
+		return [][]byte{
+			// an answer to another client's probe
+			probeMatchesEnvelope("uuid:someone-else", [3]string{"urn:uuid:other", "http://10.0.0.99/onvif/device_service", ""}),
+			probeMatchesEnvelope(messageID, [3]string{"urn:uuid:front", "http://192.168.1.10/onvif/device_service", scopes}),
+			// the same device again, as after the resent probe
+			probeMatchesEnvelope(messageID, [3]string{"urn:uuid:front", "http://192.168.1.10/onvif/device_service", scopes}),
+			// no usable XAddrs: the sender's address is used
+			probeMatchesEnvelope(messageID, [3]string{"urn:uuid:back", "", ""}),
+			[]byte("not xml"),
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	devices, err := (&Discoverer{Addr: addr}).Discover(ctx)
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+
+	want := []Device{
+		{
+			EndpointReference: "urn:uuid:back",
+			Address:           "127.0.0.1",
+			XAddrs:            []string{},
+			Types:             []string{"dn:NetworkVideoTransmitter"},
+			Scopes:            []string{},
+		},
+		{
+			EndpointReference: "urn:uuid:front",
+			Address:           "192.168.1.10",
+			XAddrs:            []string{"http://192.168.1.10/onvif/device_service"},
+			Types:             []string{"dn:NetworkVideoTransmitter"},
+			Scopes: []string{
+				"onvif://www.onvif.org/type/video_encoder",
+				"onvif://www.onvif.org/name/Front%20Door",
+				"onvif://www.onvif.org/hardware/DS-2CD2143G2-I",
+				"onvif://www.onvif.org/location/city/Bangkok",
+				"onvif://www.onvif.org/mfr/Hikvision",
+			},
+			Name:         "Front Door",
+			Manufacturer: "Hikvision",
+			Model:        "DS-2CD2143G2-I",
+			Location:     "city/Bangkok",
+		},
+	}
+	if !reflect.DeepEqual(devices, want) {
+		t.Errorf("Discover() =\n%+v\nwant\n%+v", devices, want)
+	}
+}
+
+func TestParseProbeMatches(t *testing.T) {
+	from := &net.UDPAddr{IP: net.IPv4(10, 1, 2, 3), Port: 3702}
+	tests := []struct {
+		name    string
+		data    []byte
+		wantEPR []string
+	}{
+		{"matching RelatesTo", probeMatchesEnvelope("uuid:probe", [3]string{"urn:uuid:a", "http://10.0.0.1/", ""}), []string{"urn:uuid:a"}},
+		{"other RelatesTo", probeMatchesEnvelope("uuid:other", [3]string{"urn:uuid:a", "http://10.0.0.1/", ""}), nil},
+		{"no RelatesTo", []byte(`<Envelope><Body><ProbeMatches><ProbeMatch><EndpointReference><Address>urn:uuid:b</Address></EndpointReference></ProbeMatch></ProbeMatches></Body></Envelope>`), []string{"urn:uuid:b"}},
+		{"several matches", probeMatchesEnvelope("uuid:probe", [3]string{"urn:uuid:a", "", ""}, [3]string{"urn:uuid:b", "", ""}), []string{"urn:uuid:a", "urn:uuid:b"}},
+		{"malformed", []byte("<Envelope><Body>"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, d := range parseProbeMatches(tt.data, "uuid:probe", from) {
+				got = append(got, d.EndpointReference)
+			}
+			if !reflect.DeepEqual(got, tt.wantEPR) {
+				t.Errorf("endpoint references = %v, want %v", got, tt.wantEPR)
+			}
+		})
+	}
+}
+
+func TestParseProbeMatchesAddress(t *testing.T) {
+	from := &net.UDPAddr{IP: net.IPv4(10, 1, 2, 3), Port: 3702}
+	tests := []struct {
+		xaddrs string
+		want   string
+	}{
+		{"http://192.168.1.64/onvif/device_service", "192.168.1.64"},
+		{"http://192.168.1.64:8080/onvif/device_service http://[fe80::1]/onvif/device_service", "192.168.1.64"},
+		{"", "10.1.2.3"},
+		{"not-a-url", "10.1.2.3"},
+	}
+	for _, tt := range tests {
+		devs := parseProbeMatches(probeMatchesEnvelope("uuid:probe", [3]string{"urn:uuid:a", tt.xaddrs, ""}), "uuid:probe", from)
+		if len(devs) != 1 || devs[0].Address != tt.want {
+			t.Errorf("XAddrs %q: got %+v, want address %s", tt.xaddrs, devs, tt.want)
+		}
+	}
+}
+
+func TestApplyScopes(t *testing.T) {
+	tests := []struct {
+		scopes []string
+		want   Device
+	}{
+		{
+			[]string{"onvif://www.onvif.org/name/Lobby%20Cam", "onvif://www.onvif.org/hardware/IPC-HDW", "onvif://www.onvif.org/manufacturer/Dahua"},
+			Device{Name: "Lobby Cam", Model: "IPC-HDW", Manufacturer: "Dahua"},
+		},
+		{
+			// the first location wins
+			[]string{"onvif://www.onvif.org/location/country/thailand", "onvif://www.onvif.org/location/city/bangkok"},
+			Device{Location: "country/thailand"},
+		},
+		{
+			[]string{"onvif://www.onvif.org/Profile/Streaming", "http://example.com/name/x", "onvif://www.onvif.org/name"},
+			Device{},
+		},
+	}
+	for _, tt := range tests {
+		dev := Device{Scopes: tt.scopes}
+		applyScopes(&dev)
+		tt.want.Scopes = tt.scopes
+		if !reflect.DeepEqual(dev, tt.want) {
+			t.Errorf("applyScopes(%v) = %+v, want %+v", tt.scopes, dev, tt.want)
+		}
+	}
+}
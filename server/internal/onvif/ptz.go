@@ -0,0 +1,159 @@
+package onvif
+
+import (
+	"context"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ptzNS is the namespace of the PTZ service requests.
+const ptzNS = "http://www.onvif.org/ver20/ptz/wsdl"
+
+// Vector is a PTZ position, translation or velocity in the device's generic
+// spaces: pan and tilt from -1 to 1, zoom from 0 to 1 for positions and -1
+// to 1 otherwise. A nil axis is left out of the request; when only one of
+// pan and tilt is set the other is sent as 0.
+type Vector struct {
+	Pan  *float64 `json:"pan,omitempty"`
+	Tilt *float64 `json:"tilt,omitempty"`
+	Zoom *float64 `json:"zoom,omitempty"`
+}
+
+// Preset is a PTZ position stored on the device.
+type Preset struct {
+	Token string `json:"token"`
+	Name  string `json:"name"`
+}
+
+// ContinuousMove starts moving at the given velocity. The device stops by
+// itself after timeout, if it is not zero, or on Stop.
+func (c *Client) ContinuousMove(ctx context.Context, profileToken string, velocity Vector, timeout time.Duration) error {
+	body := `<ProfileToken>` + html.EscapeString(profileToken) + `</ProfileToken>` +
+		vectorXML("Velocity", velocity)
+	if timeout > 0 {
+		body += `<Timeout>PT` + strconv.FormatFloat(timeout.Seconds(), 'f', -1, 64) + `S</Timeout>`
+	}
+	return c.ptzCall(ctx, "ContinuousMove", body, nil)
+}
+
+// Stop stops any pan, tilt and zoom movement.
+func (c *Client) Stop(ctx context.Context, profileToken string) error {
+	body := `<ProfileToken>` + html.EscapeString(profileToken) + `</ProfileToken>` +
+		`<PanTilt>true</PanTilt><Zoom>true</Zoom>`
+	return c.ptzCall(ctx, "Stop", body, nil)
+}
+
+// AbsoluteMove moves to a position. A nil speed lets the device use its
+// default.
+func (c *Client) AbsoluteMove(ctx context.Context, profileToken string, position Vector, speed *Vector) error {
+	body := `<ProfileToken>` + html.EscapeString(profileToken) + `</ProfileToken>` +
+		vectorXML("Position", position)
+	if speed != nil {
+		body += vectorXML("Speed", *speed)
+	}
+	return c.ptzCall(ctx, "AbsoluteMove", body, nil)
+}
+
+// RelativeMove moves by a translation from the current position. A nil
+// speed lets the device use its default.
+func (c *Client) RelativeMove(ctx context.Context, profileToken string, translation Vector, speed *Vector) error {
+	body := `<ProfileToken>` + html.EscapeString(profileToken) + `</ProfileToken>` +
+		vectorXML("Translation", translation)
+	if speed != nil {
+		body += vectorXML("Speed", *speed)
+	}
+	return c.ptzCall(ctx, "RelativeMove", body, nil)
+}
+
+// Presets calls GetPresets.
+func (c *Client) Presets(ctx context.Context, profileToken string) ([]Preset, error) {
+	var resp struct {
+		Presets []struct {
+			Token string `xml:"token,attr"`
+			Name  string `xml:"Name"`
+		} `xml:"Body>GetPresetsResponse>Preset"`
+	}
+	body := `<ProfileToken>` + html.EscapeString(profileToken) + `</ProfileToken>`
+	if err := c.ptzCall(ctx, "GetPresets", body, &resp); err != nil {
+		return nil, err
+	}
+	presets := make([]Preset, 0, len(resp.Presets))
+	for _, p := range resp.Presets {
+		presets = append(presets, Preset{Token: p.Token, Name: strings.TrimSpace(p.Name)})
+	}
+	return presets, nil
+}
+
+// GotoPreset moves to a preset. A nil speed lets the device use its default.
+func (c *Client) GotoPreset(ctx context.Context, profileToken, presetToken string, speed *Vector) error {
+	body := `<ProfileToken>` + html.EscapeString(profileToken) + `</ProfileToken>` +
+		`<PresetToken>` + html.EscapeString(presetToken) + `</PresetToken>`
+	if speed != nil {
+		body += vectorXML("Speed", *speed)
+	}
+	return c.ptzCall(ctx, "GotoPreset", body, nil)
+}
+
+// SetPreset stores the current position as a preset and returns its token.
+// An existing preset is overwritten when presetToken is given.
+func (c *Client) SetPreset(ctx context.Context, profileToken, name, presetToken string) (string, error) {
+	var resp struct {
+		Token string `xml:"Body>SetPresetResponse>PresetToken"`
+	}
+	body := `<ProfileToken>` + html.EscapeString(profileToken) + `</ProfileToken>`
+	if name != "" {
+		body += `<PresetName>` + html.EscapeString(name) + `</PresetName>`
+	}
+	if presetToken != "" {
+		body += `<PresetToken>` + html.EscapeString(presetToken) + `</PresetToken>`
+	}
+	if err := c.ptzCall(ctx, "SetPreset", body, &resp); err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(resp.Token)
+	if token == "" {
+		token = presetToken
+	}
+	return token, nil
+}
+
+// RemovePreset deletes a preset.
+func (c *Client) RemovePreset(ctx context.Context, profileToken, presetToken string) error {
+	body := `<ProfileToken>` + html.EscapeString(profileToken) + `</ProfileToken>` +
+		`<PresetToken>` + html.EscapeString(presetToken) + `</PresetToken>`
+	return c.ptzCall(ctx, "RemovePreset", body, nil)
+}
+
+// ptzCall sends a PTZ service request. resp may be nil when the response
+// carries nothing of interest.
+func (c *Client) ptzCall(ctx context.Context, operation, body string, resp interface{}) error {
+	addr, err := c.ptz(ctx)
+	if err != nil {
+		return err
+	}
+	if resp == nil {
+		resp = &struct{}{}
+	}
+	return c.call(ctx, addr, `<`+operation+` xmlns="`+ptzNS+`">`+body+`</`+operation+`>`, resp)
+}
+
+// vectorXML renders a PTZVector or PTZSpeed element.
+func vectorXML(name string, v Vector) string {
+	s := `<` + name + `>`
+	if v.Pan != nil || v.Tilt != nil {
+		s += `<PanTilt xmlns="http://www.onvif.org/ver10/schema" x="` + formatAxis(v.Pan) + `" y="` + formatAxis(v.Tilt) + `"/>`
+	}
+	if v.Zoom != nil {
+		s += `<Zoom xmlns="http://www.onvif.org/ver10/schema" x="` + formatAxis(v.Zoom) + `"/>`
+	}
+	return s + `</` + name + `>`
+}
+
+func formatAxis(v *float64) string {
+	if v == nil {
+		return "0"
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
@@ -0,0 +1,114 @@
+package playback
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/mp4"
+	"github.com/boytur/cctv-recording-center/server/internal/segment"
+)
+
+// targetPartDuration is the length the playlist aims for when grouping
+// consecutive fragments of a file into one HLS media segment.
+const targetPartDuration = 6 * time.Second
+
+// Playlist types.
+const (
+	TypeVOD   = "VOD"
+	TypeEvent = "EVENT"
+)
+
+// part is one HLS media segment: a byte range of consecutive fragments.
+type part struct {
+	start    time.Time
+	duration time.Duration
+	offset   int64
+	length   int64
+}
+
+// BuildPlaylist renders an HLS playlist covering [from, to) from fragmented
+// MP4 segments sorted by start time. Every file starts a new discontinuity
+// because the recorder resets timestamps per file; a program date-time tag
+// carries the wall-clock position so players show gaps at the right place.
+// An EVENT playlist is left open for the player to reload; a VOD playlist is
+// terminated with EXT-X-ENDLIST.
+func BuildPlaylist(segs []segment.Segment, from, to time.Time, playlistType string) string {
+	type fileParts struct {
+		seg   segment.Segment
+		parts []part
+	}
+	var files []fileParts
+	target := time.Second
+	for _, s := range segs {
+		if s.Media == nil || !s.End.After(from) || !s.Start.Before(to) {
+			continue
+		}
+		parts := splitParts(s.Start, s.Media, from, to)
+		if len(parts) == 0 {
+			continue
+		}
+		for _, p := range parts {
+			if p.duration > target {
+				target = p.duration
+			}
+		}
+		files = append(files, fileParts{seg: s, parts: parts})
+	}
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	b.WriteString("#EXT-X-VERSION:7\n")
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", int(math.Ceil(target.Seconds())))
+	b.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n")
+	fmt.Fprintf(&b, "#EXT-X-PLAYLIST-TYPE:%s\n", playlistType)
+	b.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+
+	for i, f := range files {
+		uri := f.seg.URL()
+		if i > 0 {
+			b.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+		fmt.Fprintf(&b, "#EXT-X-MAP:URI=\"%s\",BYTERANGE=\"%d@0\"\n", uri, f.seg.Media.InitSize)
+		for j, p := range f.parts {
+			if j == 0 || !p.start.Equal(f.parts[j-1].start.Add(f.parts[j-1].duration)) {
+				fmt.Fprintf(&b, "#EXT-X-PROGRAM-DATE-TIME:%s\n", p.start.Format("2006-01-02T15:04:05.000Z07:00"))
+			}
+			fmt.Fprintf(&b, "#EXTINF:%.3f,\n", p.duration.Seconds())
+			fmt.Fprintf(&b, "#EXT-X-BYTERANGE:%d@%d\n", p.length, p.offset)
+			b.WriteString(uri + "\n")
+		}
+	}
+	if playlistType == TypeVOD {
+		b.WriteString("#EXT-X-ENDLIST\n")
+	}
+	return b.String()
+}
+
+// splitParts groups the fragments of a file that overlap [from, to) into
+// parts of roughly targetPartDuration, each starting on a keyframe.
+func splitParts(fileStart time.Time, media *mp4.File, from, to time.Time) []part {
+	var parts []part
+	var cur *part
+	for _, fr := range media.Fragments {
+		start := fileStart.Add(fr.Start)
+		end := start.Add(fr.Duration)
+		if !end.After(from) || !start.Before(to) {
+			continue
+		}
+		contiguous := cur != nil && cur.offset+cur.length == fr.Offset
+		if cur == nil || !contiguous || (fr.Keyframe && cur.duration >= targetPartDuration) {
+			if cur != nil {
+				parts = append(parts, *cur)
+			}
+			cur = &part{start: start, offset: fr.Offset}
+		}
+		cur.duration += fr.Duration
+		cur.length += fr.Size
+	}
+	if cur != nil {
+		parts = append(parts, *cur)
+	}
+	return parts
+}
@@ -0,0 +1,132 @@
+package playback
+
+import (
+	"errors"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/segment"
+)
+
+// ErrNoRecordings is returned when a camera has nothing stored to seek into.
+var ErrNoRecordings = errors.New("no recordings for camera")
+
+// Position is where playback should begin for a requested wall-clock time.
+type Position struct {
+	Requested time.Time
+	Segment   segment.Segment
+	// Offset is the position of the requested time within the segment file.
+	// When the request falls in a gap it is the offset playback resumes at.
+	Offset time.Duration
+	// KeyframeOffset and KeyframeByte locate the nearest keyframe at or
+	// before Offset, where a player can start decoding.
+	KeyframeOffset time.Duration
+	KeyframeByte   int64
+	HasKeyframe    bool
+	// InGap is set when nothing was recorded at the requested time. GapStart
+	// and GapEnd bound the gap; either is zero if the gap is open-ended.
+	InGap    bool
+	GapStart time.Time
+	GapEnd   time.Time
+}
+
+// Seek resolves a camera and absolute time to the recorded segment covering
+// it. If the time falls in a gap, the position snaps forward to the start of
+// the next recording, or back to the last one if nothing follows.
+func Seek(cameraID string, t time.Time) (*Position, error) {
+	covering, err := segment.Range(cameraID, t, t.Add(time.Nanosecond), segment.KindContinuous)
+	if err != nil {
+		return nil, err
+	}
+	if len(covering) > 0 {
+		// prefer the latest-starting segment if two overlap
+		s := covering[len(covering)-1]
+		return at(t, s, t.Sub(s.Start)), nil
+	}
+
+	prev, next, err := neighbours(cameraID, t)
+	if err != nil {
+		return nil, err
+	}
+	if prev == nil && next == nil {
+		return nil, ErrNoRecordings
+	}
+
+	var pos *Position
+	if next != nil {
+		pos = at(t, *next, 0)
+	} else {
+		pos = at(t, *prev, prev.Duration())
+	}
+	pos.InGap = true
+	if prev != nil {
+		pos.GapStart = prev.End
+	}
+	if next != nil {
+		pos.GapEnd = next.Start
+	}
+	return pos, nil
+}
+
+func at(t time.Time, s segment.Segment, offset time.Duration) *Position {
+	pos := &Position{Requested: t, Segment: s, Offset: offset}
+	if s.Media != nil {
+		if kf, ok := s.Media.KeyframeAt(offset); ok {
+			pos.KeyframeOffset = kf.Time
+			pos.KeyframeByte = kf.Offset
+			pos.HasKeyframe = true
+		}
+	}
+	return pos
+}
+
+// neighbours finds the last segment ending before t and the first segment
+// starting after it, searching across stored days.
+func neighbours(cameraID string, t time.Time) (prev, next *segment.Segment, err error) {
+	days, err := segment.Days(cameraID)
+	if err != nil {
+		return nil, nil, err
+	}
+	local := t.In(time.Local)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.Local)
+
+	// walk forward from the day before t (its last segment may run past
+	// midnight) until a later segment turns up
+	for _, day := range days {
+		if day.Before(today.AddDate(0, 0, -1)) {
+			continue
+		}
+		segs, err := segment.List(cameraID, day, segment.KindContinuous)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := range segs {
+			if segs[i].Start.After(t) {
+				next = &segs[i]
+				break
+			}
+		}
+		if next != nil {
+			break
+		}
+	}
+
+	for i := len(days) - 1; i >= 0; i-- {
+		if days[i].After(today) {
+			continue
+		}
+		segs, err := segment.List(cameraID, days[i], segment.KindContinuous)
+		if err != nil {
+			return nil, nil, err
+		}
+		for j := len(segs) - 1; j >= 0; j-- {
+			if !segs[j].End.After(t) {
+				prev = &segs[j]
+				break
+			}
+		}
+		if prev != nil {
+			break
+		}
+	}
+	return prev, next, nil
+}
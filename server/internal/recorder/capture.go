@@ -0,0 +1,275 @@
+package recorder
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/events"
+)
+
+// captureStopTimeout is how long a stopped capture's ffmpeg gets to finish
+// the file before it is killed.
+const captureStopTimeout = 10 * time.Second
+
+// CaptureOptions describes a manual capture requested by an operator.
+type CaptureOptions struct {
+	CameraID   string
+	CameraName string
+	RTSPURL    string
+	Username   string
+	Password   string
+	// Duration stops the capture automatically; zero records until stopped.
+	Duration time.Duration
+	Operator string
+	Note     string
+	// OnExit is called once the ffmpeg process has exited, whether it was
+	// stopped, reached Duration or failed.
+	OnExit func(session CaptureSession, err error)
+}
+
+// CaptureSession is a manual capture writing a single capture_*.mp4 file. It
+// runs independently of the continuous rec_* segmenter for the same camera.
+type CaptureSession struct {
+	CameraID   string
+	CameraName string
+	FileName   string
+	Path       string
+	StartTime  time.Time
+	Duration   time.Duration
+	Operator   string
+	Note       string
+	cmd        *exec.Cmd
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+// StartCapture starts a manual capture for a camera.
+func StartCapture(opts CaptureOptions) (*CaptureSession, error) {
+	return defaultManager.StartCapture(opts)
+}
+
+// StopCapture stops the manual capture running for a camera.
+func StopCapture(cameraID string) error {
+	return defaultManager.StopCapture(cameraID)
+}
+
+// IsCapturing checks if a manual capture is running for a camera.
+func IsCapturing(cameraID string) bool {
+	return defaultManager.IsCapturing(cameraID)
+}
+
+// GetActiveCaptures returns the running manual captures.
+func GetActiveCaptures() []CaptureSession {
+	return defaultManager.GetActiveCaptures()
+}
+
+func (m *Manager) StartCapture(opts CaptureOptions) (*CaptureSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if session, exists := m.captures[opts.CameraID]; exists {
+		if session.cmd != nil && session.cmd.Process != nil {
+			return nil, fmt.Errorf("camera %s already has a manual capture running", opts.CameraID)
+		}
+	}
+
+	now := time.Now()
+	outputPath := filepath.Join(m.outputDir, opts.CameraID, now.Format("2006-01-02"))
+	if err := os.MkdirAll(outputPath, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	fileName := fmt.Sprintf("capture_%s.mp4", now.Format("20060102_150405"))
+	filePath := filepath.Join(outputPath, fileName)
+
+	// Same input handling as continuous recording, but a single fragmented
+	// MP4 file instead of a segment muxer so the capture is one clip that can
+	// be played while it is still being written.
+	args := []string{
+		"-rtsp_transport", "tcp",
+		"-fflags", "+genpts",
+		"-i", AuthRTSPURL(opts.RTSPURL, opts.Username, opts.Password),
+		"-map", "0",
+		"-c:v", "copy",
+		"-c:a", "aac",
+		"-avoid_negative_ts", "make_zero",
+	}
+	if opts.Duration > 0 {
+		args = append(args, "-t", strconv.FormatFloat(opts.Duration.Seconds(), 'f', 3, 64))
+	}
+	args = append(args,
+		"-f", "mp4",
+		"-movflags", "frag_keyframe+empty_moov+default_base_moof",
+		filePath,
+	)
+
+	logRTSPURL := opts.RTSPURL
+	if opts.Username != "" {
+		logRTSPURL = "rtsp://" + opts.Username + ":****@" + strings.TrimPrefix(opts.RTSPURL, "rtsp://")
+	}
+	log.Printf("[recorder] Starting manual capture for camera %s with RTSP: %s", opts.CameraID, logRTSPURL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+
+	logFile, err := os.OpenFile(
+		filepath.Join(outputPath, "recording.log"),
+		os.O_CREATE|os.O_WRONLY|os.O_APPEND,
+		0o644,
+	)
+	if err != nil {
+		log.Printf("[recorder] Warning: failed to create log file for camera %s: %v", opts.CameraID, err)
+	}
+	if logFile != nil {
+		fmt.Fprintf(logFile, "\n\n=== Manual capture started at %s ===\n", now.Format(time.RFC3339))
+		fmt.Fprintf(logFile, "Camera: %s (%s)\n", opts.CameraID, opts.CameraName)
+		fmt.Fprintf(logFile, "Operator: %s\n", opts.Operator)
+		fmt.Fprintf(logFile, "RTSP URL: %s\n", logRTSPURL)
+		fmt.Fprintf(logFile, "===========================================\n\n")
+		cmd.Stdout = logFile
+		cmd.Stderr = logFile
+	}
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		if logFile != nil {
+			fmt.Fprintf(logFile, "\nERROR: Failed to start ffmpeg: %v\n", err)
+			logFile.Close()
+		}
+		log.Printf("[recorder] Failed to start manual capture for camera %s: %v", opts.CameraID, err)
+		return nil, fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	session := &CaptureSession{
+		CameraID:   opts.CameraID,
+		CameraName: opts.CameraName,
+		FileName:   fileName,
+		Path:       filePath,
+		StartTime:  now,
+		Duration:   opts.Duration,
+		Operator:   opts.Operator,
+		Note:       opts.Note,
+		cmd:        cmd,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+	m.captures[opts.CameraID] = session
+
+	go func() {
+		err := cmd.Wait()
+		if logFile != nil {
+			if err != nil {
+				fmt.Fprintf(logFile, "\n=== Manual capture ended with error at %s: %v ===\n", time.Now().Format(time.RFC3339), err)
+			} else {
+				fmt.Fprintf(logFile, "\n=== Manual capture ended normally at %s ===\n", time.Now().Format(time.RFC3339))
+			}
+			logFile.Close()
+		}
+
+		// StopCapture removes the session before signalling ffmpeg, so a
+		// session still registered here ended on its own.
+		m.mu.Lock()
+		unexpected := m.captures[opts.CameraID] == session
+		if unexpected {
+			delete(m.captures, opts.CameraID)
+		}
+		m.mu.Unlock()
+
+		payload := captureEventPayload(session)
+		if err != nil {
+			log.Printf("[recorder] Manual capture for camera %s ended with error: %v", opts.CameraID, err)
+			payload["error"] = err.Error()
+		} else {
+			log.Printf("[recorder] Manual capture for camera %s ended normally", opts.CameraID)
+		}
+		if unexpected && err != nil {
+			events.Publish(domain.Event{Type: events.CaptureFailed, CameraID: opts.CameraID, Severity: domain.SeverityWarning, Payload: payload})
+		} else {
+			events.Publish(domain.Event{Type: events.CaptureStopped, CameraID: opts.CameraID, Payload: payload})
+		}
+		cancel()
+		close(session.done)
+		if opts.OnExit != nil {
+			opts.OnExit(session.copy(), err)
+		}
+	}()
+
+	log.Printf("Started manual capture for camera %s (%s) to %s", opts.CameraID, opts.CameraName, filePath)
+	events.Publish(domain.Event{Type: events.CaptureStarted, CameraID: opts.CameraID, Payload: captureEventPayload(session)})
+	copied := session.copy()
+	return &copied, nil
+}
+
+func (m *Manager) StopCapture(cameraID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, exists := m.captures[cameraID]
+	if !exists {
+		return fmt.Errorf("no active manual capture for camera %s", cameraID)
+	}
+
+	// Interrupt lets ffmpeg finish the current fragment; the context is
+	// cancelled by the wait goroutine once it exits, or here to kill an
+	// ffmpeg that doesn't exit within captureStopTimeout.
+	if session.cmd != nil && session.cmd.Process != nil {
+		if err := session.cmd.Process.Signal(os.Interrupt); err != nil {
+			session.cmd.Process.Kill()
+		}
+		time.AfterFunc(captureStopTimeout, session.cancel)
+	}
+
+	m.trackExit(cameraID, session.done)
+	delete(m.captures, cameraID)
+	log.Printf("Stopped manual capture for camera %s", cameraID)
+	return nil
+}
+
+func (m *Manager) IsCapturing(cameraID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, exists := m.captures[cameraID]
+	return exists && session.cmd != nil && session.cmd.Process != nil
+}
+
+func (m *Manager) GetActiveCaptures() []CaptureSession {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	captures := make([]CaptureSession, 0, len(m.captures))
+	for _, session := range m.captures {
+		captures = append(captures, session.copy())
+	}
+	return captures
+}
+
+func captureEventPayload(s *CaptureSession) map[string]interface{} {
+	return map[string]interface{}{
+		"name":             s.CameraName,
+		"file_name":        s.FileName,
+		"operator":         s.Operator,
+		"note":             s.Note,
+		"duration_seconds": int(s.Duration.Seconds()),
+	}
+}
+
+// copy returns the session without its process handles.
+func (s *CaptureSession) copy() CaptureSession {
+	return CaptureSession{
+		CameraID:   s.CameraID,
+		CameraName: s.CameraName,
+		FileName:   s.FileName,
+		Path:       s.Path,
+		StartTime:  s.StartTime,
+		Duration:   s.Duration,
+		Operator:   s.Operator,
+		Note:       s.Note,
+	}
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+)
+
+// AlertRepository defines persistence operations for alert settings and
+// per-camera mute windows.
+type AlertRepository interface {
+	// GetSettings returns the saved settings, or the defaults if none exist.
+	GetSettings() (*domain.AlertSettings, error)
+	SaveSettings(s *domain.AlertSettings) error
+
+	// ListMutes returns mute windows, optionally for one camera and only
+	// those that have not ended before the given time.
+	ListMutes(cameraID string, notEndedBefore time.Time) ([]*domain.AlertMute, error)
+	GetMute(id string) (*domain.AlertMute, error)
+	CreateMute(m *domain.AlertMute) error
+	DeleteMute(id string) error
+}
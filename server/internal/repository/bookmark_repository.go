@@ -0,0 +1,12 @@
+package repository
+
+import "github.com/boytur/cctv-recording-center/server/internal/domain"
+
+// BookmarkRepository defines persistence operations for timeline bookmarks.
+type BookmarkRepository interface {
+	List(f domain.BookmarkFilter) ([]*domain.Bookmark, error)
+	GetByID(id string) (*domain.Bookmark, error)
+	Create(b *domain.Bookmark) error
+	Update(b *domain.Bookmark) error
+	Delete(id string) error
+}
@@ -1,6 +1,10 @@
 package repository
 
-import "github.com/boytur/cctv-recording-center/server/internal/domain"
+import (
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+)
 
 // CameraRepository defines persistence operations for cameras.
 type CameraRepository interface {
@@ -8,5 +12,10 @@ type CameraRepository interface {
 	GetByID(id string) (*domain.Camera, error)
 	Create(c *domain.Camera) error
 	Update(c *domain.Camera) error
+	UpdateStatus(id, status string) error
+	UpdateMaintenance(id string, until *time.Time, reason string) error
+	UpdateProbe(id, status string, at time.Time, latencyMs *int64) error
+	UpdateStream(id string, stream *domain.StreamInfo, change *domain.StreamChange) error
+	UpdateHardware(id string, info *domain.DeviceInfo) error
 	Delete(id string) error
 }
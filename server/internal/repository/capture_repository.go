@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+)
+
+// CaptureRepository defines persistence operations for manual captures.
+type CaptureRepository interface {
+	ListByCamera(cameraID string, from, to time.Time) ([]*domain.Capture, error)
+	ListByStatus(status string) ([]*domain.Capture, error)
+	GetByID(id string) (*domain.Capture, error)
+	Create(c *domain.Capture) error
+	Update(c *domain.Capture) error
+}
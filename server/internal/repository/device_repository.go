@@ -0,0 +1,13 @@
+package repository
+
+import "github.com/boytur/cctv-recording-center/server/internal/domain"
+
+// DeviceRepository defines persistence operations for multi-channel devices.
+type DeviceRepository interface {
+	List() ([]*domain.Device, error)
+	GetByID(id string) (*domain.Device, error)
+	Create(d *domain.Device) error
+	Update(d *domain.Device) error
+	UpdateHealth(id string, h domain.DeviceHealth) error
+	Delete(id string) error
+}
@@ -0,0 +1,13 @@
+package repository
+
+import "github.com/boytur/cctv-recording-center/server/internal/domain"
+
+// EmailGroupRepository defines persistence operations for email recipient
+// groups.
+type EmailGroupRepository interface {
+	List() ([]*domain.EmailGroup, error)
+	GetByID(id string) (*domain.EmailGroup, error)
+	Create(g *domain.EmailGroup) error
+	Update(g *domain.EmailGroup) error
+	Delete(id string) error
+}
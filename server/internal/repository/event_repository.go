@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+)
+
+// EventRepository defines persistence operations for the event log.
+type EventRepository interface {
+	Append(e *domain.Event) error
+	List(f domain.EventFilter) ([]*domain.Event, int64, error)
+	DeleteBefore(t time.Time, except []string) (int64, error)
+	DeleteTypesBefore(t time.Time, prefixes []string) (int64, error)
+}
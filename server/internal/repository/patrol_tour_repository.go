@@ -0,0 +1,13 @@
+package repository
+
+import "github.com/boytur/cctv-recording-center/server/internal/domain"
+
+// PatrolTourRepository defines persistence operations for PTZ patrol tours.
+type PatrolTourRepository interface {
+	List(cameraID string) ([]*domain.PatrolTour, error)
+	GetByID(id string) (*domain.PatrolTour, error)
+	Create(t *domain.PatrolTour) error
+	Update(t *domain.PatrolTour) error
+	Delete(id string) error
+	DeleteByCamera(cameraID string) error
+}
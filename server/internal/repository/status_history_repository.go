@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+)
+
+// StatusHistoryRepository defines persistence operations for camera status
+// transitions.
+type StatusHistoryRepository interface {
+	Append(c *domain.StatusChange) error
+	// List returns the transitions of a camera in [from, to), oldest first.
+	List(cameraID string, from, to time.Time) ([]*domain.StatusChange, error)
+	// Last returns the latest transition of a camera before t, or nil if
+	// there is none.
+	Last(cameraID string, before time.Time) (*domain.StatusChange, error)
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+)
+
+// WebhookRepository defines persistence operations for webhook subscriptions
+// and their delivery log.
+type WebhookRepository interface {
+	List() ([]*domain.Webhook, error)
+	GetByID(id string) (*domain.Webhook, error)
+	Create(w *domain.Webhook) error
+	Update(w *domain.Webhook) error
+	Delete(id string) error
+
+	CreateDelivery(d *domain.WebhookDelivery) error
+	UpdateDelivery(d *domain.WebhookDelivery) error
+	GetDelivery(id string) (*domain.WebhookDelivery, error)
+	// ListDeliveries returns a webhook's deliveries, newest first, optionally
+	// only those in the given status.
+	ListDeliveries(webhookID, status string, limit int) ([]*domain.WebhookDelivery, error)
+	// DueDeliveries returns pending or retrying deliveries whose next attempt
+	// is at or before t.
+	DueDeliveries(t time.Time) ([]*domain.WebhookDelivery, error)
+	// DeleteFinishedDeliveries deletes succeeded and dead deliveries last
+	// updated before t.
+	DeleteFinishedDeliveries(t time.Time) (int64, error)
+}
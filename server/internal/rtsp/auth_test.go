@@ -0,0 +1,123 @@
+package rtsp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseParams(t *testing.T) {
+	tests := []struct {
+		in   string
+		want map[string]string
+	}{
+		{`realm="IP Camera", nonce="abc123"`, map[string]string{"realm": "IP Camera", "nonce": "abc123"}},
+		{`Realm=cam,NONCE=abc , stale=FALSE`, map[string]string{"realm": "cam", "nonce": "abc", "stale": "FALSE"}},
+		{`realm="a, b=c", qop="auth,auth-int"`, map[string]string{"realm": "a, b=c", "qop": "auth,auth-int"}},
+		{`realm="", nonce=`, map[string]string{"realm": "", "nonce": ""}},
+		{`realm="unterminated`, map[string]string{"realm": "unterminated"}},
+		{`realm="x", garbage`, map[string]string{"realm": "x"}},
+		{``, map[string]string{}},
+	}
+	for _, tt := range tests {
+		if got := parseParams(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseParams(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []string
+		want    *challenge
+	}{
+		{
+			name:    "digest",
+			headers: []string{`Digest realm="IP Camera", nonce="abc123", opaque="xyz", stale=TRUE`},
+			want:    &challenge{scheme: "digest", realm: "IP Camera", nonce: "abc123", opaque: "xyz", stale: true},
+		},
+		{
+			name:    "digest preferred over basic",
+			headers: []string{`Basic realm="cam"`, `Digest realm="cam", nonce="n"`},
+			want:    &challenge{scheme: "digest", realm: "cam", nonce: "n"},
+		},
+		{
+			name:    "basic",
+			headers: []string{`  basic realm="cam"`},
+			want:    &challenge{scheme: "basic", realm: "cam"},
+		},
+		{
+			name:    "qop auth among others",
+			headers: []string{`Digest realm="r", nonce="n", qop="auth-int, auth"`},
+			want:    &challenge{scheme: "digest", realm: "r", nonce: "n", qop: "auth"},
+		},
+		{
+			name:    "qop without auth",
+			headers: []string{`Digest realm="r", nonce="n", qop="auth-int"`},
+			want:    &challenge{scheme: "digest", realm: "r", nonce: "n"},
+		},
+		{name: "unsupported scheme", headers: []string{`Bearer realm="r"`}},
+		{name: "no header"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseChallenge(tt.headers); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseChallenge() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	defer func(f func() string) { cnonce = f }(cnonce)
+	cnonce = func() string { return "0a4f113b" }
+
+	// The RFC 2617 3.5 example, with and without qop.
+	rfc := challenge{scheme: "digest", realm: "testrealm@host.com", nonce: "dcd98b7102dd2f0e8b11d0f600bfb0c093", opaque: "5ccc069c403ebaf9f0171e9517f40e41"}
+	qop := rfc
+	qop.qop = "auth"
+
+	tests := []struct {
+		name   string
+		ch     challenge
+		method string
+		uri    string
+		user   string
+		pass   string
+		want   []string
+	}{
+		{
+			name: "basic", ch: challenge{scheme: "basic", realm: "cam"},
+			method: "DESCRIBE", uri: "rtsp://10.0.0.5/stream1", user: "admin", pass: "12345",
+			want: []string{"Basic YWRtaW46MTIzNDU="},
+		},
+		{
+			name: "digest", ch: challenge{scheme: "digest", realm: "IP Camera", nonce: "abc123"},
+			method: "DESCRIBE", uri: "rtsp://10.0.0.5:554/stream1", user: "admin", pass: "12345",
+			want: []string{`Digest username="admin", realm="IP Camera", nonce="abc123", uri="rtsp://10.0.0.5:554/stream1", response="dcdc2aebc3fa888c42dcad42b2ae1a81"`},
+		},
+		{
+			name: "digest with opaque", ch: rfc,
+			method: "GET", uri: "/dir/index.html", user: "Mufasa", pass: "Circle Of Life",
+			want: []string{`Digest username="Mufasa", realm="testrealm@host.com", nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", uri="/dir/index.html", response="670fd8c2df070c60b045671b8b24ff02", opaque="5ccc069c403ebaf9f0171e9517f40e41"`},
+		},
+		{
+			name: "digest qop=auth counts requests", ch: qop,
+			method: "GET", uri: "/dir/index.html", user: "Mufasa", pass: "Circle Of Life",
+			want: []string{
+				`Digest username="Mufasa", realm="testrealm@host.com", nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", uri="/dir/index.html", response="6629fae49393a05397450978507c4ef1", qop=auth, nc=00000001, cnonce="0a4f113b", opaque="5ccc069c403ebaf9f0171e9517f40e41"`,
+				`Digest username="Mufasa", realm="testrealm@host.com", nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", uri="/dir/index.html", response="15b6bb427e3fecd23a43cb702ce447d5", qop=auth, nc=00000002, cnonce="0a4f113b", opaque="5ccc069c403ebaf9f0171e9517f40e41"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := tt.ch
+			for i, want := range tt.want {
+				if got := ch.authorize(tt.method, tt.uri, tt.user, tt.pass); got != want {
+					t.Errorf("request %d: authorize() =\n%s\nwant\n%s", i+1, got, want)
+				}
+			}
+		})
+	}
+}
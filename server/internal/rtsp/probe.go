@@ -0,0 +1,314 @@
+// Package rtsp implements just enough of an RTSP client to tell whether a
+// camera stream is actually available: OPTIONS and DESCRIBE with Basic or
+// Digest authentication, and parsing of the returned SDP.
+package rtsp
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+)
+
+const userAgent = "cctv-recording-center"
+
+// maxBody caps the size of a DESCRIBE body.
+const maxBody = 64 << 10
+
+// Result is the outcome of probing a stream.
+type Result struct {
+	// Status is one of the domain.CameraStatus values.
+	Status string
+	// Code is the RTSP status code of the last response, 0 if none.
+	Code int
+	// Server is the Server header of the camera, if sent.
+	Server string
+	// Methods lists the methods from the OPTIONS Public header.
+	Methods []string
+	// SDP is the parsed session description when DESCRIBE succeeded.
+	SDP *SessionDescription
+	// Latency is the time from dialing to the DESCRIBE response.
+	Latency time.Duration
+	// Err explains a status other than online.
+	Err error
+}
+
+// Online reports whether the stream was described successfully.
+func (r *Result) Online() bool {
+	return r.Status == domain.CameraStatusOnline
+}
+
+// Probe connects to the stream at rawURL and issues OPTIONS and DESCRIBE.
+// username and password, when set, override credentials embedded in the
+// URL. The whole exchange is bounded by ctx.
+func Probe(ctx context.Context, rawURL, username, password string) *Result {
+	start := time.Now()
+	target, err := parseURL(rawURL)
+	if err != nil {
+		return &Result{Status: domain.CameraStatusError, Err: err}
+	}
+	if username != "" {
+		target.username, target.password = username, password
+	}
+
+	conn, err := dial(ctx, target)
+	if err != nil {
+		return &Result{Status: domain.CameraStatusUnreachable, Err: err}
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	// unblock reads if ctx is cancelled without a deadline
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	c := &client{conn: conn, r: bufio.NewReader(conn), target: target}
+	res := &Result{}
+
+	// Some cameras only require auth for DESCRIBE, some reject OPTIONS
+	// outright; only a transport failure ends the probe here.
+	resp, err := c.do("OPTIONS", nil)
+	if err != nil {
+		return unresponsive(res, err)
+	}
+	res.Code = resp.code
+	res.Server = resp.header.Get("Server")
+	if resp.code == 200 {
+		for _, m := range strings.Split(resp.header.Get("Public"), ",") {
+			if m = strings.TrimSpace(m); m != "" {
+				res.Methods = append(res.Methods, m)
+			}
+		}
+	}
+
+	resp, err = c.do("DESCRIBE", map[string]string{"Accept": "application/sdp"})
+	if err != nil {
+		return unresponsive(res, err)
+	}
+	res.Code = resp.code
+	res.Latency = time.Since(start)
+	if res.Server == "" {
+		res.Server = resp.header.Get("Server")
+	}
+
+	switch {
+	case resp.code == 200:
+	case resp.code == 401 || resp.code == 403:
+		res.Status = domain.CameraStatusAuthFailed
+		if target.username == "" {
+			res.Err = fmt.Errorf("camera requires credentials (%d %s)", resp.code, resp.reason)
+		} else {
+			res.Err = fmt.Errorf("credentials rejected (%d %s)", resp.code, resp.reason)
+		}
+		return res
+	case resp.code == 404 || resp.code == 454:
+		res.Status = domain.CameraStatusStreamNotFound
+		res.Err = fmt.Errorf("stream %s not found (%d %s)", target.path, resp.code, resp.reason)
+		return res
+	default:
+		res.Status = domain.CameraStatusError
+		res.Err = fmt.Errorf("DESCRIBE failed: %d %s", resp.code, resp.reason)
+		return res
+	}
+
+	sdp, err := ParseSDP(resp.body)
+	if err != nil {
+		res.Status = domain.CameraStatusError
+		res.Err = fmt.Errorf("invalid SDP: %w", err)
+		return res
+	}
+	res.SDP = sdp
+	if len(sdp.Media) == 0 {
+		res.Status = domain.CameraStatusStreamNotFound
+		res.Err = errors.New("stream describes no media")
+		return res
+	}
+	res.Status = domain.CameraStatusOnline
+	return res
+}
+
+// unresponsive records a transport or protocol error after connecting.
+func unresponsive(res *Result, err error) *Result {
+	res.Status = domain.CameraStatusUnresponsive
+	res.Err = err
+	return res
+}
+
+// target is a parsed RTSP URL with the credentials split off.
+type target struct {
+	scheme   string
+	host     string // host:port
+	path     string
+	username string
+	password string
+}
+
+// requestURL is the URL sent in requests, without credentials.
+func (t *target) requestURL() string {
+	return t.scheme + "://" + t.host + t.path
+}
+
+// parseURL parses an RTSP URL leniently: camera passwords often contain
+// characters such as '@' that are not escaped, which net/url rejects.
+func parseURL(raw string) (*target, error) {
+	scheme, rest, ok := strings.Cut(strings.TrimSpace(raw), "://")
+	if !ok {
+		return nil, fmt.Errorf("invalid rtsp url %q", raw)
+	}
+	scheme = strings.ToLower(scheme)
+	if scheme != "rtsp" && scheme != "rtsps" {
+		return nil, fmt.Errorf("unsupported scheme %q", scheme)
+	}
+
+	t := &target{scheme: scheme, path: "/"}
+	authority := rest
+	if i := strings.Index(rest, "/"); i >= 0 {
+		authority, t.path = rest[:i], rest[i:]
+	}
+	if i := strings.LastIndex(authority, "@"); i >= 0 {
+		user, pass, _ := strings.Cut(authority[:i], ":")
+		t.username, t.password = unescape(user), unescape(pass)
+		authority = authority[i+1:]
+	}
+	if authority == "" {
+		return nil, fmt.Errorf("invalid rtsp url %q: missing host", raw)
+	}
+	if _, _, err := net.SplitHostPort(authority); err != nil {
+		port := "554"
+		if scheme == "rtsps" {
+			port = "322"
+		}
+		authority = net.JoinHostPort(strings.Trim(authority, "[]"), port)
+	}
+	t.host = authority
+	return t, nil
+}
+
+func unescape(s string) string {
+	if u, err := url.PathUnescape(s); err == nil {
+		return u
+	}
+	return s
+}
+
+func dial(ctx context.Context, t *target) (net.Conn, error) {
+	d := &net.Dialer{}
+	if t.scheme == "rtsps" {
+		host, _, _ := net.SplitHostPort(t.host)
+		// cameras almost always use self-signed certificates
+		cfg := &tls.Config{ServerName: host, InsecureSkipVerify: true}
+		return (&tls.Dialer{NetDialer: d, Config: cfg}).DialContext(ctx, "tcp", t.host)
+	}
+	return d.DialContext(ctx, "tcp", t.host)
+}
+
+// client sends requests over a single RTSP connection.
+type client struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	target *target
+	cseq   int
+	auth   *challenge
+}
+
+type response struct {
+	code   int
+	reason string
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+// do sends a request, answering a 401 challenge once if credentials are
+// available. Once challenged, later requests are authorised up front.
+func (c *client) do(method string, header map[string]string) (*response, error) {
+	resp, err := c.roundTrip(method, header)
+	if err != nil || resp.code != 401 || c.target.username == "" {
+		return resp, err
+	}
+	ch := parseChallenge(resp.header.Values("WWW-Authenticate"))
+	if ch == nil {
+		return resp, nil
+	}
+	if c.auth != nil && !ch.stale {
+		// already answered a challenge and were rejected again
+		return resp, nil
+	}
+	c.auth = ch
+	return c.roundTrip(method, header)
+}
+
+func (c *client) roundTrip(method string, header map[string]string) (*response, error) {
+	c.cseq++
+	uri := c.target.requestURL()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s RTSP/1.0\r\n", method, uri)
+	fmt.Fprintf(&b, "CSeq: %d\r\n", c.cseq)
+	fmt.Fprintf(&b, "User-Agent: %s\r\n", userAgent)
+	if c.auth != nil {
+		fmt.Fprintf(&b, "Authorization: %s\r\n", c.auth.authorize(method, uri, c.target.username, c.target.password))
+	}
+	for k, v := range header {
+		fmt.Fprintf(&b, "%s: %s\r\n", k, v)
+	}
+	b.WriteString("\r\n")
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return c.readResponse()
+}
+
+func (c *client) readResponse() (*response, error) {
+	tp := textproto.NewReader(c.r)
+	line, err := tp.ReadLine()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("connection closed without a response")
+		}
+		return nil, err
+	}
+	proto, status, ok := strings.Cut(line, " ")
+	if !ok || !strings.HasPrefix(proto, "RTSP/") {
+		return nil, fmt.Errorf("not an RTSP response: %q", truncate(line, 64))
+	}
+	codeStr, reason, _ := strings.Cut(status, " ")
+	code, err := strconv.Atoi(codeStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid status line: %q", truncate(line, 64))
+	}
+
+	header, err := tp.ReadMIMEHeader()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	resp := &response{code: code, reason: reason, header: header}
+
+	if n, _ := strconv.Atoi(header.Get("Content-Length")); n > 0 {
+		if n > maxBody {
+			return nil, fmt.Errorf("response body too large (%d bytes)", n)
+		}
+		resp.body = make([]byte, n)
+		if _, err := io.ReadFull(c.r, resp.body); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n] + "..."
+	}
+	return s
+}
@@ -0,0 +1,372 @@
+package rtsp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+)
+
+const testSDP = "v=0\r\n" +
+	"o=- 1 1 IN IP4 127.0.0.1\r\n" +
+	"s=Media Presentation\r\n" +
+	"t=0 0\r\n" +
+	"m=video 0 RTP/AVP 96\r\n" +
+	"a=control:trackID=1\r\n" +
+	"a=rtpmap:96 H264/90000\r\n"
+
+// rtspRequest is a request received by the stand-in server.
+type rtspRequest struct {
+	method string
+	uri    string
+	header textproto.MIMEHeader
+}
+
+// rtspServer is a stand-in camera on a loopback port. handle returns the
+// response to a request, or "" to leave it unanswered.
+type rtspServer struct {
+	ln     net.Listener
+	handle func(req *rtspRequest) string
+
+	mu       sync.Mutex
+	requests []*rtspRequest
+}
+
+func newRTSPServer(t *testing.T, handle func(req *rtspRequest) string) *rtspServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &rtspServer{ln: ln, handle: handle}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *rtspServer) url(path string) string {
+	return "rtsp://" + s.ln.Addr().String() + path
+}
+
+func (s *rtspServer) serve(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewReader(bufio.NewReader(conn))
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		header, err := tp.ReadMIMEHeader()
+		if err != nil {
+			return
+		}
+		f := strings.Fields(line)
+		if len(f) != 3 {
+			return
+		}
+		req := &rtspRequest{method: f[0], uri: f[1], header: header}
+		s.mu.Lock()
+		s.requests = append(s.requests, req)
+		s.mu.Unlock()
+		if resp := s.handle(req); resp != "" {
+			conn.Write([]byte(resp))
+		}
+	}
+}
+
+// methods lists the methods of the requests received so far.
+func (s *rtspServer) methods() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var m []string
+	for _, r := range s.requests {
+		m = append(m, r.method)
+	}
+	return m
+}
+
+// reply builds a response to req with the given extra header lines and body.
+func reply(req *rtspRequest, status string, body string, header ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "RTSP/1.0 %s\r\nCSeq: %s\r\n", status, req.header.Get("CSeq"))
+	for _, h := range header {
+		b.WriteString(h + "\r\n")
+	}
+	if body != "" {
+		fmt.Fprintf(&b, "Content-Length: %d\r\n", len(body))
+	}
+	b.WriteString("\r\n" + body)
+	return b.String()
+}
+
+// describeWith answers OPTIONS with 200 and DESCRIBE with status, body and
+// the given header lines.
+func describeWith(status, body string, header ...string) func(*rtspRequest) string {
+	return func(req *rtspRequest) string {
+		if req.method == "OPTIONS" {
+			return reply(req, "200 OK", "", "Public: OPTIONS, DESCRIBE, SETUP, PLAY", "Server: TestCam/1.0")
+		}
+		return reply(req, status, body, append([]string{"Content-Type: application/sdp"}, header...)...)
+	}
+}
+
+func probe(t *testing.T, url, username, password string) *Result {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	return Probe(ctx, url, username, password)
+}
+
+func TestProbeStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		handle   func(*rtspRequest) string
+		username string
+		want     string
+		wantCode int
+		wantErr  string
+	}{
+		{name: "online", handle: describeWith("200 OK", testSDP), want: domain.CameraStatusOnline, wantCode: 200},
+		{
+			name:     "401 without credentials",
+			handle:   describeWith("401 Unauthorized", "", `WWW-Authenticate: Basic realm="cam"`),
+			want:     domain.CameraStatusAuthFailed,
+			wantCode: 401,
+			wantErr:  "requires credentials",
+		},
+		{
+			name:     "403 with credentials",
+			handle:   describeWith("403 Forbidden", ""),
+			username: "admin",
+			want:     domain.CameraStatusAuthFailed,
+			wantCode: 403,
+			wantErr:  "credentials rejected",
+		},
+		{name: "404", handle: describeWith("404 Not Found", ""), want: domain.CameraStatusStreamNotFound, wantCode: 404, wantErr: "/stream not found"},
+		{name: "454", handle: describeWith("454 Session Not Found", ""), want: domain.CameraStatusStreamNotFound, wantCode: 454, wantErr: "not found"},
+		{name: "no media", handle: describeWith("200 OK", "v=0\r\ns=-\r\n"), want: domain.CameraStatusStreamNotFound, wantCode: 200, wantErr: "no media"},
+		{name: "500", handle: describeWith("500 Internal Server Error", ""), want: domain.CameraStatusError, wantCode: 500, wantErr: "DESCRIBE failed"},
+		{
+			name: "OPTIONS rejected",
+			handle: func(req *rtspRequest) string {
+				if req.method == "OPTIONS" {
+					return reply(req, "405 Method Not Allowed", "")
+				}
+				return reply(req, "200 OK", testSDP)
+			},
+			want:     domain.CameraStatusOnline,
+			wantCode: 200,
+		},
+		{
+			name:    "not RTSP",
+			handle:  func(*rtspRequest) string { return "HTTP/1.1 400 Bad Request\r\n\r\n" },
+			want:    domain.CameraStatusUnresponsive,
+			wantErr: "not an RTSP response",
+		},
+		{
+			name:    "hung",
+			handle:  func(*rtspRequest) string { return "" },
+			want:    domain.CameraStatusUnresponsive,
+			wantErr: "timeout",
+		},
+		{
+			name: "hung after OPTIONS",
+			handle: func(req *rtspRequest) string {
+				if req.method == "OPTIONS" {
+					return reply(req, "200 OK", "")
+				}
+				return ""
+			},
+			want:     domain.CameraStatusUnresponsive,
+			wantCode: 200,
+			wantErr:  "timeout",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newRTSPServer(t, tt.handle)
+			res := probe(t, srv.url("/stream"), tt.username, "secret")
+			if res.Status != tt.want || res.Code != tt.wantCode {
+				t.Errorf("Probe() = %s (%d), want %s (%d); err %v", res.Status, res.Code, tt.want, tt.wantCode, res.Err)
+			}
+			if tt.wantErr == "" && res.Err != nil {
+				t.Errorf("Err = %v, want nil", res.Err)
+			}
+			if tt.wantErr != "" && (res.Err == nil || !strings.Contains(res.Err.Error(), tt.wantErr)) {
+				t.Errorf("Err = %v, want it to mention %q", res.Err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProbeOnline(t *testing.T) {
+	srv := newRTSPServer(t, describeWith("200 OK", testSDP))
+	res := probe(t, srv.url("/Streaming/Channels/101"), "", "")
+	if !res.Online() {
+		t.Fatalf("Probe() = %s: %v", res.Status, res.Err)
+	}
+	if res.Server != "TestCam/1.0" {
+		t.Errorf("Server = %q", res.Server)
+	}
+	if got := strings.Join(res.Methods, ","); got != "OPTIONS,DESCRIBE,SETUP,PLAY" {
+		t.Errorf("Methods = %v", res.Methods)
+	}
+	if len(res.SDP.Media) != 1 || res.SDP.Media[0].Codec != "H264" {
+		t.Errorf("SDP media = %+v", res.SDP.Media)
+	}
+	if res.Latency <= 0 {
+		t.Errorf("Latency = %s", res.Latency)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for _, r := range srv.requests {
+		if want := srv.url("/Streaming/Channels/101"); r.uri != want {
+			t.Errorf("%s URI = %q, want %q", r.method, r.uri, want)
+		}
+	}
+}
+
+func TestProbeUnreachable(t *testing.T) {
+	// a port nothing listens on
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	res := probe(t, "rtsp://"+addr+"/stream", "", "")
+	if res.Status != domain.CameraStatusUnreachable || res.Err == nil {
+		t.Errorf("Probe() = %s, %v, want unreachable", res.Status, res.Err)
+	}
+
+	if res := probe(t, "http://"+addr+"/stream", "", ""); res.Status != domain.CameraStatusError {
+		t.Errorf("Probe(http URL) = %s, want error", res.Status)
+	}
+}
+
+// digestCamera requires Digest authentication with qop=auth for every
+// request. The first accepted DESCRIBE is answered with a stale nonce when
+// staleOnce is set.
+type digestCamera struct {
+	realm, nonce       string
+	username, password string
+	staleOnce          bool
+}
+
+func (d *digestCamera) handle(req *rtspRequest) string {
+	challenge := func(nonce string, stale bool) string {
+		return reply(req, "401 Unauthorized", "",
+			`WWW-Authenticate: Basic realm="`+d.realm+`"`,
+			fmt.Sprintf(`WWW-Authenticate: Digest realm="%s", nonce="%s", qop="auth", stale=%v`, d.realm, nonce, stale))
+	}
+	auth := req.header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Digest ") {
+		return challenge(d.nonce, false)
+	}
+	p := parseParams(strings.TrimPrefix(auth, "Digest "))
+	if p["nonce"] != d.nonce {
+		return challenge(d.nonce, true)
+	}
+	ha1 := md5hex(d.username + ":" + d.realm + ":" + d.password)
+	ha2 := md5hex(req.method + ":" + p["uri"])
+	want := md5hex(ha1 + ":" + d.nonce + ":" + p["nc"] + ":" + p["cnonce"] + ":auth:" + ha2)
+	if p["username"] != d.username || p["uri"] != req.uri || p["response"] != want {
+		return challenge(d.nonce, false)
+	}
+	if req.method == "OPTIONS" {
+		return reply(req, "200 OK", "", "Public: OPTIONS, DESCRIBE")
+	}
+	if d.staleOnce {
+		d.staleOnce = false
+		d.nonce = "rotated"
+		return challenge(d.nonce, true)
+	}
+	return reply(req, "200 OK", testSDP)
+}
+
+func TestProbeDigest(t *testing.T) {
+	defer func(f func() string) { cnonce = f }(cnonce)
+	cnonce = func() string { return "0a4f113b" }
+
+	tests := []struct {
+		name     string
+		url      string
+		username string
+		password string
+		stale    bool
+		want     string
+		methods  string
+	}{
+		{
+			// challenged once, then every request is authorised up front
+			name:     "accepted",
+			username: "admin", password: "s3cr@t",
+			want:    domain.CameraStatusOnline,
+			methods: "OPTIONS,OPTIONS,DESCRIBE",
+		},
+		{
+			name:    "credentials from the URL",
+			url:     "admin:s3cr%40t@",
+			want:    domain.CameraStatusOnline,
+			methods: "OPTIONS,OPTIONS,DESCRIBE",
+		},
+		{
+			name:     "arguments override the URL",
+			url:      "viewer:wrong@",
+			username: "admin", password: "s3cr@t",
+			want:    domain.CameraStatusOnline,
+			methods: "OPTIONS,OPTIONS,DESCRIBE",
+		},
+		{
+			name:     "stale nonce",
+			username: "admin", password: "s3cr@t",
+			stale:   true,
+			want:    domain.CameraStatusOnline,
+			methods: "OPTIONS,OPTIONS,DESCRIBE,DESCRIBE",
+		},
+		{
+			// rejected again after answering the challenge: no further retry
+			name:     "wrong password",
+			username: "admin", password: "guess",
+			want:    domain.CameraStatusAuthFailed,
+			methods: "OPTIONS,OPTIONS,DESCRIBE",
+		},
+		{
+			name:    "no credentials",
+			want:    domain.CameraStatusAuthFailed,
+			methods: "OPTIONS,DESCRIBE",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cam := &digestCamera{realm: "IP Camera", nonce: "dcd98b7102dd2f0e", username: "admin", password: "s3cr@t", staleOnce: tt.stale}
+			srv := newRTSPServer(t, cam.handle)
+			url := strings.Replace(srv.url("/stream"), "rtsp://", "rtsp://"+tt.url, 1)
+
+			res := probe(t, url, tt.username, tt.password)
+			if res.Status != tt.want {
+				t.Errorf("Probe() = %s, want %s; err %v", res.Status, tt.want, res.Err)
+			}
+			if got := strings.Join(srv.methods(), ","); got != tt.methods {
+				t.Errorf("requests = %s, want %s", got, tt.methods)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package rtsp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SessionDescription is the part of an SDP document (RFC 8866) that
+// describes what a camera streams.
+type SessionDescription struct {
+	Name  string
+	Media []Media
+}
+
+// Media is one m= section of an SDP document.
+type Media struct {
+	// Type is "video", "audio", "application", ...
+	Type     string
+	Port     int
+	Protocol string
+	Formats  []string
+	// Control is the a=control URL used to SETUP the track.
+	Control string
+	// Codec, ClockRate and Channels come from the a=rtpmap of the first
+	// format, e.g. H264/90000 or MPEG4-GENERIC/16000/1.
+	Codec     string
+	ClockRate int
+	Channels  int
+	// FormatParams holds the a=fmtp parameters of the first format.
+	FormatParams map[string]string
+	// FrameRate is the a=framerate attribute, 0 if absent.
+	FrameRate float64
+	// Bandwidth is the b=AS bandwidth in kbit/s, 0 if absent.
+	Bandwidth int
+}
+
+// Video returns the first video media, or nil.
+func (s *SessionDescription) Video() *Media {
+	return s.first("video")
+}
+
+// Audio returns the first audio media, or nil.
+func (s *SessionDescription) Audio() *Media {
+	return s.first("audio")
+}
+
+func (s *SessionDescription) first(typ string) *Media {
+	for i := range s.Media {
+		if s.Media[i].Type == typ {
+			return &s.Media[i]
+		}
+	}
+	return nil
+}
+
+// ParseSDP parses an SDP document. Unknown lines are ignored.
+func ParseSDP(data []byte) (*SessionDescription, error) {
+	sdp := &SessionDescription{}
+	var m *Media
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sawVersion := false
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if len(line) < 2 || line[1] != '=' {
+			continue
+		}
+		key, value := line[0], line[2:]
+
+		switch key {
+		case 'v':
+			sawVersion = true
+		case 's':
+			sdp.Name = value
+		case 'm':
+			fields := strings.Fields(value)
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("invalid media line %q", line)
+			}
+			port, _ := strconv.Atoi(strings.SplitN(fields[1], "/", 2)[0])
+			sdp.Media = append(sdp.Media, Media{
+				Type:     fields[0],
+				Port:     port,
+				Protocol: fields[2],
+				Formats:  fields[3:],
+			})
+			m = &sdp.Media[len(sdp.Media)-1]
+		case 'b':
+			if m != nil {
+				if kbps, ok := strings.CutPrefix(value, "AS:"); ok {
+					m.Bandwidth, _ = strconv.Atoi(strings.TrimSpace(kbps))
+				}
+			}
+		case 'a':
+			if m != nil {
+				m.parseAttribute(value)
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if !sawVersion {
+		return nil, fmt.Errorf("missing v= line")
+	}
+	return sdp, nil
+}
+
+func (m *Media) parseAttribute(attr string) {
+	name, value, _ := strings.Cut(attr, ":")
+	switch name {
+	case "control":
+		m.Control = value
+	case "framerate":
+		m.FrameRate, _ = strconv.ParseFloat(strings.TrimSpace(value), 64)
+	case "rtpmap":
+		pt, enc, ok := strings.Cut(value, " ")
+		if !ok || !m.isFirstFormat(pt) {
+			return
+		}
+		parts := strings.Split(strings.TrimSpace(enc), "/")
+		m.Codec = parts[0]
+		if len(parts) > 1 {
+			m.ClockRate, _ = strconv.Atoi(parts[1])
+		}
+		if len(parts) > 2 {
+			m.Channels, _ = strconv.Atoi(parts[2])
+		}
+	case "fmtp":
+		pt, params, ok := strings.Cut(value, " ")
+		if !ok || !m.isFirstFormat(pt) {
+			return
+		}
+		m.FormatParams = make(map[string]string)
+		for _, p := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if k != "" {
+				m.FormatParams[strings.ToLower(k)] = v
+			}
+		}
+	}
+}
+
+func (m *Media) isFirstFormat(pt string) bool {
+	return len(m.Formats) > 0 && m.Formats[0] == pt
+}
@@ -0,0 +1,127 @@
+package rtsp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSDP(t *testing.T) {
+	tests := []struct {
+		name    string
+		sdp     string
+		want    *SessionDescription
+		wantErr bool
+	}{
+		{
+			name: "camera with video and audio",
+			sdp: "v=0\r\n" +
+				"o=- 1700000000 1 IN IP4 192.168.1.64\r\n" +
+				"s=Media Presentation\r\n" +
+				"t=0 0\r\n" +
+				"a=control:*\r\n" +
+				"m=video 0 RTP/AVP 96\r\n" +
+				"b=AS:4096\r\n" +
+				"a=control:trackID=1\r\n" +
+				"a=framerate:25.0\r\n" +
+				"a=rtpmap:96 H264/90000\r\n" +
+				"a=fmtp:96 profile-level-id=640028; packetization-mode=1; sprop-parameter-sets=Z2QAKKzaAeAIn5YQAAADABAAAAMDLA==,aO48gA==\r\n" +
+				"m=audio 0 RTP/AVP 97\r\n" +
+				"a=control:trackID=2\r\n" +
+				"a=rtpmap:97 MPEG4-GENERIC/16000/1\r\n",
+			want: &SessionDescription{
+				Name: "Media Presentation",
+				Media: []Media{
+					{
+						Type: "video", Protocol: "RTP/AVP", Formats: []string{"96"},
+						Control: "trackID=1", Codec: "H264", ClockRate: 90000,
+						FormatParams: map[string]string{
+							"profile-level-id":     "640028",
+							"packetization-mode":   "1",
+							"sprop-parameter-sets": "Z2QAKKzaAeAIn5YQAAADABAAAAMDLA==,aO48gA==",
+						},
+						FrameRate: 25, Bandwidth: 4096,
+					},
+					{
+						Type: "audio", Protocol: "RTP/AVP", Formats: []string{"97"},
+						Control: "trackID=2", Codec: "MPEG4-GENERIC", ClockRate: 16000, Channels: 1,
+					},
+				},
+			},
+		},
+		{
+			name: "only the first format's rtpmap and fmtp",
+			sdp: "v=0\n" +
+				"s=-\n" +
+				"m=video 5000/2 RTP/AVP 98 96\n" +
+				"a=rtpmap:96 H264/90000\n" +
+				"a=fmtp:96 packetization-mode=1\n" +
+				"a=rtpmap:98 H265/90000\n" +
+				"a=fmtp:98 sprop-sps=QgEB\n",
+			want: &SessionDescription{
+				Name: "-",
+				Media: []Media{{
+					Type: "video", Port: 5000, Protocol: "RTP/AVP", Formats: []string{"98", "96"},
+					Codec: "H265", ClockRate: 90000,
+					FormatParams: map[string]string{"sprop-sps": "QgEB"},
+				}},
+			},
+		},
+		{
+			name: "session attributes and junk lines are ignored",
+			sdp:  "v=0\na=control:*\nb=AS:100\nnot a line\nx\n\nm=application 0 RTP/AVP 107\na=rtpmap:107 vnd.onvif.metadata/90000\n",
+			want: &SessionDescription{
+				Media: []Media{{
+					Type: "application", Protocol: "RTP/AVP", Formats: []string{"107"},
+					Codec: "vnd.onvif.metadata", ClockRate: 90000,
+				}},
+			},
+		},
+		{name: "missing version", sdp: "s=x\nm=video 0 RTP/AVP 96\n", wantErr: true},
+		{name: "invalid media line", sdp: "v=0\nm=video 0\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSDP([]byte(tt.sdp))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseSDP() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSDP: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSDP() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionDescriptionTracks(t *testing.T) {
+	sdp, err := ParseSDP([]byte(strings.Join([]string{
+		"v=0",
+		"m=application 0 RTP/AVP 107",
+		"m=audio 0 RTP/AVP 0",
+		"m=video 0 RTP/AVP 96",
+		"m=video 0 RTP/AVP 97",
+	}, "\n")))
+	if err != nil {
+		t.Fatalf("ParseSDP: %v", err)
+	}
+	if v := sdp.Video(); v == nil || v.Formats[0] != "96" {
+		t.Errorf("Video() = %+v, want the first video media", v)
+	}
+	if a := sdp.Audio(); a == nil || a.Formats[0] != "0" {
+		t.Errorf("Audio() = %+v, want the audio media", a)
+	}
+
+	sdp, err = ParseSDP([]byte("v=0\nm=video 0 RTP/AVP 96\n"))
+	if err != nil {
+		t.Fatalf("ParseSDP: %v", err)
+	}
+	if a := sdp.Audio(); a != nil {
+		t.Errorf("Audio() = %+v, want nil", a)
+	}
+}
@@ -0,0 +1,336 @@
+package rtsp
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/boytur/cctv-recording-center/server/internal/mp4"
+)
+
+// VideoParams is what can be learned about a video stream from its sequence
+// parameter set without decoding any frames.
+type VideoParams struct {
+	Profile   string
+	Width     int
+	Height    int
+	FrameRate float64
+}
+
+// VideoParams extracts the profile, resolution and, when the encoder signals
+// it, the frame rate from the parameter sets in the fmtp line. ok is false
+// if the SDP carries no usable parameter set.
+func (m *Media) VideoParams() (VideoParams, bool) {
+	switch strings.ToUpper(m.Codec) {
+	case "H264":
+		sets := strings.Split(m.FormatParams["sprop-parameter-sets"], ",")
+		if sps := decodeParamSet(sets[0]); sps != nil {
+			if p, err := parseAVCSPS(sps); err == nil {
+				return p, true
+			}
+		}
+		// profile-level-id still names the profile without an SPS
+		if b, err := hex.DecodeString(m.FormatParams["profile-level-id"]); err == nil && len(b) == 3 {
+			return VideoParams{Profile: mp4.AVCProfile(b[0])}, true
+		}
+	case "H265", "HEVC":
+		if sps := decodeParamSet(m.FormatParams["sprop-sps"]); sps != nil {
+			if p, err := parseHEVCSPS(sps); err == nil {
+				return p, true
+			}
+		}
+	}
+	return VideoParams{}, false
+}
+
+func decodeParamSet(s string) []byte {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		if b, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "=")); err != nil {
+			return nil
+		}
+	}
+	return b
+}
+
+var errShortSPS = errors.New("truncated sequence parameter set")
+
+// parseAVCSPS parses an H.264 SPS NAL unit (ITU-T H.264 7.3.2.1.1).
+func parseAVCSPS(nal []byte) (VideoParams, error) {
+	if len(nal) < 4 || nal[0]&0x1f != 7 {
+		return VideoParams{}, errors.New("not an H.264 SPS")
+	}
+	r := &bitReader{data: unescapeRBSP(nal[1:])}
+	profileIdc := uint8(r.bits(8))
+	r.skip(16) // constraint flags and level_idc
+	r.ue()     // seq_parameter_set_id
+
+	chromaFormat := uint(1)
+	separateColourPlane := false
+	switch profileIdc {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		chromaFormat = r.ue()
+		if chromaFormat == 3 {
+			separateColourPlane = r.flag()
+		}
+		r.ue() // bit_depth_luma_minus8
+		r.ue() // bit_depth_chroma_minus8
+		r.skip(1)
+		if r.flag() { // seq_scaling_matrix_present_flag
+			n := 8
+			if chromaFormat == 3 {
+				n = 12
+			}
+			for i := 0; i < n; i++ {
+				if r.flag() {
+					size := 16
+					if i >= 6 {
+						size = 64
+					}
+					skipScalingList(r, size)
+				}
+			}
+		}
+	}
+	r.ue() // log2_max_frame_num_minus4
+
+	// pic_order_cnt_type
+	switch r.ue() {
+	case 0:
+		r.ue()
+	case 1:
+		r.skip(1)
+		r.se()
+		r.se()
+		n := r.ue()
+		for i := uint(0); i < n && r.err == nil; i++ {
+			r.se()
+		}
+	}
+	r.ue()    // max_num_ref_frames
+	r.skip(1) // gaps_in_frame_num_value_allowed_flag
+	widthMbs := r.ue() + 1
+	heightMapUnits := r.ue() + 1
+	frameMbsOnly := r.flag()
+	if !frameMbsOnly {
+		r.skip(1) // mb_adaptive_frame_field_flag
+	}
+	r.skip(1) // direct_8x8_inference_flag
+
+	width := int(widthMbs * 16)
+	height := int(heightMapUnits * 16)
+	if !frameMbsOnly {
+		height *= 2
+	}
+	if r.flag() { // frame_cropping_flag
+		left, right, top, bottom := r.ue(), r.ue(), r.ue(), r.ue()
+		cropX, cropY := 1, 1
+		if !separateColourPlane && chromaFormat != 0 {
+			if chromaFormat == 1 || chromaFormat == 2 {
+				cropX = 2
+			}
+			if chromaFormat == 1 {
+				cropY = 2
+			}
+		}
+		if !frameMbsOnly {
+			cropY *= 2
+		}
+		width -= (int(left) + int(right)) * cropX
+		height -= (int(top) + int(bottom)) * cropY
+	}
+	if r.err != nil {
+		return VideoParams{}, r.err
+	}
+
+	p := VideoParams{Profile: mp4.AVCProfile(profileIdc), Width: width, Height: height}
+	if r.flag() { // vui_parameters_present_flag
+		p.FrameRate = avcVUIFrameRate(r)
+	}
+	return p, nil
+}
+
+// avcVUIFrameRate reads the VUI up to the timing info. Most cameras signal
+// num_units_in_tick and time_scale with two ticks per frame.
+func avcVUIFrameRate(r *bitReader) float64 {
+	if r.flag() { // aspect_ratio_info_present_flag
+		if r.bits(8) == 255 { // Extended_SAR
+			r.skip(32)
+		}
+	}
+	if r.flag() { // overscan_info_present_flag
+		r.skip(1)
+	}
+	if r.flag() { // video_signal_type_present_flag
+		r.skip(4)
+		if r.flag() { // colour_description_present_flag
+			r.skip(24)
+		}
+	}
+	if r.flag() { // chroma_loc_info_present_flag
+		r.ue()
+		r.ue()
+	}
+	if !r.flag() { // timing_info_present_flag
+		return 0
+	}
+	units := r.bits(32)
+	scale := r.bits(32)
+	if r.err != nil || units == 0 {
+		return 0
+	}
+	return roundRate(float64(scale) / float64(2*units))
+}
+
+func skipScalingList(r *bitReader, size int) {
+	last, next := 8, 8
+	for j := 0; j < size && r.err == nil; j++ {
+		if next != 0 {
+			next = (last + r.se() + 256) % 256
+		}
+		if next != 0 {
+			last = next
+		}
+	}
+}
+
+// parseHEVCSPS parses the start of an H.265 SPS NAL unit (ITU-T H.265
+// 7.3.2.2) up to the conformance window.
+func parseHEVCSPS(nal []byte) (VideoParams, error) {
+	if len(nal) < 3 || (nal[0]>>1)&0x3f != 33 {
+		return VideoParams{}, errors.New("not an H.265 SPS")
+	}
+	r := &bitReader{data: unescapeRBSP(nal[2:])}
+	r.skip(4) // sps_video_parameter_set_id
+	maxSubLayers := int(r.bits(3))
+	r.skip(1) // sps_temporal_id_nesting_flag
+
+	// profile_tier_level
+	r.skip(3) // general_profile_space, general_tier_flag
+	profileIdc := uint8(r.bits(5))
+	r.skip(32 + 48 + 8) // compatibility flags, constraint flags, level
+	subProfile := make([]bool, maxSubLayers)
+	subLevel := make([]bool, maxSubLayers)
+	for i := 0; i < maxSubLayers; i++ {
+		subProfile[i] = r.flag()
+		subLevel[i] = r.flag()
+	}
+	if maxSubLayers > 0 {
+		r.skip(2 * (8 - maxSubLayers))
+	}
+	for i := 0; i < maxSubLayers; i++ {
+		if subProfile[i] {
+			r.skip(88)
+		}
+		if subLevel[i] {
+			r.skip(8)
+		}
+	}
+
+	r.ue() // sps_seq_parameter_set_id
+	chromaFormat := r.ue()
+	if chromaFormat == 3 {
+		r.skip(1) // separate_colour_plane_flag
+	}
+	width := int(r.ue())
+	height := int(r.ue())
+	if r.flag() { // conformance_window_flag
+		left, right, top, bottom := r.ue(), r.ue(), r.ue(), r.ue()
+		subW, subH := 1, 1
+		if chromaFormat == 1 || chromaFormat == 2 {
+			subW = 2
+		}
+		if chromaFormat == 1 {
+			subH = 2
+		}
+		width -= (int(left) + int(right)) * subW
+		height -= (int(top) + int(bottom)) * subH
+	}
+	if r.err != nil {
+		return VideoParams{}, r.err
+	}
+	return VideoParams{Profile: mp4.HEVCProfile(profileIdc), Width: width, Height: height}, nil
+}
+
+// unescapeRBSP removes emulation prevention bytes (00 00 03 -> 00 00).
+func unescapeRBSP(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	zeros := 0
+	for _, c := range b {
+		if zeros >= 2 && c == 3 {
+			zeros = 0
+			continue
+		}
+		out = append(out, c)
+		if c == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return out
+}
+
+func roundRate(f float64) float64 {
+	return float64(int(f*100+0.5)) / 100
+}
+
+// bitReader reads big-endian bits and Exp-Golomb codes. Reading past the
+// end sets err and returns zeros.
+type bitReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *bitReader) bits(n int) uint {
+	var v uint
+	for i := 0; i < n; i++ {
+		if r.pos >= len(r.data)*8 {
+			r.err = errShortSPS
+			return 0
+		}
+		bit := (r.data[r.pos/8] >> (7 - uint(r.pos%8))) & 1
+		v = v<<1 | uint(bit)
+		r.pos++
+	}
+	return v
+}
+
+func (r *bitReader) skip(n int) {
+	r.pos += n
+	if r.pos > len(r.data)*8 {
+		r.err = errShortSPS
+	}
+}
+
+func (r *bitReader) flag() bool {
+	return r.bits(1) == 1
+}
+
+// ue reads an unsigned Exp-Golomb code.
+func (r *bitReader) ue() uint {
+	zeros := 0
+	for r.bits(1) == 0 {
+		if r.err != nil || zeros > 31 {
+			r.err = errShortSPS
+			return 0
+		}
+		zeros++
+	}
+	return (1<<uint(zeros) - 1) + r.bits(zeros)
+}
+
+// se reads a signed Exp-Golomb code.
+func (r *bitReader) se() int {
+	v := r.ue()
+	if v%2 == 1 {
+		return int(v/2) + 1
+	}
+	return -int(v / 2)
+}
@@ -0,0 +1,127 @@
+package rtsp
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("bad hex %q: %v", s, err)
+	}
+	return b
+}
+
+func TestParseAVCSPS(t *testing.T) {
+	tests := []struct {
+		name    string
+		sps     string
+		want    VideoParams
+		wantErr bool
+	}{
+		{
+			// cropped from 1088, emulation prevention bytes in the timing info
+			name: "High 1080p25",
+			sps:  "67640028acda01e0089f96100000030010000003032c",
+			want: VideoParams{Profile: "High", Width: 1920, Height: 1080, FrameRate: 25},
+		},
+		{
+			name: "Main 720p29.97",
+			sps:  "674d001fed00a00b742000007d20001d4c18",
+			want: VideoParams{Profile: "Main", Width: 1280, Height: 720, FrameRate: 29.97},
+		},
+		{
+			// field coded, no VUI
+			name: "Baseline 720x576 interlaced",
+			sps:  "6742001eed01684890",
+			want: VideoParams{Profile: "Baseline", Width: 720, Height: 576},
+		},
+		{name: "not an SPS", sps: "68ee3c80", wantErr: true},
+		{name: "truncated", sps: "67640028acda01", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAVCSPS(mustHex(t, tt.sps))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseAVCSPS() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAVCSPS: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseAVCSPS() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHEVCSPS(t *testing.T) {
+	got, err := parseHEVCSPS(mustHex(t, "42010101600000030000030000030000030078a003c0801107cb96"))
+	if err != nil {
+		t.Fatalf("parseHEVCSPS: %v", err)
+	}
+	if want := (VideoParams{Profile: "Main", Width: 1920, Height: 1080}); got != want {
+		t.Errorf("parseHEVCSPS() = %+v, want %+v", got, want)
+	}
+
+	if _, err := parseHEVCSPS(mustHex(t, "67640028acda")); err == nil {
+		t.Error("parseHEVCSPS accepted an H.264 SPS")
+	}
+}
+
+func TestMediaVideoParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		media  Media
+		want   VideoParams
+		wantOK bool
+	}{
+		{
+			name: "H.264 sprop-parameter-sets",
+			media: Media{Codec: "H264", FormatParams: map[string]string{
+				"sprop-parameter-sets": "Z2QAKKzaAeAIn5YQAAADABAAAAMDLA==,aO48gA==",
+			}},
+			want:   VideoParams{Profile: "High", Width: 1920, Height: 1080, FrameRate: 25},
+			wantOK: true,
+		},
+		{
+			name: "unpadded base64",
+			media: Media{Codec: "h264", FormatParams: map[string]string{
+				"sprop-parameter-sets": "Z00AH+0AoAt0IAAAfSAAHUwY",
+			}},
+			want:   VideoParams{Profile: "Main", Width: 1280, Height: 720, FrameRate: 29.97},
+			wantOK: true,
+		},
+		{
+			name: "H.264 profile-level-id only",
+			media: Media{Codec: "H264", FormatParams: map[string]string{
+				"profile-level-id": "4d001f",
+			}},
+			want:   VideoParams{Profile: "Main"},
+			wantOK: true,
+		},
+		{
+			name: "H.265 sprop-sps",
+			media: Media{Codec: "H265", FormatParams: map[string]string{
+				"sprop-sps": "QgEBAWAAAAMAAAMAAAMAAAMAeKADwIARB8uW",
+			}},
+			want:   VideoParams{Profile: "Main", Width: 1920, Height: 1080},
+			wantOK: true,
+		},
+		{name: "H.264 without parameters", media: Media{Codec: "H264"}},
+		{name: "MJPEG", media: Media{Codec: "JPEG"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.media.VideoParams()
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("VideoParams() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
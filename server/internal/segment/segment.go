@@ -0,0 +1,410 @@
+package segment
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/mp4"
+)
+
+// Kind distinguishes continuous recorder output from manual captures.
+type Kind string
+
+const (
+	KindContinuous Kind = "continuous"
+	KindCapture    Kind = "capture"
+)
+
+const fileTimeLayout = "20060102_150405"
+
+// Segment is a single recorded MP4 file with its real time extent.
+type Segment struct {
+	CameraID string
+	Name     string
+	Path     string
+	Kind     Kind
+	Start    time.Time
+	End      time.Time
+	Size     int64
+	// Probed is false when the duration could not be read from the file and
+	// End was estimated from the modification time instead.
+	Probed bool
+	// Media is the parsed fragment structure, or nil when the file could
+	// only be inspected with ffprobe.
+	Media *mp4.File
+}
+
+// Duration returns the length of the segment.
+func (s Segment) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// URL returns the public URL the file is served under.
+func (s Segment) URL() string {
+	rel := strings.TrimPrefix(filepath.ToSlash(s.Path), "data/")
+	return "/" + rel
+}
+
+const (
+	// maxCachedProbes bounds how many files' durations are remembered.
+	maxCachedProbes = 50000
+	// maxCachedMedia bounds how many parsed fragment structures are kept;
+	// older ones are parsed again when needed.
+	maxCachedMedia = 512
+	// sweepInterval is how often cached files are checked for deletion.
+	sweepInterval = 10 * time.Minute
+)
+
+// Index lists recorded segments on disk and remembers what it read from
+// them so finished files are only probed once.
+type Index struct {
+	root      string
+	mu        sync.Mutex
+	probes    *lru[probeResult]
+	media     *lru[*mp4.File]
+	lastSweep time.Time
+}
+
+type probeResult struct {
+	size     int64
+	modTime  time.Time
+	duration time.Duration
+	// parsed is true when the pure-Go parser read the file, so its media
+	// structure can be parsed again after being evicted.
+	parsed bool
+}
+
+var defaultIndex = NewIndex(filepath.Join("data", "recordings"))
+
+// NewIndex creates an index over recordings stored under root.
+func NewIndex(root string) *Index {
+	return &Index{root: root, probes: newLRU[probeResult](maxCachedProbes), media: newLRU[*mp4.File](maxCachedMedia), lastSweep: time.Now()}
+}
+
+// List returns the segments recorded for a camera on the given day using the
+// default index.
+func List(cameraID string, day time.Time, kinds ...Kind) ([]Segment, error) {
+	return defaultIndex.List(cameraID, day, kinds...)
+}
+
+// List returns the segments of the requested kinds (all kinds if none are
+// given) recorded for a camera on the given day, sorted by start time.
+func (ix *Index) List(cameraID string, day time.Time, kinds ...Kind) ([]Segment, error) {
+	dir := filepath.Join(ix.root, cameraID, day.Format("2006-01-02"))
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []Segment{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	segs := []Segment{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		kind, start, ok := parseName(name)
+		if !ok || !wantKind(kind, kinds) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || info.Size() == 0 {
+			// Skip files that are 0 bytes (corrupted or still being written)
+			continue
+		}
+		seg := Segment{
+			CameraID: cameraID,
+			Name:     name,
+			Path:     filepath.Join(dir, name),
+			Kind:     kind,
+			Start:    start,
+			Size:     info.Size(),
+		}
+		if media, d, ok := ix.inspect(seg.Path, info); ok {
+			seg.End = start.Add(d)
+			seg.Media = media
+			seg.Probed = true
+		} else {
+			seg.End = info.ModTime()
+			if seg.End.Before(start) {
+				seg.End = start
+			}
+		}
+		segs = append(segs, seg)
+	}
+
+	sort.Slice(segs, func(i, j int) bool { return segs[i].Start.Before(segs[j].Start) })
+	return segs, nil
+}
+
+// Range returns the segments of the requested kinds that overlap [from, to)
+// using the default index.
+func Range(cameraID string, from, to time.Time, kinds ...Kind) ([]Segment, error) {
+	return defaultIndex.Range(cameraID, from, to, kinds...)
+}
+
+// Range returns the segments of the requested kinds that overlap [from, to),
+// across as many day directories as the window spans. The day before from is
+// included because a segment started before midnight is stored under it.
+func (ix *Index) Range(cameraID string, from, to time.Time, kinds ...Kind) ([]Segment, error) {
+	out := []Segment{}
+	local := from.In(time.Local)
+	first := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, -1)
+	for day := first; day.Before(to); day = day.AddDate(0, 0, 1) {
+		segs, err := ix.List(cameraID, day, kinds...)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range segs {
+			if s.End.After(from) && s.Start.Before(to) {
+				out = append(out, s)
+			}
+		}
+	}
+	return out, nil
+}
+
+// Days returns the dates that have a recordings directory for the camera,
+// oldest first, using the default index.
+func Days(cameraID string) ([]time.Time, error) {
+	return defaultIndex.Days(cameraID)
+}
+
+// Days returns the dates that have a recordings directory for the camera,
+// oldest first.
+func (ix *Index) Days(cameraID string) ([]time.Time, error) {
+	entries, err := os.ReadDir(filepath.Join(ix.root, cameraID))
+	if os.IsNotExist(err) {
+		return []time.Time{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	days := []time.Time{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		day, err := time.ParseInLocation("2006-01-02", e.Name(), time.Local)
+		if err != nil {
+			continue
+		}
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days, nil
+}
+
+// Usage returns the bytes stored for a camera using the default index.
+func Usage(cameraID string) (int64, error) {
+	return defaultIndex.Usage(cameraID)
+}
+
+// Usage returns the total size of every file stored for a camera, including
+// logs and files that are not segments.
+func (ix *Index) Usage(cameraID string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(filepath.Join(ix.root, cameraID), func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		total += info.Size()
+		return nil
+	})
+	return total, err
+}
+
+// Archive moves a camera's recordings out of the default index into dir.
+func Archive(cameraID, dir string) (string, error) {
+	return defaultIndex.Archive(cameraID, dir)
+}
+
+// Archive moves everything stored for a camera into a directory under dir
+// named after the camera and the current time, and returns its path. It
+// returns "" if the camera has no recordings.
+func (ix *Index) Archive(cameraID, dir string) (string, error) {
+	src := filepath.Join(ix.root, cameraID)
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return "", nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	dst := filepath.Join(dir, cameraID+"_"+time.Now().Format("20060102-150405"))
+	if err := os.Rename(src, dst); err != nil {
+		return "", err
+	}
+	ix.forget(src)
+	return dst, nil
+}
+
+// Purge deletes a camera's recordings from the default index.
+func Purge(cameraID string) error {
+	return defaultIndex.Purge(cameraID)
+}
+
+// Purge deletes everything stored for a camera.
+func (ix *Index) Purge(cameraID string) error {
+	dir := filepath.Join(ix.root, cameraID)
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	ix.forget(dir)
+	return nil
+}
+
+// forget drops cached results for files under dir.
+func (ix *Index) forget(dir string) {
+	prefix := dir + string(filepath.Separator)
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for _, path := range ix.probes.keys() {
+		if strings.HasPrefix(path, prefix) {
+			ix.probes.remove(path)
+			ix.media.remove(path)
+		}
+	}
+}
+
+// sweep drops cached results for files that no longer exist, e.g. removed
+// by retention, at most once per sweepInterval.
+func (ix *Index) sweep() {
+	ix.mu.Lock()
+	if time.Since(ix.lastSweep) < sweepInterval {
+		ix.mu.Unlock()
+		return
+	}
+	ix.lastSweep = time.Now()
+	paths := ix.probes.keys()
+	ix.mu.Unlock()
+
+	var gone []string
+	for _, path := range paths {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			gone = append(gone, path)
+		}
+	}
+	ix.mu.Lock()
+	for _, path := range gone {
+		ix.probes.remove(path)
+		ix.media.remove(path)
+	}
+	ix.mu.Unlock()
+}
+
+func wantKind(k Kind, kinds []Kind) bool {
+	if len(kinds) == 0 {
+		return true
+	}
+	for _, want := range kinds {
+		if k == want {
+			return true
+		}
+	}
+	return false
+}
+
+// parseName extracts the kind and wall-clock start time from a recorder file
+// name such as rec_20250101_120000.mp4 or capture_20250101_120000.mp4.
+func parseName(name string) (Kind, time.Time, bool) {
+	if !strings.HasSuffix(strings.ToLower(name), ".mp4") {
+		return "", time.Time{}, false
+	}
+	var kind Kind
+	var rest string
+	switch {
+	case strings.HasPrefix(name, "rec_"):
+		kind, rest = KindContinuous, name[len("rec_"):]
+	case strings.HasPrefix(name, "capture_"):
+		kind, rest = KindCapture, name[len("capture_"):]
+	default:
+		return "", time.Time{}, false
+	}
+	if len(rest) < len(fileTimeLayout) {
+		return "", time.Time{}, false
+	}
+	// ffmpeg's strftime writes local time
+	t, err := time.ParseInLocation(fileTimeLayout, rest[:len(fileTimeLayout)], time.Local)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return kind, t, true
+}
+
+// inspect returns the media structure and duration of path, using the cache
+// when the file hasn't changed since it was last read. The pure-Go MP4 parser
+// is tried first; ffprobe is only spawned for files it can't read.
+func (ix *Index) inspect(path string, info os.FileInfo) (*mp4.File, time.Duration, bool) {
+	ix.sweep()
+	ix.mu.Lock()
+	cached, ok := ix.probes.get(path)
+	ok = ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime())
+	var media *mp4.File
+	if ok {
+		media, _ = ix.media.get(path)
+	}
+	ix.mu.Unlock()
+	if ok && (media != nil || !cached.parsed) {
+		return media, cached.duration, true
+	}
+
+	var d time.Duration
+	media, err := mp4.ParseFile(path)
+	if err == nil && media.Duration > 0 {
+		d = media.Duration
+	} else {
+		media = nil
+		if d, err = probeDuration(path); err != nil {
+			return nil, 0, false
+		}
+	}
+	ix.mu.Lock()
+	ix.probes.put(path, probeResult{size: info.Size(), modTime: info.ModTime(), duration: d, parsed: media != nil})
+	if media != nil {
+		ix.media.put(path, media)
+	} else {
+		ix.media.remove(path)
+	}
+	ix.mu.Unlock()
+	return media, d, true
+}
+
+// probeDuration asks ffprobe for the container duration of a media file.
+func probeDuration(path string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		path,
+	).Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe %s: %w", path, err)
+	}
+	secs, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil || secs <= 0 {
+		return 0, fmt.Errorf("ffprobe %s: no duration", path)
+	}
+	return time.Duration(secs * float64(time.Second)), nil
+}
@@ -0,0 +1,96 @@
+package segment
+
+import "time"
+
+// Span is a continuous stretch of time.
+type Span struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Duration returns the length of the span.
+func (s Span) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Bucket summarises how much of a fixed-size time slot is covered by recordings.
+type Bucket struct {
+	Start   time.Time
+	End     time.Time
+	Covered time.Duration
+}
+
+// Merge joins segments into continuous spans. Segments separated by no more
+// than tolerance are treated as continuous, which absorbs the small gap
+// ffmpeg leaves between consecutive segment files. Segments must be sorted
+// by start time.
+func Merge(segs []Segment, tolerance time.Duration) []Span {
+	spans := []Span{}
+	for _, s := range segs {
+		if n := len(spans); n > 0 && !s.Start.After(spans[n-1].End.Add(tolerance)) {
+			if s.End.After(spans[n-1].End) {
+				spans[n-1].End = s.End
+			}
+			continue
+		}
+		spans = append(spans, Span{Start: s.Start, End: s.End})
+	}
+	return spans
+}
+
+// Clip limits spans to the [from, to) window, dropping those outside it.
+func Clip(spans []Span, from, to time.Time) []Span {
+	out := []Span{}
+	for _, s := range spans {
+		if !s.End.After(from) || !s.Start.Before(to) {
+			continue
+		}
+		if s.Start.Before(from) {
+			s.Start = from
+		}
+		if s.End.After(to) {
+			s.End = to
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// Gaps returns the uncovered parts of [from, to) given sorted, merged spans.
+func Gaps(spans []Span, from, to time.Time) []Span {
+	gaps := []Span{}
+	cursor := from
+	for _, s := range Clip(spans, from, to) {
+		if s.Start.After(cursor) {
+			gaps = append(gaps, Span{Start: cursor, End: s.Start})
+		}
+		if s.End.After(cursor) {
+			cursor = s.End
+		}
+	}
+	if cursor.Before(to) {
+		gaps = append(gaps, Span{Start: cursor, End: to})
+	}
+	return gaps
+}
+
+// Buckets slices [from, to) into fixed-size slots and reports how much of
+// each slot is covered by the given spans.
+func Buckets(spans []Span, from, to time.Time, size time.Duration) []Bucket {
+	buckets := []Bucket{}
+	if size <= 0 {
+		return buckets
+	}
+	for start := from; start.Before(to); start = start.Add(size) {
+		end := start.Add(size)
+		if end.After(to) {
+			end = to
+		}
+		var covered time.Duration
+		for _, s := range Clip(spans, start, end) {
+			covered += s.Duration()
+		}
+		buckets = append(buckets, Bucket{Start: start, End: end, Covered: covered})
+	}
+	return buckets
+}
@@ -0,0 +1,183 @@
+package segment
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var day = time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+// at is a time d into the test day.
+func at(d time.Duration) time.Time { return day.Add(d) }
+
+func sp(from, to time.Duration) Span { return Span{Start: at(from), End: at(to)} }
+
+func seg(from, to time.Duration) Segment { return Segment{Start: at(from), End: at(to)} }
+
+const (
+	second = time.Second
+	minute = time.Minute
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name      string
+		segs      []Segment
+		tolerance time.Duration
+		want      []Span
+	}{
+		{"empty", nil, 2 * second, []Span{}},
+		{"single", []Segment{seg(0, minute)}, 2 * second, []Span{sp(0, minute)}},
+		{"touching", []Segment{seg(0, minute), seg(minute, 2*minute)}, 0, []Span{sp(0, 2*minute)}},
+		{
+			"gap within tolerance",
+			[]Segment{seg(0, minute), seg(minute+second, 2*minute), seg(2*minute+1500*time.Millisecond, 3*minute)},
+			2 * second,
+			[]Span{sp(0, 3*minute)},
+		},
+		{"gap equal to tolerance", []Segment{seg(0, minute), seg(minute+2*second, 2*minute)}, 2 * second, []Span{sp(0, 2*minute)}},
+		{
+			"gap beyond tolerance",
+			[]Segment{seg(0, minute), seg(minute+3*second, 2*minute)},
+			2 * second,
+			[]Span{sp(0, minute), sp(minute+3*second, 2*minute)},
+		},
+		{"gap without tolerance", []Segment{seg(0, minute), seg(minute+second, 2*minute)}, 0, []Span{sp(0, minute), sp(minute+second, 2*minute)}},
+		{"overlapping", []Segment{seg(0, 2*minute), seg(minute, 3*minute)}, 0, []Span{sp(0, 3*minute)}},
+		{"contained", []Segment{seg(0, 3*minute), seg(minute, 2*minute), seg(3*minute+second, 4*minute)}, 2 * second, []Span{sp(0, 4*minute)}},
+		{
+			"several spans",
+			[]Segment{seg(0, minute), seg(minute, 2*minute), seg(10*minute, 11*minute), seg(20*minute, 21*minute), seg(21*minute+second, 22*minute)},
+			2 * second,
+			[]Span{sp(0, 2*minute), sp(10*minute, 11*minute), sp(20*minute, 22*minute)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Merge(tt.segs, tt.tolerance); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Merge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClip(t *testing.T) {
+	from, to := 10*minute, 20*minute
+	tests := []struct {
+		name  string
+		spans []Span
+		want  []Span
+	}{
+		{"empty", nil, []Span{}},
+		{"inside", []Span{sp(12*minute, 15*minute)}, []Span{sp(12*minute, 15*minute)}},
+		{"before and after", []Span{sp(0, 5*minute), sp(25*minute, 30*minute)}, []Span{}},
+		{"ending at from", []Span{sp(5*minute, 10*minute)}, []Span{}},
+		{"starting at to", []Span{sp(20*minute, 25*minute)}, []Span{}},
+		{"starting before from", []Span{sp(5*minute, 12*minute)}, []Span{sp(10*minute, 12*minute)}},
+		{"ending after to", []Span{sp(18*minute, 25*minute)}, []Span{sp(18*minute, 20*minute)}},
+		{"covering the window", []Span{sp(0, 30*minute)}, []Span{sp(10*minute, 20*minute)}},
+		{
+			"mixed",
+			[]Span{sp(0, 11*minute), sp(13*minute, 14*minute), sp(19*minute, 21*minute), sp(22*minute, 23*minute)},
+			[]Span{sp(10*minute, 11*minute), sp(13*minute, 14*minute), sp(19*minute, 20*minute)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Clip(tt.spans, at(from), at(to)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Clip() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGaps(t *testing.T) {
+	tests := []struct {
+		name     string
+		spans    []Span
+		from, to time.Duration
+		want     []Span
+	}{
+		{"no recordings", nil, 10 * minute, 20 * minute, []Span{sp(10*minute, 20*minute)}},
+		{"fully covered", []Span{sp(0, 30*minute)}, 10 * minute, 20 * minute, []Span{}},
+		{"exactly covered", []Span{sp(10*minute, 20*minute)}, 10 * minute, 20 * minute, []Span{}},
+		{"starting inside a span", []Span{sp(5*minute, 12*minute)}, 10 * minute, 20 * minute, []Span{sp(12*minute, 20*minute)}},
+		{"ending inside a span", []Span{sp(18*minute, 25*minute)}, 10 * minute, 20 * minute, []Span{sp(10*minute, 18*minute)}},
+		{
+			"between spans",
+			[]Span{sp(5*minute, 12*minute), sp(14*minute, 15*minute), sp(18*minute, 25*minute)},
+			10 * minute, 20 * minute,
+			[]Span{sp(12*minute, 14*minute), sp(15*minute, 18*minute)},
+		},
+		{
+			"overlapping spans",
+			[]Span{sp(11*minute, 15*minute), sp(12*minute, 13*minute), sp(14*minute, 16*minute)},
+			10 * minute, 20 * minute,
+			[]Span{sp(10*minute, 11*minute), sp(16*minute, 20*minute)},
+		},
+		{"spans outside the range", []Span{sp(0, 5*minute), sp(25*minute, 30*minute)}, 10 * minute, 20 * minute, []Span{sp(10*minute, 20*minute)}},
+		{"empty range", []Span{sp(0, 5*minute)}, 10 * minute, 10 * minute, []Span{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Gaps(tt.spans, at(tt.from), at(tt.to)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Gaps() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuckets(t *testing.T) {
+	bucket := func(from, to, covered time.Duration) Bucket {
+		return Bucket{Start: at(from), End: at(to), Covered: covered}
+	}
+	tests := []struct {
+		name     string
+		spans    []Span
+		from, to time.Duration
+		size     time.Duration
+		want     []Bucket
+	}{
+		{"no size", []Span{sp(0, minute)}, 0, time.Hour, 0, []Bucket{}},
+		{"negative size", []Span{sp(0, minute)}, 0, time.Hour, -minute, []Bucket{}},
+		{"empty range", []Span{sp(0, minute)}, time.Hour, time.Hour, 10 * minute, []Bucket{}},
+		{
+			"no recordings",
+			nil, 0, 20 * minute, 10 * minute,
+			[]Bucket{bucket(0, 10*minute, 0), bucket(10*minute, 20*minute, 0)},
+		},
+		{
+			"span across a bucket edge",
+			[]Span{sp(8*minute, 13*minute)}, 0, 20 * minute, 10 * minute,
+			[]Bucket{bucket(0, 10*minute, 2*minute), bucket(10*minute, 20*minute, 3*minute)},
+		},
+		{
+			"span ending on a bucket edge",
+			[]Span{sp(5*minute, 10*minute)}, 0, 20 * minute, 10 * minute,
+			[]Bucket{bucket(0, 10*minute, 5*minute), bucket(10*minute, 20*minute, 0)},
+		},
+		{
+			"several spans in a bucket",
+			[]Span{sp(0, minute), sp(2*minute, 4*minute), sp(9*minute, 11*minute)}, 0, 20 * minute, 10 * minute,
+			[]Bucket{bucket(0, 10*minute, 4*minute), bucket(10*minute, 20*minute, minute)},
+		},
+		{
+			"last bucket cut short",
+			[]Span{sp(0, 25*minute)}, 0, 25 * minute, 10 * minute,
+			[]Bucket{bucket(0, 10*minute, 10*minute), bucket(10*minute, 20*minute, 10*minute), bucket(20*minute, 25*minute, 5*minute)},
+		},
+		{
+			"spans outside the range",
+			[]Span{sp(0, 10*minute), sp(30*minute, 40*minute)}, 10 * minute, 30 * minute, 10 * minute,
+			[]Bucket{bucket(10*minute, 20*minute, 0), bucket(20*minute, 30*minute, 0)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Buckets(tt.spans, at(tt.from), at(tt.to), tt.size); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Buckets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/recorder"
+	"github.com/boytur/cctv-recording-center/server/internal/segment"
+	"github.com/boytur/cctv-recording-center/server/internal/stream"
+)
+
+// Source names where a snapshot frame was taken from.
+const (
+	SourceHLS      = "hls"
+	SourceRecorder = "recorder"
+	SourceRTSP     = "rtsp"
+	SourceArchive  = "archive"
+)
+
+// archiveLookback is how far back Latest looks for a recorded segment.
+const archiveLookback = 24 * time.Hour
+
+// Snapshot is a single JPEG frame grabbed from a camera.
+type Snapshot struct {
+	CameraID string
+	Data     []byte
+	Source   string
+	TakenAt  time.Time
+}
+
+// Grabber produces JPEG stills for cameras. It prefers frames already written
+// by a running HLS transcoder or recorder and only falls back to opening the
+// RTSP stream itself. Results are cached per camera for a short time so that
+// dashboards polling many cameras don't spawn an ffmpeg per request.
+type Grabber struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	ttl     time.Duration
+	timeout time.Duration
+}
+
+type entry struct {
+	mu   sync.Mutex
+	snap *Snapshot
+}
+
+var defaultGrabber = NewGrabber(5*time.Second, 8*time.Second)
+
+// NewGrabber creates a Grabber that caches frames for ttl and gives up on a
+// single ffmpeg grab after timeout.
+func NewGrabber(ttl, timeout time.Duration) *Grabber {
+	return &Grabber{
+		entries: make(map[string]*entry),
+		ttl:     ttl,
+		timeout: timeout,
+	}
+}
+
+// Get returns a current JPEG for the camera using the default grabber.
+func Get(ctx context.Context, cameraID, rtspURL, username, password string) (*Snapshot, error) {
+	return defaultGrabber.Get(ctx, cameraID, rtspURL, username, password)
+}
+
+// Latest returns a current JPEG for the camera like Get, falling back to the
+// last frame of its newest recording when the camera can't be reached. An
+// archive frame's TakenAt is the end of the recording it came from.
+func Latest(ctx context.Context, cameraID, rtspURL, username, password string) (*Snapshot, error) {
+	snap, err := defaultGrabber.Get(ctx, cameraID, rtspURL, username, password)
+	if err == nil {
+		return snap, nil
+	}
+	now := time.Now()
+	segs, rerr := segment.Range(cameraID, now.Add(-archiveLookback), now)
+	if rerr != nil || len(segs) == 0 {
+		return nil, err
+	}
+	last := segs[len(segs)-1]
+	gctx, cancel := context.WithTimeout(ctx, defaultGrabber.timeout)
+	defer cancel()
+	data, ferr := grabFile(gctx, last.Path)
+	if ferr != nil {
+		return nil, fmt.Errorf("%v; archive fallback: %w", err, ferr)
+	}
+	return &Snapshot{CameraID: cameraID, Data: data, Source: SourceArchive, TakenAt: last.End}, nil
+}
+
+// Forget drops the default grabber's cached frame for a camera.
+func Forget(cameraID string) {
+	defaultGrabber.Forget(cameraID)
+}
+
+// Forget drops the cached frame for a camera, e.g. after its stream changed.
+func (g *Grabber) Forget(cameraID string) {
+	g.mu.Lock()
+	delete(g.entries, cameraID)
+	g.mu.Unlock()
+}
+
+// Get returns a cached frame if it is fresh enough, otherwise grabs a new one.
+// Concurrent callers for the same camera share a single grab.
+func (g *Grabber) Get(ctx context.Context, cameraID, rtspURL, username, password string) (*Snapshot, error) {
+	g.mu.Lock()
+	e, ok := g.entries[cameraID]
+	if !ok {
+		e = &entry{}
+		g.entries[cameraID] = e
+	}
+	g.mu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.snap != nil && time.Since(e.snap.TakenAt) < g.ttl {
+		return e.snap, nil
+	}
+
+	snap, err := g.grab(ctx, cameraID, rtspURL, username, password)
+	if err != nil {
+		return nil, err
+	}
+	e.snap = snap
+	return snap, nil
+}
+
+func (g *Grabber) grab(ctx context.Context, cameraID, rtspURL, username, password string) (*Snapshot, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+
+	// Prefer the live HLS output: its newest segment is at most a few
+	// seconds old and decoding the tail of a local file is cheap.
+	if seg, ok := stream.LatestSegment(cameraID); ok {
+		data, err := grabFile(ctx, seg)
+		if err == nil {
+			return &Snapshot{CameraID: cameraID, Data: data, Source: SourceHLS, TakenAt: time.Now()}, nil
+		}
+		log.Printf("snapshot: hls grab failed for camera %s: %v", cameraID, err)
+	}
+
+	// Next, the fragmented MP4 the recorder is appending to.
+	if seg, ok := recorder.CurrentSegment(cameraID); ok {
+		data, err := grabFile(ctx, seg)
+		if err == nil {
+			return &Snapshot{CameraID: cameraID, Data: data, Source: SourceRecorder, TakenAt: time.Now()}, nil
+		}
+		log.Printf("snapshot: recorder grab failed for camera %s: %v", cameraID, err)
+	}
+
+	if rtspURL == "" {
+		return nil, errors.New("camera has no rtsp url")
+	}
+	data, err := grabRTSP(ctx, recorder.AuthRTSPURL(rtspURL, username, password))
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{CameraID: cameraID, Data: data, Source: SourceRTSP, TakenAt: time.Now()}, nil
+}
+
+// grabFile decodes the last frame near the end of a local media file.
+func grabFile(ctx context.Context, path string) ([]byte, error) {
+	return runFFmpeg(ctx,
+		"-sseof", "-1",
+		"-i", path,
+		"-frames:v", "1",
+		"-q:v", "3",
+		"-f", "image2",
+		"-c:v", "mjpeg",
+		"pipe:1",
+	)
+}
+
+// grabRTSP opens the RTSP stream and decodes a single frame.
+func grabRTSP(ctx context.Context, rtspURL string) ([]byte, error) {
+	return runFFmpeg(ctx,
+		"-rtsp_transport", "tcp",
+		"-i", rtspURL,
+		"-frames:v", "1",
+		"-q:v", "3",
+		"-f", "image2",
+		"-c:v", "mjpeg",
+		"pipe:1",
+	)
+}
+
+func runFFmpeg(ctx context.Context, args ...string) ([]byte, error) {
+	args = append([]string{"-hide_banner", "-loglevel", "error", "-y"}, args...)
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("ffmpeg timed out: %w", ctx.Err())
+		}
+		return nil, fmt.Errorf("ffmpeg failed: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	if stdout.Len() == 0 {
+		return nil, errors.New("ffmpeg produced no frame")
+	}
+	return stdout.Bytes(), nil
+}
@@ -1,11 +1,13 @@
 package stream
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"sync"
 )
 
@@ -69,7 +71,10 @@ func StartHLS(id, rtsp string) (string, error) {
 		// wait for process to exit
 		_ = cmd.Wait()
 		m.mu.Lock()
-		delete(m.procs, id)
+		// a restart may already have replaced it
+		if m.procs[id] == cmd {
+			delete(m.procs, id)
+		}
 		m.mu.Unlock()
 		cancel()
 	}()
@@ -93,3 +98,66 @@ func StopHLS(id string) error {
 	delete(m.procs, id)
 	return nil
 }
+
+// Restart restarts a running transcoder for id on a new source, clearing
+// the old playlist so players don't mix segments from both. It does nothing
+// if no transcoder is running.
+func Restart(id, rtsp string) error {
+	if !IsRunning(id) {
+		return nil
+	}
+	if err := Remove(id); err != nil {
+		return err
+	}
+	_, err := StartHLS(id, rtsp)
+	return err
+}
+
+// Remove stops the transcoder for id and deletes its HLS files.
+func Remove(id string) error {
+	if err := StopHLS(id); err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join("data", "streams", id))
+}
+
+// IsRunning reports whether an HLS transcoder is active for id.
+func IsRunning(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cmd, ok := m.procs[id]
+	return ok && cmd != nil && cmd.Process != nil
+}
+
+// LatestSegment returns the path of the newest complete HLS segment for id.
+// Only segments already listed in the playlist are considered, so the file
+// ffmpeg is still writing is never returned.
+func LatestSegment(id string) (string, bool) {
+	if !IsRunning(id) {
+		return "", false
+	}
+	outDir := filepath.Join("data", "streams", id)
+	f, err := os.Open(filepath.Join(outDir, "index.m3u8"))
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	var last string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		last = line
+	}
+	if last == "" {
+		return "", false
+	}
+	path := filepath.Join(outDir, filepath.Base(last))
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/alert"
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrMuteNotFound is returned for an unknown mute window id.
+	ErrMuteNotFound = errors.New("mute window not found")
+	// ErrInvalidAlertSettings is returned when alert settings or a mute
+	// window fail validation.
+	ErrInvalidAlertSettings = errors.New("invalid alert settings")
+)
+
+// minAlertGracePeriod keeps the grace period above one monitor cycle.
+const minAlertGracePeriod = 30 * time.Second
+
+// AlertRepo is the minimal interface the alert usecase depends on.
+type AlertRepo interface {
+	GetSettings() (*domain.AlertSettings, error)
+	SaveSettings(s *domain.AlertSettings) error
+	ListMutes(cameraID string, notEndedBefore time.Time) ([]*domain.AlertMute, error)
+	GetMute(id string) (*domain.AlertMute, error)
+	CreateMute(m *domain.AlertMute) error
+	DeleteMute(id string) error
+}
+
+// OutageTracker reports the cameras that are currently offline.
+type OutageTracker interface {
+	Outages() []alert.Outage
+}
+
+// MuteDTO is a transport-friendly mute window for handlers. A zero Start
+// means now.
+type MuteDTO struct {
+	CameraID  string
+	Start     time.Time
+	End       time.Time
+	Reason    string
+	CreatedBy string
+}
+
+// AlertUsecase contains business logic for offline alert settings and mute
+// windows.
+type AlertUsecase struct {
+	repo    AlertRepo
+	cameras CameraRepo
+	tracker OutageTracker
+}
+
+// NewAlertUsecase creates a new AlertUsecase.
+func NewAlertUsecase(r AlertRepo, cameras CameraRepo, tracker OutageTracker) *AlertUsecase {
+	return &AlertUsecase{repo: r, cameras: cameras, tracker: tracker}
+}
+
+// GetSettings returns the current alert settings.
+func (u *AlertUsecase) GetSettings() (*domain.AlertSettings, error) {
+	return u.repo.GetSettings()
+}
+
+// UpdateSettings changes the given settings; nil leaves a value unchanged.
+func (u *AlertUsecase) UpdateSettings(enabled *bool, gracePeriod *time.Duration) (*domain.AlertSettings, error) {
+	s, err := u.repo.GetSettings()
+	if err != nil {
+		return nil, err
+	}
+	if enabled != nil {
+		s.Enabled = *enabled
+	}
+	if gracePeriod != nil {
+		if *gracePeriod < minAlertGracePeriod {
+			return nil, fmt.Errorf("%w: grace period must be at least %s", ErrInvalidAlertSettings, minAlertGracePeriod)
+		}
+		s.GracePeriod = *gracePeriod
+	}
+	s.UpdatedAt = time.Now()
+	if err := u.repo.SaveSettings(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Outages returns the cameras currently offline and whether they were
+// alerted.
+func (u *AlertUsecase) Outages() []alert.Outage {
+	return u.tracker.Outages()
+}
+
+// ListMutes returns mute windows, optionally for one camera. Unless
+// includeEnded is set, windows that are over are left out.
+func (u *AlertUsecase) ListMutes(cameraID string, includeEnded bool) ([]*domain.AlertMute, error) {
+	var since time.Time
+	if !includeEnded {
+		since = time.Now()
+	}
+	return u.repo.ListMutes(cameraID, since)
+}
+
+// CreateMute validates and stores a mute window.
+func (u *AlertUsecase) CreateMute(dto *MuteDTO) (*domain.AlertMute, error) {
+	if _, err := u.cameras.GetByID(dto.CameraID); err != nil {
+		return nil, ErrCameraNotFound
+	}
+	now := time.Now()
+	m := &domain.AlertMute{
+		ID:        uuid.New().String(),
+		CameraID:  dto.CameraID,
+		Start:     dto.Start,
+		End:       dto.End,
+		Reason:    strings.TrimSpace(dto.Reason),
+		CreatedBy: dto.CreatedBy,
+		CreatedAt: now,
+	}
+	if m.Start.IsZero() {
+		m.Start = now
+	}
+	if !m.End.After(m.Start) {
+		return nil, fmt.Errorf("%w: end must be after start", ErrInvalidAlertSettings)
+	}
+	if err := u.repo.CreateMute(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DeleteMute removes a mute window.
+func (u *AlertUsecase) DeleteMute(id string) error {
+	if _, err := u.repo.GetMute(id); err != nil {
+		return ErrMuteNotFound
+	}
+	return u.repo.DeleteMute(id)
+}
@@ -0,0 +1,226 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+)
+
+// ErrInvalidRange is returned when a report's time range is empty or
+// reversed.
+var ErrInvalidRange = errors.New("invalid time range")
+
+// StatusHistoryRepo is the minimal interface the availability usecase
+// depends on.
+type StatusHistoryRepo interface {
+	List(cameraID string, from, to time.Time) ([]*domain.StatusChange, error)
+	Last(cameraID string, before time.Time) (*domain.StatusChange, error)
+}
+
+// FleetAvailability is the availability of every camera over one range.
+type FleetAvailability struct {
+	From             time.Time              `json:"from"`
+	To               time.Time              `json:"to"`
+	SLATarget        *float64               `json:"sla_target,omitempty"`
+	MonitoredSeconds int64                  `json:"monitored_seconds"`
+	DowntimeSeconds  int64                  `json:"downtime_seconds"`
+	UptimePercent    float64                `json:"uptime_percent"`
+	OutageCount      int                    `json:"outage_count"`
+	MTTRSeconds      int64                  `json:"mttr_seconds"`
+	Compliant        *int                   `json:"compliant_cameras,omitempty"`
+	Cameras          []*domain.Availability `json:"cameras"`
+}
+
+// AvailabilityUsecase computes uptime and outage statistics from the camera
+// status history.
+type AvailabilityUsecase struct {
+	history StatusHistoryRepo
+	cameras CameraRepo
+	now     func() time.Time
+}
+
+// NewAvailabilityUsecase creates a new AvailabilityUsecase.
+func NewAvailabilityUsecase(h StatusHistoryRepo, cameras CameraRepo) *AvailabilityUsecase {
+	return &AvailabilityUsecase{history: h, cameras: cameras, now: time.Now}
+}
+
+// StatusHistory returns a camera's status transitions in [from, to).
+func (u *AvailabilityUsecase) StatusHistory(cameraID string, from, to time.Time) ([]*domain.StatusChange, error) {
+	if !from.Before(to) {
+		return nil, ErrInvalidRange
+	}
+	if _, err := u.cameras.GetByID(cameraID); err != nil {
+		return nil, ErrCameraNotFound
+	}
+	return u.history.List(cameraID, from, to)
+}
+
+// CameraAvailability computes a camera's availability over [from, to). The
+// part of the range in the future is ignored.
+func (u *AvailabilityUsecase) CameraAvailability(cameraID string, from, to time.Time) (*domain.Availability, error) {
+	if !from.Before(to) {
+		return nil, ErrInvalidRange
+	}
+	cam, err := u.cameras.GetByID(cameraID)
+	if err != nil {
+		return nil, ErrCameraNotFound
+	}
+	return u.availability(cam, from, to)
+}
+
+// FleetReport computes the availability of every camera over [from, to).
+// When slaTarget is set each camera is checked against it, as an uptime
+// percentage.
+func (u *AvailabilityUsecase) FleetReport(from, to time.Time, slaTarget *float64) (*FleetAvailability, error) {
+	if !from.Before(to) {
+		return nil, ErrInvalidRange
+	}
+	if slaTarget != nil && (*slaTarget <= 0 || *slaTarget > 100) {
+		return nil, fmt.Errorf("%w: sla must be a percentage between 0 and 100", ErrInvalidRange)
+	}
+	cams, err := u.cameras.List()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &FleetAvailability{From: from, To: to, SLATarget: slaTarget, Cameras: []*domain.Availability{}}
+	var uptime, recovered, recoveries int64
+	compliant := 0
+	for _, cam := range cams {
+		a, err := u.availability(cam, from, to)
+		if err != nil {
+			return nil, err
+		}
+		if slaTarget != nil {
+			ok := a.MonitoredSeconds > 0 && a.UptimePercent >= *slaTarget
+			a.SLATarget, a.Compliant = slaTarget, &ok
+			if ok {
+				compliant++
+			}
+		}
+		report.MonitoredSeconds += a.MonitoredSeconds
+		report.DowntimeSeconds += a.DowntimeSeconds
+		uptime += a.MonitoredSeconds - a.DowntimeSeconds
+		report.OutageCount += a.OutageCount
+		for _, o := range a.Outages {
+			if !o.Ongoing {
+				recovered += o.DurationSeconds
+				recoveries++
+			}
+		}
+		report.Cameras = append(report.Cameras, a)
+	}
+	sort.Slice(report.Cameras, func(i, j int) bool { return report.Cameras[i].CameraName < report.Cameras[j].CameraName })
+	if report.MonitoredSeconds > 0 {
+		report.UptimePercent = percent(uptime, report.MonitoredSeconds)
+	}
+	if recoveries > 0 {
+		report.MTTRSeconds = recovered / recoveries
+	}
+	if slaTarget != nil {
+		report.Compliant = &compliant
+	}
+	return report, nil
+}
+
+func (u *AvailabilityUsecase) availability(cam *domain.Camera, from, to time.Time) (*domain.Availability, error) {
+	a := &domain.Availability{CameraID: cam.ID, CameraName: cam.Name, From: from, To: to, Outages: []domain.OutageInterval{}}
+	end := to
+	if now := u.now(); now.Before(end) {
+		end = now
+	}
+	if !from.Before(end) {
+		return a, nil
+	}
+
+	status := domain.CameraStatusUnknown
+	last, err := u.history.Last(cam.ID, from)
+	if err != nil {
+		return nil, err
+	}
+	if last != nil {
+		status = last.Status
+	}
+	changes, err := u.history.List(cam.ID, from, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var outage *domain.OutageInterval
+	if isOutage(status) {
+		// already down when the range starts
+		outage = &domain.OutageInterval{Start: from, Status: status}
+	}
+	var monitored, down, recovered time.Duration
+	recoveries := 0
+	t := from
+	advance := func(next time.Time) {
+		if monitoredStatus(status) {
+			monitored += next.Sub(t)
+			if status != domain.CameraStatusOnline {
+				down += next.Sub(t)
+			}
+		}
+		t = next
+	}
+
+	for _, c := range changes {
+		advance(c.At)
+		status = c.Status
+		switch {
+		case isOutage(status) && outage == nil:
+			outage = &domain.OutageInterval{Start: c.At, Status: status}
+		case !isOutage(status) && outage != nil:
+			d := c.At.Sub(outage.Start)
+			outage.End = c.At
+			outage.DurationSeconds = int64(d / time.Second)
+			a.Outages = append(a.Outages, *outage)
+			outage = nil
+			recovered += d
+			recoveries++
+		}
+	}
+	advance(end)
+	if outage != nil {
+		outage.End = end
+		outage.DurationSeconds = int64(end.Sub(outage.Start) / time.Second)
+		outage.Ongoing = true
+		a.Outages = append(a.Outages, *outage)
+	}
+
+	a.MonitoredSeconds = int64(monitored / time.Second)
+	a.DowntimeSeconds = int64(down / time.Second)
+	if a.MonitoredSeconds > 0 {
+		a.UptimePercent = percent(a.MonitoredSeconds-a.DowntimeSeconds, a.MonitoredSeconds)
+	}
+	a.OutageCount = len(a.Outages)
+	if recoveries > 0 {
+		a.MTTRSeconds = int64(recovered / time.Duration(recoveries) / time.Second)
+	}
+	return a, nil
+}
+
+// monitoredStatus reports whether time spent in a status counts towards
+// availability. A camera that has not been probed yet, or was disabled or in
+// maintenance, is neither up nor down.
+func monitoredStatus(status string) bool {
+	switch status {
+	case domain.CameraStatusUnknown, domain.CameraStatusDisabled, domain.CameraStatusMaintenance:
+		return false
+	}
+	return true
+}
+
+// isOutage reports whether a status counts as down.
+func isOutage(status string) bool {
+	return status != domain.CameraStatusOnline && monitoredStatus(status)
+}
+
+// percent returns part/total as a percentage rounded to three decimals.
+func percent(part, total int64) float64 {
+	p := float64(part) * 100 / float64(total)
+	return float64(int64(p*1000+0.5)) / 1000
+}
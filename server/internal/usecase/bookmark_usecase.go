@@ -0,0 +1,182 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/events"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrBookmarkNotFound is returned for an unknown bookmark id.
+	ErrBookmarkNotFound = errors.New("bookmark not found")
+	// ErrInvalidBookmark is returned when a bookmark fails validation.
+	ErrInvalidBookmark = errors.New("invalid bookmark")
+)
+
+// pointBookmarkPadding is the window exported around a bookmark that marks a
+// single moment rather than a span.
+const pointBookmarkPadding = 30 * time.Second
+
+// BookmarkRepo is the minimal interface the bookmark usecase depends on.
+type BookmarkRepo interface {
+	List(f domain.BookmarkFilter) ([]*domain.Bookmark, error)
+	GetByID(id string) (*domain.Bookmark, error)
+	Create(b *domain.Bookmark) error
+	Update(b *domain.Bookmark) error
+	Delete(id string) error
+}
+
+// BookmarkDTO is a transport-friendly bookmark representation for handlers.
+type BookmarkDTO struct {
+	ID          string
+	CameraID    string
+	Title       string
+	Description string
+	Tags        []string
+	Time        time.Time
+	EndTime     *time.Time
+	CreatedBy   string
+}
+
+// BookmarkUsecase contains business logic for timeline bookmarks.
+type BookmarkUsecase struct {
+	repo    BookmarkRepo
+	cameras CameraRepo
+}
+
+// NewBookmarkUsecase creates a new BookmarkUsecase.
+func NewBookmarkUsecase(r BookmarkRepo, cameras CameraRepo) *BookmarkUsecase {
+	return &BookmarkUsecase{repo: r, cameras: cameras}
+}
+
+// ListBookmarks searches bookmarks.
+func (u *BookmarkUsecase) ListBookmarks(f domain.BookmarkFilter) ([]*domain.Bookmark, error) {
+	return u.repo.List(f)
+}
+
+// GetBookmark returns a single bookmark.
+func (u *BookmarkUsecase) GetBookmark(id string) (*domain.Bookmark, error) {
+	b, err := u.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrBookmarkNotFound
+	}
+	return b, nil
+}
+
+// CreateBookmark validates and stores a new bookmark.
+func (u *BookmarkUsecase) CreateBookmark(dto *BookmarkDTO) (*domain.Bookmark, error) {
+	if _, err := u.cameras.GetByID(dto.CameraID); err != nil {
+		return nil, ErrCameraNotFound
+	}
+	now := time.Now()
+	b := &domain.Bookmark{
+		ID:          uuid.New().String(),
+		CameraID:    dto.CameraID,
+		Title:       strings.TrimSpace(dto.Title),
+		Description: dto.Description,
+		Tags:        normalizeTags(dto.Tags),
+		Time:        dto.Time,
+		EndTime:     dto.EndTime,
+		CreatedBy:   dto.CreatedBy,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := validateBookmark(b); err != nil {
+		return nil, err
+	}
+	if err := u.repo.Create(b); err != nil {
+		return nil, err
+	}
+	events.Publish(domain.Event{
+		Type:     events.BookmarkCreated,
+		CameraID: b.CameraID,
+		Time:     b.CreatedAt,
+		Payload: map[string]interface{}{
+			"bookmark_id": b.ID,
+			"title":       b.Title,
+			"time":        b.Time.Format(time.RFC3339),
+			"created_by":  b.CreatedBy,
+		},
+	})
+	return b, nil
+}
+
+// UpdateBookmark updates the non-empty fields of an existing bookmark. Tags
+// are replaced when given; EndTime is replaced when given.
+func (u *BookmarkUsecase) UpdateBookmark(dto *BookmarkDTO) (*domain.Bookmark, error) {
+	b, err := u.repo.GetByID(dto.ID)
+	if err != nil {
+		return nil, ErrBookmarkNotFound
+	}
+	if dto.Title != "" {
+		b.Title = strings.TrimSpace(dto.Title)
+	}
+	if dto.Description != "" {
+		b.Description = dto.Description
+	}
+	if dto.Tags != nil {
+		b.Tags = normalizeTags(dto.Tags)
+	}
+	if !dto.Time.IsZero() {
+		b.Time = dto.Time
+	}
+	if dto.EndTime != nil {
+		b.EndTime = dto.EndTime
+	}
+	b.UpdatedAt = time.Now()
+	if err := validateBookmark(b); err != nil {
+		return nil, err
+	}
+	if err := u.repo.Update(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// DeleteBookmark removes a bookmark.
+func (u *BookmarkUsecase) DeleteBookmark(id string) error {
+	return u.repo.Delete(id)
+}
+
+// Span returns the time range a bookmark covers for export. A point bookmark
+// is padded on both sides.
+func (u *BookmarkUsecase) Span(b *domain.Bookmark) (time.Time, time.Time) {
+	if b.EndTime != nil {
+		return b.Time, *b.EndTime
+	}
+	return b.Time.Add(-pointBookmarkPadding), b.Time.Add(pointBookmarkPadding)
+}
+
+func validateBookmark(b *domain.Bookmark) error {
+	if b.Title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidBookmark)
+	}
+	if b.Time.IsZero() {
+		return fmt.Errorf("%w: time is required", ErrInvalidBookmark)
+	}
+	if b.EndTime != nil && !b.EndTime.After(b.Time) {
+		return fmt.Errorf("%w: end_time must be after time", ErrInvalidBookmark)
+	}
+	return nil
+}
+
+// normalizeTags trims, lower-cases and de-duplicates tags. Commas are not
+// allowed inside a tag because they separate tags in storage.
+func normalizeTags(tags []string) []string {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(t, ",", " ")))
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	return out
+}
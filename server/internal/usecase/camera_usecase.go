@@ -1,19 +1,73 @@
 package usecase
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
 	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/events"
+	"github.com/boytur/cctv-recording-center/server/internal/recorder"
+	"github.com/boytur/cctv-recording-center/server/internal/segment"
+	"github.com/boytur/cctv-recording-center/server/internal/snapshot"
+	"github.com/boytur/cctv-recording-center/server/internal/stream"
 	"github.com/google/uuid"
 )
 
+// Limits on the per-camera probe settings, in seconds.
+const (
+	MinProbeInterval = 10
+	MaxProbeInterval = 24 * 60 * 60
+	MinProbeTimeout  = 1
+	MaxProbeTimeout  = 60
+)
+
+var (
+	// ErrInvalidCamera is returned when camera settings fail validation.
+	ErrInvalidCamera = errors.New("invalid camera")
+	// ErrFootageNotRemoved is returned when a camera was deleted but its
+	// recordings could not be archived or purged.
+	ErrFootageNotRemoved = errors.New("camera deleted but its footage was not removed")
+)
+
 // CameraRepo is the minimal interface the usecase depends on.
 type CameraRepo interface {
 	List() ([]*domain.Camera, error)
 	GetByID(id string) (*domain.Camera, error)
 	Create(c *domain.Camera) error
 	Update(c *domain.Camera) error
+	UpdateMaintenance(id string, until *time.Time, reason string) error
+	UpdateStream(id string, stream *domain.StreamInfo, change *domain.StreamChange) error
+	UpdateHardware(id string, info *domain.DeviceInfo) error
 	Delete(id string) error
 }
 
+// CaptureStopper stops a camera's manual capture and records how it ended.
+type CaptureStopper interface {
+	StopCapture(cameraID string) error
+}
+
+// FootageAction says what happens to a deleted camera's recordings.
+type FootageAction string
+
+const (
+	// FootageKeep leaves recordings where they are.
+	FootageKeep FootageAction = "keep"
+	// FootageArchive moves recordings to the archive directory.
+	FootageArchive FootageAction = "archive"
+	// FootagePurge deletes recordings.
+	FootagePurge FootageAction = "purge"
+)
+
+// archiveDir holds the recordings of deleted cameras that were archived.
+var archiveDir = filepath.Join("data", "archive")
+
+// sessionExitTimeout bounds the wait for a deleted camera's ffmpeg
+// processes to exit before its footage is archived or purged.
+const sessionExitTimeout = 15 * time.Second
+
 // CameraDTO is a transport-friendly camera representation for handlers.
 type CameraDTO struct {
 	ID       string
@@ -23,16 +77,31 @@ type CameraDTO struct {
 	Username string
 	Password string
 	Status   string
+	// ProbeIntervalSeconds and ProbeTimeoutSeconds are left unchanged when
+	// nil; 0 resets them to the monitor default.
+	ProbeIntervalSeconds *int
+	ProbeTimeoutSeconds  *int
+	// Enabled is left unchanged when nil; new cameras are enabled.
+	Enabled *bool
+	// Hardware is set for cameras added from an ONVIF device.
+	Hardware *domain.DeviceInfo
+	// DeviceID and Channel are set for the channels of a multi-channel
+	// device, by the device usecase only.
+	DeviceID string
+	Channel  int
 }
 
-// CameraUsecase contains business logic for cameras.
+// CameraUsecase contains business logic for cameras. It also keeps the
+// camera's running sessions (recorder, live stream, snapshot cache) in step
+// with changes to it.
 type CameraUsecase struct {
-	repo CameraRepo
+	repo     CameraRepo
+	captures CaptureStopper
 }
 
 // NewCameraUsecase creates a new CameraUsecase.
-func NewCameraUsecase(r CameraRepo) *CameraUsecase {
-	return &CameraUsecase{repo: r}
+func NewCameraUsecase(r CameraRepo, captures CaptureStopper) *CameraUsecase {
+	return &CameraUsecase{repo: r, captures: captures}
 }
 
 // ListCameras returns all cameras from the repository.
@@ -40,6 +109,11 @@ func (u *CameraUsecase) ListCameras() ([]*domain.Camera, error) {
 	return u.repo.List()
 }
 
+// GetCamera returns a single camera by id.
+func (u *CameraUsecase) GetCamera(id string) (*domain.Camera, error) {
+	return u.repo.GetByID(id)
+}
+
 // CreateCamera validates and creates a camera, generating an ID if needed.
 func (u *CameraUsecase) CreateCamera(dto *CameraDTO) (*domain.Camera, error) {
 	id := dto.ID
@@ -54,22 +128,47 @@ func (u *CameraUsecase) CreateCamera(dto *CameraDTO) (*domain.Camera, error) {
 		Username: dto.Username,
 		Password: dto.Password,
 		Status:   dto.Status,
+		Enabled:  dto.Enabled == nil || *dto.Enabled,
+		Hardware: dto.Hardware,
+		DeviceID: dto.DeviceID,
+		Channel:  dto.Channel,
 	}
 	if cam.Status == "" {
 		cam.Status = "unknown"
 	}
+	if err := applyProbeSettings(cam, dto); err != nil {
+		return nil, err
+	}
 	if err := u.repo.Create(cam); err != nil {
 		return nil, err
 	}
+	publishCamera(events.CameraCreated, cam)
 	return cam, nil
 }
 
-// UpdateCamera updates an existing camera.
+// UpdateCamera updates an existing camera. The stream URL and credentials
+// of a device channel are managed by its device and can't be changed here.
 func (u *CameraUsecase) UpdateCamera(dto *CameraDTO) (*domain.Camera, error) {
+	return u.update(dto, false)
+}
+
+// update updates an existing camera. fromDevice is set when the device
+// usecase moves a channel to its device's settings.
+func (u *CameraUsecase) update(dto *CameraDTO, fromDevice bool) (*domain.Camera, error) {
 	existing, err := u.repo.GetByID(dto.ID)
 	if err != nil {
 		return nil, err
 	}
+	if existing.DeviceID != "" && !fromDevice &&
+		(dto.RTSPURL != "" && dto.RTSPURL != existing.RTSPURL ||
+			dto.Username != "" && dto.Username != existing.Username ||
+			dto.Password != "" && dto.Password != existing.Password) {
+		return nil, fmt.Errorf("%w: the stream URL and credentials of a device channel are set on its device", ErrInvalidCamera)
+	}
+	before := *existing
+	if fromDevice {
+		existing.DeviceID, existing.Channel = dto.DeviceID, dto.Channel
+	}
 	if dto.Name != "" {
 		existing.Name = dto.Name
 	}
@@ -88,13 +187,234 @@ func (u *CameraUsecase) UpdateCamera(dto *CameraDTO) (*domain.Camera, error) {
 	if dto.Status != "" {
 		existing.Status = dto.Status
 	}
+	if err := applyProbeSettings(existing, dto); err != nil {
+		return nil, err
+	}
+	if dto.Enabled != nil {
+		existing.Enabled = *dto.Enabled
+	}
+	streamChanged := existing.RTSPURL != before.RTSPURL || existing.Username != before.Username || existing.Password != before.Password
+	if streamChanged && dto.Status == "" {
+		// the old status says nothing about the new stream; the monitor
+		// probes it again as soon as the update is published
+		existing.Status = domain.CameraStatusUnknown
+	}
 	if err := u.repo.Update(existing); err != nil {
 		return nil, err
 	}
+	if existing.Name != before.Name {
+		recorder.RenameCamera(existing.ID, existing.Name)
+	}
+	if streamChanged {
+		u.restartSessions(existing)
+	}
+	if !existing.Enabled && before.Enabled {
+		u.stopOutOfService(existing.ID)
+	}
+	publishCamera(events.CameraUpdated, existing)
 	return existing, nil
 }
 
-// DeleteCamera removes a camera.
-func (u *CameraUsecase) DeleteCamera(id string) error {
-	return u.repo.Delete(id)
+// StartMaintenance takes a camera out of service until the given time. The
+// monitor stops probing it, so it is neither recorded nor alerted on, and
+// the time does not count against its availability. A running manual
+// capture is stopped.
+func (u *CameraUsecase) StartMaintenance(id string, until time.Time, reason string) (*domain.Camera, error) {
+	if !until.After(time.Now()) {
+		return nil, fmt.Errorf("%w: maintenance must end in the future", ErrInvalidCamera)
+	}
+	cam, err := u.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrCameraNotFound
+	}
+	until = until.UTC()
+	cam.MaintenanceUntil, cam.MaintenanceReason = &until, reason
+	if err := u.repo.UpdateMaintenance(cam.ID, cam.MaintenanceUntil, reason); err != nil {
+		return nil, err
+	}
+	u.stopOutOfService(cam.ID)
+	publishCamera(events.CameraUpdated, cam)
+	return cam, nil
+}
+
+// EndMaintenance returns a camera to service before its maintenance window
+// closes. The monitor probes it straight away.
+func (u *CameraUsecase) EndMaintenance(id string) (*domain.Camera, error) {
+	cam, err := u.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrCameraNotFound
+	}
+	if cam.MaintenanceUntil == nil {
+		return cam, nil
+	}
+	cam.MaintenanceUntil, cam.MaintenanceReason = nil, ""
+	if err := u.repo.UpdateMaintenance(cam.ID, nil, ""); err != nil {
+		return nil, err
+	}
+	publishCamera(events.CameraUpdated, cam)
+	return cam, nil
+}
+
+// stopOutOfService stops what a camera taken out of service still has
+// running: its live stream and any manual capture. The auto-recorder stops
+// the continuous recording once the update is published.
+func (u *CameraUsecase) stopOutOfService(id string) {
+	stopLiveStream(id)
+	if recorder.IsCapturing(id) && u.captures != nil {
+		if err := u.captures.StopCapture(id); err != nil {
+			log.Printf("camera %s: failed to stop capture: %v", id, err)
+		}
+	}
+}
+
+// stopLiveStream stops a camera's live stream and removes its HLS files.
+func stopLiveStream(id string) {
+	if err := stream.Remove(id); err != nil {
+		log.Printf("camera %s: failed to stop live stream: %v", id, err)
+	}
+}
+
+// restartSessions moves a camera's sessions to its new stream. The
+// continuous recording is stopped and started again by the auto-recorder
+// once the new stream is found online; a running live stream is restarted
+// straight away. Manual captures keep running until they are stopped.
+func (u *CameraUsecase) restartSessions(c *domain.Camera) {
+	if recorder.IsRecording(c.ID) {
+		log.Printf("camera %s stream changed, stopping recording", c.ID)
+		if err := recorder.StopRecording(c.ID); err != nil {
+			log.Printf("camera %s: failed to stop recording: %v", c.ID, err)
+		}
+	}
+	if err := stream.Restart(c.ID, recorder.AuthRTSPURL(c.RTSPURL, c.Username, c.Password)); err != nil {
+		log.Printf("camera %s: failed to restart live stream: %v", c.ID, err)
+	}
+	snapshot.Forget(c.ID)
+}
+
+// AcknowledgeStreamChange clears the stream change flagged on a camera once
+// an operator has seen it.
+func (u *CameraUsecase) AcknowledgeStreamChange(id string) (*domain.Camera, error) {
+	cam, err := u.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrCameraNotFound
+	}
+	if cam.StreamChange == nil {
+		return cam, nil
+	}
+	if err := u.repo.UpdateStream(cam.ID, cam.Stream, nil); err != nil {
+		return nil, err
+	}
+	cam.StreamChange = nil
+	return cam, nil
+}
+
+// ReportMotion publishes a motion event for a camera, as reported by the
+// camera itself or an external detector named by source. Motion from a camera
+// that is disabled or in maintenance is refused so work on it doesn't raise
+// events.
+func (u *CameraUsecase) ReportMotion(id, source string) error {
+	cam, err := u.repo.GetByID(id)
+	if err != nil {
+		return ErrCameraNotFound
+	}
+	if cam.ServiceStatus(time.Now()) != "" {
+		return ErrCameraOutOfService
+	}
+	payload := map[string]interface{}{"name": cam.Name, "location": cam.Location}
+	if source != "" {
+		payload["source"] = source
+	}
+	events.Publish(domain.Event{Type: events.MotionDetected, CameraID: cam.ID, Payload: payload})
+	return nil
+}
+
+// DeleteCamera removes a camera and stops its recording, manual capture and
+// live stream. footage says what happens to its recordings and defaults to
+// keeping them.
+func (u *CameraUsecase) DeleteCamera(id string, footage FootageAction) error {
+	switch footage {
+	case "":
+		footage = FootageKeep
+	case FootageKeep, FootageArchive, FootagePurge:
+	default:
+		return fmt.Errorf("%w: footage must be keep, archive or purge", ErrInvalidCamera)
+	}
+	cam, err := u.repo.GetByID(id)
+	if err != nil {
+		return ErrCameraNotFound
+	}
+	// delete first so the auto-recorder can't restart what is stopped below
+	if err := u.repo.Delete(id); err != nil {
+		return err
+	}
+
+	if recorder.IsCapturing(id) && u.captures != nil {
+		if err := u.captures.StopCapture(id); err != nil {
+			log.Printf("camera %s: failed to stop capture: %v", id, err)
+		}
+	}
+	if recorder.IsRecording(id) {
+		if err := recorder.StopRecording(id); err != nil {
+			log.Printf("camera %s: failed to stop recording: %v", id, err)
+		}
+	}
+	stopLiveStream(id)
+	snapshot.Forget(id)
+
+	payload := map[string]interface{}{"name": cam.Name, "location": cam.Location, "footage": string(footage)}
+	var footageErr error
+	if footage != FootageKeep {
+		// ffmpeg may still be flushing the last fragment
+		footageErr = recorder.WaitStopped(id, sessionExitTimeout)
+	}
+	if footageErr == nil {
+		switch footage {
+		case FootageArchive:
+			var path string
+			if path, footageErr = segment.Archive(id, archiveDir); footageErr == nil && path != "" {
+				log.Printf("camera %s: recordings archived to %s", id, path)
+				payload["archive_path"] = path
+			}
+		case FootagePurge:
+			footageErr = segment.Purge(id)
+		}
+	}
+	if footageErr != nil {
+		log.Printf("camera %s: failed to %s recordings: %v", id, footage, footageErr)
+		payload["footage_error"] = footageErr.Error()
+	}
+	events.Publish(domain.Event{Type: events.CameraDeleted, CameraID: id, Payload: payload})
+	if footageErr != nil {
+		return fmt.Errorf("%w: %v", ErrFootageNotRemoved, footageErr)
+	}
+	return nil
+}
+
+// applyProbeSettings copies the probe interval and timeout from dto and
+// checks them. The timeout must leave room before the next probe.
+func applyProbeSettings(c *domain.Camera, dto *CameraDTO) error {
+	if v := dto.ProbeIntervalSeconds; v != nil {
+		if *v != 0 && (*v < MinProbeInterval || *v > MaxProbeInterval) {
+			return fmt.Errorf("%w: probe_interval_seconds must be between %d and %d", ErrInvalidCamera, MinProbeInterval, MaxProbeInterval)
+		}
+		c.ProbeIntervalSeconds = *v
+	}
+	if v := dto.ProbeTimeoutSeconds; v != nil {
+		if *v != 0 && (*v < MinProbeTimeout || *v > MaxProbeTimeout) {
+			return fmt.Errorf("%w: probe_timeout_seconds must be between %d and %d", ErrInvalidCamera, MinProbeTimeout, MaxProbeTimeout)
+		}
+		c.ProbeTimeoutSeconds = *v
+	}
+	if c.ProbeIntervalSeconds != 0 && c.ProbeTimeoutSeconds >= c.ProbeIntervalSeconds {
+		return fmt.Errorf("%w: probe_timeout_seconds must be shorter than probe_interval_seconds", ErrInvalidCamera)
+	}
+	return nil
+}
+
+func publishCamera(eventType string, c *domain.Camera) {
+	events.Publish(domain.Event{
+		Type:     eventType,
+		CameraID: c.ID,
+		Payload:  map[string]interface{}{"name": c.Name, "location": c.Location},
+	})
 }
@@ -0,0 +1,265 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/recorder"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrCameraNotFound is returned when an operation targets an unknown camera.
+	ErrCameraNotFound = errors.New("camera not found")
+	// ErrCameraOffline is returned when a capture is requested for a camera
+	// that isn't online.
+	ErrCameraOffline = errors.New("camera is offline")
+	// ErrCameraOutOfService is returned when a capture is requested for, or
+	// motion is reported by, a camera that is disabled or in maintenance.
+	ErrCameraOutOfService = errors.New("camera is out of service")
+	// ErrInvalidStopTime is returned when a timed capture would end in the past.
+	ErrInvalidStopTime = errors.New("stop time must be in the future")
+)
+
+// CaptureRepo is the minimal interface the capture usecase depends on.
+type CaptureRepo interface {
+	ListByCamera(cameraID string, from, to time.Time) ([]*domain.Capture, error)
+	ListByStatus(status string) ([]*domain.Capture, error)
+	GetByID(id string) (*domain.Capture, error)
+	Create(c *domain.Capture) error
+	Update(c *domain.Capture) error
+}
+
+// CaptureRequest describes a manual capture an operator wants to start.
+type CaptureRequest struct {
+	CameraID string
+	Operator string
+	Note     string
+	// Duration or StopAt schedule an automatic stop; if both are zero the
+	// capture records until stopped. StopAt wins when both are set.
+	Duration time.Duration
+	StopAt   time.Time
+}
+
+// CaptureUsecase starts and stops manual captures and keeps their metadata.
+type CaptureUsecase struct {
+	repo    CaptureRepo
+	cameras CameraRepo
+
+	mu           sync.Mutex
+	shuttingDown bool
+}
+
+// NewCaptureUsecase creates a new CaptureUsecase.
+func NewCaptureUsecase(r CaptureRepo, cameras CameraRepo) *CaptureUsecase {
+	return &CaptureUsecase{repo: r, cameras: cameras}
+}
+
+// StartCapture starts a manual capture for an online camera in service and
+// records who started it, why, and when it is scheduled to stop.
+func (u *CaptureUsecase) StartCapture(req CaptureRequest) (*domain.Capture, error) {
+	cam, err := u.cameras.GetByID(req.CameraID)
+	if err != nil {
+		return nil, ErrCameraNotFound
+	}
+	if cam.ServiceStatus(time.Now()) != "" {
+		return nil, ErrCameraOutOfService
+	}
+	if cam.Status != "online" {
+		return nil, ErrCameraOffline
+	}
+
+	capture := &domain.Capture{
+		ID:       uuid.New().String(),
+		CameraID: cam.ID,
+		Operator: req.Operator,
+		Note:     req.Note,
+	}
+	stopAt := req.StopAt
+	if stopAt.IsZero() && req.Duration > 0 {
+		stopAt = time.Now().Add(req.Duration)
+	}
+	if !stopAt.IsZero() {
+		if !stopAt.After(time.Now()) {
+			return nil, ErrInvalidStopTime
+		}
+		capture.StopAt = &stopAt
+	}
+	if err := u.start(cam, capture); err != nil {
+		return nil, err
+	}
+	return capture, nil
+}
+
+// start persists capture and launches the recorder for it. The capture is
+// saved as starting first so an ffmpeg that exits straight away finds it.
+func (u *CaptureUsecase) start(cam *domain.Camera, capture *domain.Capture) error {
+	var remaining time.Duration
+	if capture.StopAt != nil {
+		remaining = time.Until(*capture.StopAt)
+	}
+	capture.StartedAt = time.Now()
+	capture.Duration = int(remaining.Seconds())
+	capture.Status = domain.CaptureStarting
+	if err := u.repo.Create(capture); err != nil {
+		return fmt.Errorf("failed to save capture: %w", err)
+	}
+
+	// held until the capture is marked recording so finish can't run in
+	// between
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	session, err := recorder.StartCapture(recorder.CaptureOptions{
+		CameraID:   cam.ID,
+		CameraName: cam.Name,
+		RTSPURL:    cam.RTSPURL,
+		Username:   cam.Username,
+		Password:   cam.Password,
+		Duration:   remaining,
+		Operator:   capture.Operator,
+		Note:       capture.Note,
+		OnExit: func(session recorder.CaptureSession, err error) {
+			u.finish(capture.ID, session.FileName, err)
+		},
+	})
+	if err != nil {
+		now := time.Now()
+		capture.Status = domain.CaptureFailed
+		capture.StoppedAt = &now
+		if uerr := u.repo.Update(capture); uerr != nil {
+			log.Printf("capture: failed to mark capture %s failed: %v", capture.ID, uerr)
+		}
+		return err
+	}
+	capture.FileName = session.FileName
+	capture.StartedAt = session.StartTime
+	capture.Status = domain.CaptureRecording
+	if err := u.repo.Update(capture); err != nil {
+		log.Printf("capture: failed to update capture %s for camera %s: %v", capture.ID, cam.ID, err)
+	}
+	return nil
+}
+
+// StopCapture stops the manual capture running for a camera.
+func (u *CaptureUsecase) StopCapture(cameraID string) error {
+	// Mark the capture as stopped by the operator before the process exits
+	// so it isn't resumed or reported as failed.
+	u.mu.Lock()
+	now := time.Now()
+	for _, capture := range u.open() {
+		if capture.CameraID != cameraID {
+			continue
+		}
+		capture.Status = domain.CaptureStopped
+		capture.StoppedAt = &now
+		if err := u.repo.Update(capture); err != nil {
+			log.Printf("capture: failed to mark capture %s stopped: %v", capture.ID, err)
+		}
+	}
+	u.mu.Unlock()
+	return recorder.StopCapture(cameraID)
+}
+
+// open returns the captures that are starting or recording.
+func (u *CaptureUsecase) open() []*domain.Capture {
+	var open []*domain.Capture
+	for _, status := range []string{domain.CaptureStarting, domain.CaptureRecording} {
+		caps, err := u.repo.ListByStatus(status)
+		if err != nil {
+			log.Printf("capture: failed to list %s captures: %v", status, err)
+			continue
+		}
+		open = append(open, caps...)
+	}
+	return open
+}
+
+// ListCaptures returns captures for a camera started within [from, to).
+func (u *CaptureUsecase) ListCaptures(cameraID string, from, to time.Time) ([]*domain.Capture, error) {
+	return u.repo.ListByCamera(cameraID, from, to)
+}
+
+// ResumePending restarts timed captures that were still recording when the
+// server went down and whose scheduled stop time hasn't passed yet. Each
+// resumed capture writes a new file linked to the interrupted one; captures
+// that can't be resumed are marked interrupted.
+func (u *CaptureUsecase) ResumePending() {
+	for _, old := range u.open() {
+		if recorder.IsCapturing(old.CameraID) {
+			continue
+		}
+		old.Status = domain.CaptureInterrupted
+		if err := u.repo.Update(old); err != nil {
+			log.Printf("capture: failed to mark capture %s interrupted: %v", old.ID, err)
+		}
+		if old.StopAt == nil || !old.StopAt.After(time.Now()) {
+			continue
+		}
+
+		cam, err := u.cameras.GetByID(old.CameraID)
+		if err != nil {
+			log.Printf("capture: cannot resume capture %s, camera %s not found", old.ID, old.CameraID)
+			continue
+		}
+		if s := cam.ServiceStatus(time.Now()); s != "" {
+			log.Printf("capture: not resuming capture %s, camera %s is %s", old.ID, old.CameraID, s)
+			continue
+		}
+		capture := &domain.Capture{
+			ID:          uuid.New().String(),
+			CameraID:    old.CameraID,
+			Operator:    old.Operator,
+			Note:        old.Note,
+			StopAt:      old.StopAt,
+			ResumedFrom: old.ID,
+		}
+		if err := u.start(cam, capture); err != nil {
+			log.Printf("capture: failed to resume capture %s for camera %s: %v", old.ID, old.CameraID, err)
+			continue
+		}
+		log.Printf("capture: resumed capture %s for camera %s until %s", old.ID, old.CameraID, old.StopAt.Format(time.RFC3339))
+	}
+}
+
+// Shutdown stops tracking capture exits so that captures killed by a server
+// shutdown stay in the recording state and are resumed on the next start.
+func (u *CaptureUsecase) Shutdown() {
+	u.mu.Lock()
+	u.shuttingDown = true
+	u.mu.Unlock()
+}
+
+// finish records how a capture's ffmpeg process ended.
+func (u *CaptureUsecase) finish(id, fileName string, exitErr error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.shuttingDown {
+		return
+	}
+
+	capture, err := u.repo.GetByID(id)
+	if err != nil {
+		log.Printf("capture: failed to load capture %s: %v", id, err)
+		return
+	}
+	if capture.Status != domain.CaptureRecording && capture.Status != domain.CaptureStarting {
+		// already stopped by the operator
+		return
+	}
+	now := time.Now()
+	if capture.FileName == "" {
+		capture.FileName = fileName
+	}
+	capture.StoppedAt = &now
+	capture.Status = domain.CaptureCompleted
+	if exitErr != nil {
+		capture.Status = domain.CaptureFailed
+	}
+	if err := u.repo.Update(capture); err != nil {
+		log.Printf("capture: failed to update capture %s: %v", id, err)
+	}
+}
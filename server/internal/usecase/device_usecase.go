@@ -0,0 +1,407 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/events"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrDeviceNotFound is returned for an unknown device id.
+	ErrDeviceNotFound = errors.New("device not found")
+	// ErrInvalidDevice is returned when a device or its channels fail
+	// validation.
+	ErrInvalidDevice = errors.New("invalid device")
+)
+
+// MaxDeviceChannel is the highest channel number a device can have.
+const MaxDeviceChannel = 256
+
+// DeviceRepo is the minimal interface the device usecase depends on.
+type DeviceRepo interface {
+	List() ([]*domain.Device, error)
+	GetByID(id string) (*domain.Device, error)
+	Create(d *domain.Device) error
+	Update(d *domain.Device) error
+	Delete(id string) error
+}
+
+// DeviceDTO is a transport-friendly device for handlers. Empty fields leave
+// the existing value unchanged on update.
+type DeviceDTO struct {
+	ID          string
+	Name        string
+	Location    string
+	Vendor      string
+	Host        string
+	Username    string
+	Password    string
+	URLTemplate string
+	Stream      string
+	// Channels are created with the device.
+	Channels []int
+}
+
+// DeviceView is a device with its channel cameras, ordered by channel.
+type DeviceView struct {
+	*domain.Device
+	Channels []*domain.Camera `json:"channels"`
+}
+
+// DeviceUsecase manages multi-channel devices. A device's channels are
+// ordinary cameras whose stream URL and credentials follow the device, so
+// a change made once on the device reaches every channel.
+type DeviceUsecase struct {
+	repo       DeviceRepo
+	cameras    *CameraUsecase
+	cameraRepo CameraRepo
+}
+
+// NewDeviceUsecase creates a new DeviceUsecase.
+func NewDeviceUsecase(r DeviceRepo, cameras *CameraUsecase, cameraRepo CameraRepo) *DeviceUsecase {
+	return &DeviceUsecase{repo: r, cameras: cameras, cameraRepo: cameraRepo}
+}
+
+// ListDevices returns all devices with their channels.
+func (u *DeviceUsecase) ListDevices() ([]*DeviceView, error) {
+	devices, err := u.repo.List()
+	if err != nil {
+		return nil, err
+	}
+	cams, err := u.cameraRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	views := make([]*DeviceView, 0, len(devices))
+	for _, d := range devices {
+		views = append(views, &DeviceView{Device: d, Channels: channelsOf(d.ID, cams)})
+	}
+	return views, nil
+}
+
+// GetDevice returns a device with its channels.
+func (u *DeviceUsecase) GetDevice(id string) (*DeviceView, error) {
+	d, err := u.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrDeviceNotFound
+	}
+	channels, err := u.channels(d.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &DeviceView{Device: d, Channels: channels}, nil
+}
+
+// CreateDevice validates and stores a device and creates the requested
+// channels.
+func (u *DeviceUsecase) CreateDevice(dto *DeviceDTO) (*DeviceView, error) {
+	now := time.Now()
+	d := &domain.Device{
+		ID:          uuid.New().String(),
+		Name:        strings.TrimSpace(dto.Name),
+		Location:    dto.Location,
+		Vendor:      strings.ToLower(strings.TrimSpace(dto.Vendor)),
+		Host:        strings.TrimSpace(dto.Host),
+		Username:    dto.Username,
+		Password:    dto.Password,
+		URLTemplate: strings.TrimSpace(dto.URLTemplate),
+		Stream:      dto.Stream,
+		Health:      domain.DeviceHealth{Status: domain.DeviceStatusUnknown},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := validateDevice(d); err != nil {
+		return nil, err
+	}
+	if err := checkChannels(dto.Channels, nil); err != nil {
+		return nil, err
+	}
+	if err := u.repo.Create(d); err != nil {
+		return nil, err
+	}
+	publishDevice(events.DeviceCreated, d)
+	channels, err := u.addChannels(d, dto.Channels, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &DeviceView{Device: d, Channels: channels}, nil
+}
+
+// AddChannels creates channel cameras on a device. A camera that already
+// streams a new channel's URL, e.g. one added by hand before the device
+// existed, becomes that channel instead of being duplicated.
+func (u *DeviceUsecase) AddChannels(id string, channels []int) (*DeviceView, error) {
+	d, err := u.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrDeviceNotFound
+	}
+	existing, err := u.channels(d.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(channels) == 0 {
+		return nil, fmt.Errorf("%w: no channels given", ErrInvalidDevice)
+	}
+	if err := checkChannels(channels, existing); err != nil {
+		return nil, err
+	}
+	added, err := u.addChannels(d, channels, existing)
+	if err != nil {
+		return nil, err
+	}
+	return &DeviceView{Device: d, Channels: added}, nil
+}
+
+// UpdateDevice updates a device and moves every channel to the new host,
+// URL template, stream and credentials. Channels still named after the
+// device follow a rename.
+func (u *DeviceUsecase) UpdateDevice(dto *DeviceDTO) (*DeviceView, error) {
+	d, err := u.repo.GetByID(dto.ID)
+	if err != nil {
+		return nil, ErrDeviceNotFound
+	}
+	before := *d
+	if v := strings.TrimSpace(dto.Name); v != "" {
+		d.Name = v
+	}
+	if dto.Location != "" {
+		d.Location = dto.Location
+	}
+	if v := strings.TrimSpace(dto.Vendor); v != "" {
+		d.Vendor = strings.ToLower(v)
+	}
+	if v := strings.TrimSpace(dto.Host); v != "" {
+		d.Host = v
+	}
+	if dto.Username != "" {
+		d.Username = dto.Username
+	}
+	if dto.Password != "" {
+		d.Password = dto.Password
+	}
+	if v := strings.TrimSpace(dto.URLTemplate); v != "" {
+		d.URLTemplate = v
+	}
+	if dto.Stream != "" {
+		d.Stream = dto.Stream
+	}
+	if err := validateDevice(d); err != nil {
+		return nil, err
+	}
+	d.UpdatedAt = time.Now()
+	if err := u.repo.Update(d); err != nil {
+		return nil, err
+	}
+	publishDevice(events.DeviceUpdated, d)
+
+	channels, err := u.channels(d.ID)
+	if err != nil {
+		return nil, err
+	}
+	updated := make([]*domain.Camera, 0, len(channels))
+	for _, cam := range channels {
+		cdto := channelDTO(d, cam.Channel)
+		cdto.ID, cdto.Name = cam.ID, ""
+		if cam.Name == before.ChannelName(cam.Channel) {
+			cdto.Name = d.ChannelName(cam.Channel)
+		}
+		c, err := u.cameras.update(cdto, true)
+		if err != nil {
+			log.Printf("device %s: failed to update channel %d: %v", d.ID, cam.Channel, err)
+			continue
+		}
+		updated = append(updated, c)
+	}
+	return &DeviceView{Device: d, Channels: updated}, nil
+}
+
+// DeleteDevice removes a device and its channel cameras. footage says what
+// happens to the channels' recordings, as for DeleteCamera.
+func (u *DeviceUsecase) DeleteDevice(id string, footage FootageAction) error {
+	switch footage {
+	case "", FootageKeep, FootageArchive, FootagePurge:
+	default:
+		return fmt.Errorf("%w: footage must be keep, archive or purge", ErrInvalidDevice)
+	}
+	d, err := u.repo.GetByID(id)
+	if err != nil {
+		return ErrDeviceNotFound
+	}
+	channels, err := u.channels(d.ID)
+	if err != nil {
+		return err
+	}
+	// a channel whose footage could not be removed is still deleted, so
+	// the device goes too and the failure is reported at the end
+	var footageErr error
+	for _, cam := range channels {
+		err := u.cameras.DeleteCamera(cam.ID, footage)
+		switch {
+		case err == nil, errors.Is(err, ErrCameraNotFound):
+		case errors.Is(err, ErrFootageNotRemoved):
+			footageErr = err
+		default:
+			return err
+		}
+	}
+	if err := u.repo.Delete(d.ID); err != nil {
+		return err
+	}
+	publishDevice(events.DeviceDeleted, d)
+	return footageErr
+}
+
+// addChannels creates or adopts a camera for each channel.
+func (u *DeviceUsecase) addChannels(d *domain.Device, channels []int, existing []*domain.Camera) ([]*domain.Camera, error) {
+	cams, err := u.cameraRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	added := make([]*domain.Camera, 0, len(channels))
+	for _, ch := range channels {
+		dto := channelDTO(d, ch)
+		var cam *domain.Camera
+		if c := findStream(cams, dto.RTSPURL); c != nil {
+			log.Printf("device %s: camera %s (%s) becomes channel %d", d.ID, c.ID, c.Name, ch)
+			dto.ID, dto.Name = c.ID, ""
+			cam, err = u.cameras.update(dto, true)
+		} else {
+			dto.Location = d.Location
+			cam, err = u.cameras.CreateCamera(dto)
+		}
+		if err != nil {
+			return nil, err
+		}
+		added = append(added, cam)
+	}
+	return added, nil
+}
+
+func (u *DeviceUsecase) channels(deviceID string) ([]*domain.Camera, error) {
+	cams, err := u.cameraRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	return channelsOf(deviceID, cams), nil
+}
+
+func channelsOf(deviceID string, cams []*domain.Camera) []*domain.Camera {
+	channels := make([]*domain.Camera, 0)
+	for _, c := range cams {
+		if c.DeviceID == deviceID {
+			channels = append(channels, c)
+		}
+	}
+	sort.Slice(channels, func(i, j int) bool { return channels[i].Channel < channels[j].Channel })
+	return channels
+}
+
+// channelDTO is the camera a device channel should be.
+func channelDTO(d *domain.Device, channel int) *CameraDTO {
+	return &CameraDTO{
+		Name:     d.ChannelName(channel),
+		RTSPURL:  d.ChannelURL(channel),
+		Username: d.Username,
+		Password: d.Password,
+		DeviceID: d.ID,
+		Channel:  channel,
+	}
+}
+
+// findStream returns the camera that is not a device channel and streams
+// rawURL, ignoring credentials in the URL and the default port.
+func findStream(cams []*domain.Camera, rawURL string) *domain.Camera {
+	want := streamKey(rawURL)
+	for _, c := range cams {
+		if c.DeviceID == "" && streamKey(c.RTSPURL) == want {
+			return c
+		}
+	}
+	return nil
+}
+
+func streamKey(rawURL string) string {
+	scheme, rest, ok := strings.Cut(rawURL, "://")
+	if !ok {
+		return rawURL
+	}
+	authority, path := rest, ""
+	if i := strings.IndexAny(rest, "/?"); i >= 0 {
+		authority, path = rest[:i], rest[i:]
+	}
+	// passwords may contain @, so the host follows the last one
+	if i := strings.LastIndex(authority, "@"); i >= 0 {
+		authority = authority[i+1:]
+	}
+	authority = strings.TrimSuffix(strings.ToLower(authority), ":554")
+	return strings.ToLower(scheme) + "://" + authority + path
+}
+
+// checkChannels validates channel numbers and sorts them. Numbers already
+// taken by existing channels are rejected.
+func checkChannels(channels []int, existing []*domain.Camera) error {
+	taken := make(map[int]bool)
+	for _, c := range existing {
+		taken[c.Channel] = true
+	}
+	for _, ch := range channels {
+		if ch < 1 || ch > MaxDeviceChannel {
+			return fmt.Errorf("%w: channels must be between 1 and %d", ErrInvalidDevice, MaxDeviceChannel)
+		}
+		if taken[ch] {
+			return fmt.Errorf("%w: channel %d already exists", ErrInvalidDevice, ch)
+		}
+		taken[ch] = true
+	}
+	sort.Ints(channels)
+	return nil
+}
+
+func validateDevice(d *domain.Device) error {
+	if d.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidDevice)
+	}
+	if d.Host == "" || strings.ContainsAny(d.Host, "/@ ") {
+		return fmt.Errorf("%w: host must be a host name or address with an optional port", ErrInvalidDevice)
+	}
+	if d.Vendor == "" {
+		d.Vendor = domain.VendorGeneric
+	}
+	switch d.Stream {
+	case "":
+		d.Stream = domain.DeviceStreamMain
+	case domain.DeviceStreamMain, domain.DeviceStreamSub:
+	default:
+		return fmt.Errorf("%w: stream must be main or sub", ErrInvalidDevice)
+	}
+	tmpl := d.Template()
+	if tmpl == "" {
+		return fmt.Errorf("%w: url_template is required for %s devices", ErrInvalidDevice, d.Vendor)
+	}
+	if !strings.Contains(tmpl, "{channel}") {
+		return fmt.Errorf("%w: url_template must contain {channel}", ErrInvalidDevice)
+	}
+	u, err := url.Parse(d.ChannelURL(1))
+	if err != nil || (u.Scheme != "rtsp" && u.Scheme != "rtsps") || u.Host == "" {
+		return fmt.Errorf("%w: url_template must give an rtsp:// URL", ErrInvalidDevice)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: url_template must not contain credentials, set username and password instead", ErrInvalidDevice)
+	}
+	return nil
+}
+
+func publishDevice(eventType string, d *domain.Device) {
+	events.Publish(domain.Event{
+		Type:    eventType,
+		Payload: map[string]interface{}{"device_id": d.ID, "name": d.Name, "host": d.Host, "vendor": d.Vendor},
+	})
+}
@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/boytur/cctv-recording-center/server/internal/onvif"
+)
+
+// Discoverer finds ONVIF devices on the network.
+type Discoverer interface {
+	Discover(ctx context.Context) ([]onvif.Device, error)
+}
+
+// DiscoveredDevice is an ONVIF device found on the network and the cameras
+// already configured at the same address.
+type DiscoveredDevice struct {
+	onvif.Device
+	Configured bool     `json:"configured"`
+	CameraIDs  []string `json:"camera_ids,omitempty"`
+}
+
+// DiscoveryUsecase finds cameras to add.
+type DiscoveryUsecase struct {
+	discoverer Discoverer
+	cameras    CameraRepo
+}
+
+// NewDiscoveryUsecase creates a new DiscoveryUsecase.
+func NewDiscoveryUsecase(d Discoverer, cameras CameraRepo) *DiscoveryUsecase {
+	return &DiscoveryUsecase{discoverer: d, cameras: cameras}
+}
+
+// Discover probes the network until ctx is done and marks the devices whose
+// address matches the RTSP host of a configured camera.
+func (u *DiscoveryUsecase) Discover(ctx context.Context) ([]DiscoveredDevice, error) {
+	devices, err := u.discoverer.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cams, err := u.cameras.List()
+	if err != nil {
+		return nil, err
+	}
+	byHost := make(map[string][]string)
+	for _, c := range cams {
+		if h := rtspHost(c.RTSPURL); h != "" {
+			byHost[h] = append(byHost[h], c.ID)
+		}
+	}
+
+	res := make([]DiscoveredDevice, 0, len(devices))
+	for _, d := range devices {
+		ids := byHost[strings.ToLower(d.Address)]
+		res = append(res, DiscoveredDevice{Device: d, Configured: len(ids) > 0, CameraIDs: ids})
+	}
+	return res, nil
+}
+
+// rtspHost returns the lower-cased host of an RTSP URL. Passwords often
+// contain characters net/url rejects, so userinfo is cut at the last '@'.
+func rtspHost(raw string) string {
+	_, rest, ok := strings.Cut(raw, "://")
+	if !ok {
+		return ""
+	}
+	if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+		rest = rest[:i]
+	}
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		rest = rest[i+1:]
+	}
+	if h, _, err := net.SplitHostPort(rest); err == nil {
+		rest = h
+	}
+	return strings.ToLower(strings.Trim(rest, "[]"))
+}
@@ -0,0 +1,183 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrEmailGroupNotFound is returned for an unknown email group id.
+	ErrEmailGroupNotFound = errors.New("email group not found")
+	// ErrInvalidEmailGroup is returned when an email group fails validation.
+	ErrInvalidEmailGroup = errors.New("invalid email group")
+	// ErrEmailDisabled is returned when sending mail without SMTP configured.
+	ErrEmailDisabled = errors.New("smtp is not configured")
+)
+
+// EmailGroupRepo is the minimal interface the email group usecase depends on.
+type EmailGroupRepo interface {
+	List() ([]*domain.EmailGroup, error)
+	GetByID(id string) (*domain.EmailGroup, error)
+	Create(g *domain.EmailGroup) error
+	Update(g *domain.EmailGroup) error
+	Delete(id string) error
+}
+
+// EmailSender sends test messages and digests to a group.
+type EmailSender interface {
+	Enabled() bool
+	SendTest(ctx context.Context, g *domain.EmailGroup) error
+	SendDigest(ctx context.Context, g *domain.EmailGroup) error
+}
+
+// EmailGroupDTO is a transport-friendly email group for handlers. Nil
+// fields leave the existing value unchanged on update.
+type EmailGroupDTO struct {
+	ID             string
+	Name           string
+	Recipients     []string
+	CameraIDs      []string
+	Alerts         *bool
+	Digest         *bool
+	AttachSnapshot *bool
+}
+
+// EmailGroupUsecase contains business logic for email recipient groups.
+type EmailGroupUsecase struct {
+	repo   EmailGroupRepo
+	sender EmailSender
+}
+
+// NewEmailGroupUsecase creates a new EmailGroupUsecase.
+func NewEmailGroupUsecase(r EmailGroupRepo, sender EmailSender) *EmailGroupUsecase {
+	return &EmailGroupUsecase{repo: r, sender: sender}
+}
+
+// ListGroups returns all email groups.
+func (u *EmailGroupUsecase) ListGroups() ([]*domain.EmailGroup, error) {
+	return u.repo.List()
+}
+
+// GetGroup returns a single email group.
+func (u *EmailGroupUsecase) GetGroup(id string) (*domain.EmailGroup, error) {
+	g, err := u.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrEmailGroupNotFound
+	}
+	return g, nil
+}
+
+// CreateGroup validates and stores an email group. Alerts default to on,
+// the digest and snapshot attachments to off.
+func (u *EmailGroupUsecase) CreateGroup(dto *EmailGroupDTO) (*domain.EmailGroup, error) {
+	now := time.Now()
+	g := &domain.EmailGroup{
+		ID:         uuid.New().String(),
+		Name:       strings.TrimSpace(dto.Name),
+		Recipients: cleanList(dto.Recipients),
+		CameraIDs:  cleanList(dto.CameraIDs),
+		Alerts:     dto.Alerts == nil || *dto.Alerts,
+		Digest:     dto.Digest != nil && *dto.Digest,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if dto.AttachSnapshot != nil {
+		g.AttachSnapshot = *dto.AttachSnapshot
+	}
+	if g.CameraIDs == nil {
+		g.CameraIDs = []string{}
+	}
+	if err := validateEmailGroup(g); err != nil {
+		return nil, err
+	}
+	if err := u.repo.Create(g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// UpdateGroup updates the given fields of an existing email group.
+func (u *EmailGroupUsecase) UpdateGroup(dto *EmailGroupDTO) (*domain.EmailGroup, error) {
+	g, err := u.repo.GetByID(dto.ID)
+	if err != nil {
+		return nil, ErrEmailGroupNotFound
+	}
+	if dto.Name != "" {
+		g.Name = strings.TrimSpace(dto.Name)
+	}
+	if dto.Recipients != nil {
+		g.Recipients = cleanList(dto.Recipients)
+	}
+	if dto.CameraIDs != nil {
+		g.CameraIDs = cleanList(dto.CameraIDs)
+	}
+	if dto.Alerts != nil {
+		g.Alerts = *dto.Alerts
+	}
+	if dto.Digest != nil {
+		g.Digest = *dto.Digest
+	}
+	if dto.AttachSnapshot != nil {
+		g.AttachSnapshot = *dto.AttachSnapshot
+	}
+	g.UpdatedAt = time.Now()
+	if err := validateEmailGroup(g); err != nil {
+		return nil, err
+	}
+	if err := u.repo.Update(g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// DeleteGroup removes an email group.
+func (u *EmailGroupUsecase) DeleteGroup(id string) error {
+	return u.repo.Delete(id)
+}
+
+// SendTest sends a test email to the group.
+func (u *EmailGroupUsecase) SendTest(ctx context.Context, id string) error {
+	g, err := u.GetGroup(id)
+	if err != nil {
+		return err
+	}
+	if !u.sender.Enabled() {
+		return ErrEmailDisabled
+	}
+	return u.sender.SendTest(ctx, g)
+}
+
+// SendDigest sends the group its digest now instead of waiting for the
+// daily schedule.
+func (u *EmailGroupUsecase) SendDigest(ctx context.Context, id string) error {
+	g, err := u.GetGroup(id)
+	if err != nil {
+		return err
+	}
+	if !u.sender.Enabled() {
+		return ErrEmailDisabled
+	}
+	return u.sender.SendDigest(ctx, g)
+}
+
+func validateEmailGroup(g *domain.EmailGroup) error {
+	if g.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidEmailGroup)
+	}
+	if len(g.Recipients) == 0 {
+		return fmt.Errorf("%w: at least one recipient is required", ErrInvalidEmailGroup)
+	}
+	for _, r := range g.Recipients {
+		if _, err := mail.ParseAddress(r); err != nil {
+			return fmt.Errorf("%w: invalid recipient %q", ErrInvalidEmailGroup, r)
+		}
+	}
+	return nil
+}
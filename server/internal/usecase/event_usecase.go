@@ -0,0 +1,62 @@
+package usecase
+
+import "github.com/boytur/cctv-recording-center/server/internal/domain"
+
+// defaultEventPageSize is used when a listing does not give a limit.
+const defaultEventPageSize = 100
+
+// maxEventPageSize caps a single page of events.
+const maxEventPageSize = 1000
+
+// maxReplayEvents caps the events replayed to a reconnecting stream client.
+const maxReplayEvents = 1000
+
+// EventRepo is the minimal interface the event usecase depends on.
+type EventRepo interface {
+	List(f domain.EventFilter) ([]*domain.Event, int64, error)
+}
+
+// EventUsecase exposes the persisted event history.
+type EventUsecase struct {
+	repo EventRepo
+}
+
+// NewEventUsecase creates a new EventUsecase.
+func NewEventUsecase(r EventRepo) *EventUsecase {
+	return &EventUsecase{repo: r}
+}
+
+// ListEvents returns one page of events matching the filter and the total
+// number of matches. The limit defaults to 100 and is capped at 1000.
+func (u *EventUsecase) ListEvents(f domain.EventFilter) ([]*domain.Event, int64, error) {
+	if f.Limit <= 0 {
+		f.Limit = defaultEventPageSize
+	}
+	if f.Limit > maxEventPageSize {
+		f.Limit = maxEventPageSize
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+	return u.repo.List(f)
+}
+
+// EventsAfter returns the events matching the filter with an id greater than
+// afterID, oldest first. It is used to replay what a reconnecting client
+// missed. At most maxReplayEvents are returned, the most recent ones, and
+// missed reports how many there were in all.
+func (u *EventUsecase) EventsAfter(afterID uint64, f domain.EventFilter) (events []*domain.Event, missed int64, err error) {
+	f.AfterID = afterID
+	f.Limit = 1
+	f.Offset = 0
+	events, missed, err = u.repo.List(f)
+	if err != nil || missed <= 1 {
+		return events, missed, err
+	}
+	f.Limit = maxReplayEvents
+	if missed > maxReplayEvents {
+		f.Offset = int(missed - maxReplayEvents)
+	}
+	events, _, err = u.repo.List(f)
+	return events, missed, err
+}
@@ -0,0 +1,159 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/onvif"
+)
+
+var (
+	// ErrDeviceAuth is returned when an ONVIF device rejects the credentials.
+	ErrDeviceAuth = errors.New("device rejected the credentials")
+	// ErrDeviceUnavailable is returned when an ONVIF device can't be reached
+	// or answers with an error.
+	ErrDeviceUnavailable = errors.New("device unavailable")
+)
+
+// ONVIFDevice is what an ONVIF device reports about itself and the streams
+// it offers.
+type ONVIFDevice struct {
+	Address string            `json:"address"`
+	Info    domain.DeviceInfo `json:"info"`
+	Streams []onvif.Stream    `json:"streams"`
+}
+
+// AddONVIFCameraRequest asks for a camera to be created from one of an
+// ONVIF device's streams.
+type AddONVIFCameraRequest struct {
+	Address  string
+	Username string
+	Password string
+	// ProfileToken picks the stream; the main stream when empty.
+	ProfileToken string
+	// Name defaults to the device's manufacturer and model.
+	Name     string
+	Location string
+}
+
+// ONVIFUsecase adds cameras from ONVIF devices.
+type ONVIFUsecase struct {
+	cameras *CameraUsecase
+	repo    CameraRepo
+}
+
+// NewONVIFUsecase creates a new ONVIFUsecase.
+func NewONVIFUsecase(cameras *CameraUsecase, repo CameraRepo) *ONVIFUsecase {
+	return &ONVIFUsecase{cameras: cameras, repo: repo}
+}
+
+// InspectDevice reads a device's information and lists its streams with
+// their resolution and RTSP URI.
+func (u *ONVIFUsecase) InspectDevice(ctx context.Context, address, username, password string) (*ONVIFDevice, error) {
+	client, err := onvif.NewClient(address, username, password)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCamera, err)
+	}
+	info, err := client.DeviceInformation(ctx)
+	if err != nil {
+		return nil, deviceError(err)
+	}
+	streams, err := client.Streams(ctx)
+	if err != nil {
+		return nil, deviceError(err)
+	}
+	return &ONVIFDevice{Address: client.XAddr, Info: deviceInfo(info, client.XAddr, ""), Streams: streams}, nil
+}
+
+// AddCamera creates a camera from the chosen stream of a device, storing
+// the device information with it.
+func (u *ONVIFUsecase) AddCamera(ctx context.Context, req AddONVIFCameraRequest) (*domain.Camera, error) {
+	dev, err := u.InspectDevice(ctx, req.Address, req.Username, req.Password)
+	if err != nil {
+		return nil, err
+	}
+	if len(dev.Streams) == 0 {
+		return nil, fmt.Errorf("%w: device offers no streams", ErrDeviceUnavailable)
+	}
+	stream := dev.Streams[0]
+	if req.ProfileToken != "" {
+		found := false
+		for _, s := range dev.Streams {
+			if s.ProfileToken == req.ProfileToken {
+				stream, found = s, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: device has no profile %q", ErrInvalidCamera, req.ProfileToken)
+		}
+	}
+
+	name := req.Name
+	if name == "" {
+		name = strings.TrimSpace(dev.Info.Manufacturer + " " + dev.Info.Model)
+	}
+	if name == "" {
+		name = dev.Address
+	}
+	info := dev.Info
+	info.ProfileToken = stream.ProfileToken
+	return u.cameras.CreateCamera(&CameraDTO{
+		Name:     name,
+		Location: req.Location,
+		RTSPURL:  stream.URI,
+		Username: req.Username,
+		Password: req.Password,
+		Hardware: &info,
+	})
+}
+
+// RefreshHardware reads the device information of a camera added from an
+// ONVIF device again, e.g. after a firmware upgrade.
+func (u *ONVIFUsecase) RefreshHardware(ctx context.Context, cameraID string) (*domain.Camera, error) {
+	cam, err := u.repo.GetByID(cameraID)
+	if err != nil {
+		return nil, ErrCameraNotFound
+	}
+	if cam.Hardware == nil || cam.Hardware.ONVIFAddress == "" {
+		return nil, fmt.Errorf("%w: camera was not added from an ONVIF device", ErrInvalidCamera)
+	}
+	client, err := onvif.NewClient(cam.Hardware.ONVIFAddress, cam.Username, cam.Password)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCamera, err)
+	}
+	info, err := client.DeviceInformation(ctx)
+	if err != nil {
+		return nil, deviceError(err)
+	}
+	d := deviceInfo(info, client.XAddr, cam.Hardware.ProfileToken)
+	cam.Hardware = &d
+	if err := u.repo.UpdateHardware(cam.ID, cam.Hardware); err != nil {
+		return nil, err
+	}
+	return cam, nil
+}
+
+func deviceInfo(info *onvif.DeviceInformation, addr, profileToken string) domain.DeviceInfo {
+	return domain.DeviceInfo{
+		Manufacturer:    strings.TrimSpace(info.Manufacturer),
+		Model:           strings.TrimSpace(info.Model),
+		FirmwareVersion: strings.TrimSpace(info.FirmwareVersion),
+		SerialNumber:    strings.TrimSpace(info.SerialNumber),
+		HardwareID:      strings.TrimSpace(info.HardwareID),
+		ONVIFAddress:    addr,
+		ProfileToken:    profileToken,
+		UpdatedAt:       time.Now().UTC(),
+	}
+}
+
+func deviceError(err error) error {
+	if errors.Is(err, onvif.ErrUnauthorized) {
+		return fmt.Errorf("%w: %v", ErrDeviceAuth, err)
+	}
+	return fmt.Errorf("%w: %v", ErrDeviceUnavailable, err)
+}
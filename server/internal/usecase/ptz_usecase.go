@@ -0,0 +1,818 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/events"
+	"github.com/boytur/cctv-recording-center/server/internal/onvif"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrPTZUnsupported is returned for cameras that were not added from an
+	// ONVIF device or whose device has no PTZ.
+	ErrPTZUnsupported = errors.New("camera does not support PTZ")
+	// ErrPTZLocked is returned when another operator controls the camera.
+	ErrPTZLocked = errors.New("camera is controlled by another operator")
+	// ErrInvalidPTZCommand is returned when a PTZ command fails validation.
+	ErrInvalidPTZCommand = errors.New("invalid PTZ command")
+	// ErrPatrolTourNotFound is returned for an unknown patrol tour id.
+	ErrPatrolTourNotFound = errors.New("patrol tour not found")
+	// ErrInvalidPatrolTour is returned when a patrol tour fails validation.
+	ErrInvalidPatrolTour = errors.New("invalid patrol tour")
+)
+
+// PTZ move modes.
+const (
+	PTZContinuous = "continuous"
+	PTZAbsolute   = "absolute"
+	PTZRelative   = "relative"
+)
+
+const (
+	// PTZLockTTL is how long an operator keeps control of a camera after
+	// their last command.
+	PTZLockTTL = 2 * time.Minute
+	// MaxPTZLock is the longest an operator can take control for at once.
+	MaxPTZLock = 30 * time.Minute
+	// DefaultContinuousTimeout stops a continuous move that is never
+	// stopped, e.g. because the operator's browser went away.
+	DefaultContinuousTimeout = 10 * time.Second
+	// MaxContinuousTimeout is the longest a continuous move may run.
+	MaxContinuousTimeout = time.Minute
+
+	// MinPatrolDwell and MaxPatrolDwell bound the seconds a tour stays at a
+	// preset.
+	MinPatrolDwell = 5
+	MaxPatrolDwell = 3600
+
+	// patrolSyncInterval is how often tours are started and stopped to
+	// follow their schedules.
+	patrolSyncInterval = 30 * time.Second
+	// ptzCallTimeout bounds a PTZ request made by a patrol tour.
+	ptzCallTimeout = 10 * time.Second
+)
+
+// PatrolTourRepo is the minimal interface the PTZ usecase depends on for
+// patrol tours.
+type PatrolTourRepo interface {
+	List(cameraID string) ([]*domain.PatrolTour, error)
+	GetByID(id string) (*domain.PatrolTour, error)
+	Create(t *domain.PatrolTour) error
+	Update(t *domain.PatrolTour) error
+	Delete(id string) error
+	DeleteByCamera(cameraID string) error
+}
+
+// PTZCommand is a move an operator asks for.
+type PTZCommand struct {
+	// Mode is PTZContinuous (velocities), PTZAbsolute (a position) or
+	// PTZRelative (a translation from the current position).
+	Mode string
+	onvif.Vector
+	// Speed from 0 to 1 for absolute and relative moves; nil uses the
+	// camera's default.
+	Speed *float64
+	// Timeout stops a continuous move, DefaultContinuousTimeout if zero.
+	Timeout time.Duration
+}
+
+// PTZStatus is who controls a camera and the patrol tour running on it.
+type PTZStatus struct {
+	CameraID string          `json:"camera_id"`
+	Lock     *domain.PTZLock `json:"lock,omitempty"`
+	Patrol   *PatrolState    `json:"patrol,omitempty"`
+}
+
+// PatrolState is the progress of a running patrol tour.
+type PatrolState struct {
+	TourID    string    `json:"tour_id"`
+	TourName  string    `json:"tour_name"`
+	Step      int       `json:"step"`
+	Preset    string    `json:"preset_token,omitempty"`
+	Paused    bool      `json:"paused"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// PatrolTourDTO is a transport-friendly patrol tour for handlers. Nil
+// fields leave the existing value unchanged on update; an empty StartTime
+// and EndTime clear the daily window.
+type PatrolTourDTO struct {
+	ID        string
+	CameraID  string
+	Name      string
+	Steps     []domain.PatrolStep
+	Enabled   *bool
+	Days      []int
+	StartTime *string
+	EndTime   *string
+	CreatedBy string
+}
+
+// ptzDevice is the ONVIF client of a camera and the profile its PTZ
+// commands go to.
+type ptzDevice struct {
+	// key is the address and credentials the client was made with.
+	key     string
+	client  *onvif.Client
+	profile string
+}
+
+type patrolRun struct {
+	tour  *domain.PatrolTour
+	state PatrolState
+	stop  chan struct{}
+}
+
+// PTZUsecase controls PTZ cameras over ONVIF. Operators take a per-camera
+// control lock with their first command, and every command is published as
+// an event so the event log doubles as the PTZ audit trail, which the event
+// retention keeps for its own period (see events.AuditPrefixes). It also runs
+// the patrol tours.
+type PTZUsecase struct {
+	cameras CameraRepo
+	tours   PatrolTourRepo
+
+	mu       sync.Mutex
+	devices  map[string]*ptzDevice
+	locks    map[string]*domain.PTZLock
+	patrols  map[string]*patrolRun
+	stopped  bool
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewPTZUsecase creates a new PTZUsecase.
+func NewPTZUsecase(cameras CameraRepo, tours PatrolTourRepo) *PTZUsecase {
+	return &PTZUsecase{
+		cameras:  cameras,
+		tours:    tours,
+		devices:  make(map[string]*ptzDevice),
+		locks:    make(map[string]*domain.PTZLock),
+		patrols:  make(map[string]*patrolRun),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start runs the patrol tours that are due and keeps following their
+// schedules. Tours of deleted cameras are removed.
+func (u *PTZUsecase) Start() {
+	ch, cancel := events.Subscribe(16, events.OfTypes(events.CameraUpdated, events.CameraDeleted))
+	u.wg.Add(1)
+	go u.run(ch, cancel)
+}
+
+// Stop stops the patrol tours and waits for their last command to finish.
+func (u *PTZUsecase) Stop() {
+	u.mu.Lock()
+	u.stopped = true
+	for id, run := range u.patrols {
+		close(run.stop)
+		delete(u.patrols, id)
+	}
+	u.mu.Unlock()
+	close(u.stopChan)
+	u.wg.Wait()
+}
+
+func (u *PTZUsecase) run(ch <-chan domain.Event, cancel func()) {
+	defer u.wg.Done()
+	defer cancel()
+	ticker := time.NewTicker(patrolSyncInterval)
+	defer ticker.Stop()
+
+	u.syncPatrols()
+	for {
+		select {
+		case e := <-ch:
+			u.mu.Lock()
+			// address or credentials may have changed
+			delete(u.devices, e.CameraID)
+			if e.Type == events.CameraDeleted {
+				delete(u.locks, e.CameraID)
+			}
+			u.mu.Unlock()
+			if e.Type == events.CameraDeleted {
+				if err := u.tours.DeleteByCamera(e.CameraID); err != nil {
+					log.Printf("ptz: failed to delete patrol tours of camera %s: %v", e.CameraID, err)
+				}
+				u.syncPatrols()
+			}
+		case <-ticker.C:
+			u.syncPatrols()
+		case <-u.stopChan:
+			return
+		}
+	}
+}
+
+// Status returns who controls a camera and the patrol tour running on it.
+func (u *PTZUsecase) Status(cameraID string) (*PTZStatus, error) {
+	if _, err := u.cameras.GetByID(cameraID); err != nil {
+		return nil, ErrCameraNotFound
+	}
+	status := &PTZStatus{CameraID: cameraID}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if l := u.locks[cameraID]; l.Held(time.Now()) {
+		lock := *l
+		status.Lock = &lock
+	}
+	if run, ok := u.patrols[cameraID]; ok {
+		state := run.state
+		state.Paused = status.Lock != nil
+		status.Patrol = &state
+	}
+	return status, nil
+}
+
+// Lock gives an operator control of a camera for ttl, PTZLockTTL if zero.
+// The operator holding the lock can renew it.
+func (u *PTZUsecase) Lock(cameraID, operator string, ttl time.Duration) (*domain.PTZLock, error) {
+	if ttl < 0 || ttl > MaxPTZLock {
+		return nil, fmt.Errorf("%w: lock duration must be at most %s", ErrInvalidPTZCommand, MaxPTZLock)
+	}
+	cam, err := u.ptzCamera(cameraID)
+	if err != nil {
+		return nil, err
+	}
+	return u.acquire(cam, operator, ttl)
+}
+
+// Unlock releases an operator's control of a camera. force releases
+// another operator's lock.
+func (u *PTZUsecase) Unlock(cameraID, operator string, force bool) error {
+	cam, err := u.cameras.GetByID(cameraID)
+	if err != nil {
+		return ErrCameraNotFound
+	}
+	u.mu.Lock()
+	l := u.locks[cameraID]
+	if !l.Held(time.Now()) {
+		delete(u.locks, cameraID)
+		u.mu.Unlock()
+		return nil
+	}
+	if l.Operator != operator && !force {
+		u.mu.Unlock()
+		return ErrPTZLocked
+	}
+	delete(u.locks, cameraID)
+	u.mu.Unlock()
+
+	payload := map[string]interface{}{"operator": operator}
+	if l.Operator != operator {
+		payload["holder"] = l.Operator
+		payload["forced"] = true
+	}
+	publishPTZ(events.PTZUnlocked, cam, payload, nil)
+	return nil
+}
+
+// Move sends a continuous, absolute or relative move.
+func (u *PTZUsecase) Move(ctx context.Context, cameraID, operator string, cmd PTZCommand) error {
+	if err := validatePTZCommand(&cmd); err != nil {
+		return err
+	}
+	cam, dev, err := u.control(ctx, cameraID, operator)
+	if err != nil {
+		return err
+	}
+	payload := map[string]interface{}{"operator": operator, "action": cmd.Mode}
+	addVector(payload, cmd.Vector)
+	switch cmd.Mode {
+	case PTZContinuous:
+		payload["timeout_ms"] = cmd.Timeout.Milliseconds()
+		err = dev.client.ContinuousMove(ctx, dev.profile, cmd.Vector, cmd.Timeout)
+	case PTZAbsolute:
+		err = dev.client.AbsoluteMove(ctx, dev.profile, cmd.Vector, speedVector(cmd.Speed))
+	case PTZRelative:
+		err = dev.client.RelativeMove(ctx, dev.profile, cmd.Vector, speedVector(cmd.Speed))
+	}
+	if cmd.Speed != nil {
+		payload["speed"] = *cmd.Speed
+	}
+	return u.audit(cam, payload, err)
+}
+
+// StopMove stops a camera's pan, tilt and zoom movement.
+func (u *PTZUsecase) StopMove(ctx context.Context, cameraID, operator string) error {
+	cam, dev, err := u.control(ctx, cameraID, operator)
+	if err != nil {
+		return err
+	}
+	err = dev.client.Stop(ctx, dev.profile)
+	return u.audit(cam, map[string]interface{}{"operator": operator, "action": "stop"}, err)
+}
+
+// Presets lists the presets stored on a camera.
+func (u *PTZUsecase) Presets(ctx context.Context, cameraID string) ([]onvif.Preset, error) {
+	cam, err := u.ptzCamera(cameraID)
+	if err != nil {
+		return nil, err
+	}
+	dev, err := u.device(ctx, cam)
+	if err != nil {
+		return nil, err
+	}
+	presets, err := dev.client.Presets(ctx, dev.profile)
+	if err != nil {
+		return nil, ptzError(err)
+	}
+	return presets, nil
+}
+
+// GotoPreset moves a camera to a preset.
+func (u *PTZUsecase) GotoPreset(ctx context.Context, cameraID, operator, presetToken string, speed *float64) error {
+	if presetToken == "" {
+		return fmt.Errorf("%w: preset token is required", ErrInvalidPTZCommand)
+	}
+	if err := validateSpeed(speed); err != nil {
+		return err
+	}
+	cam, dev, err := u.control(ctx, cameraID, operator)
+	if err != nil {
+		return err
+	}
+	err = dev.client.GotoPreset(ctx, dev.profile, presetToken, speedVector(speed))
+	payload := map[string]interface{}{"operator": operator, "action": "goto_preset", "preset_token": presetToken}
+	if speed != nil {
+		payload["speed"] = *speed
+	}
+	return u.audit(cam, payload, err)
+}
+
+// SavePreset stores a camera's current position as a preset, overwriting
+// the preset with presetToken if given.
+func (u *PTZUsecase) SavePreset(ctx context.Context, cameraID, operator, name, presetToken string) (*onvif.Preset, error) {
+	name = strings.TrimSpace(name)
+	if name == "" && presetToken == "" {
+		return nil, fmt.Errorf("%w: preset name is required", ErrInvalidPTZCommand)
+	}
+	cam, dev, err := u.control(ctx, cameraID, operator)
+	if err != nil {
+		return nil, err
+	}
+	token, err := dev.client.SetPreset(ctx, dev.profile, name, presetToken)
+	payload := map[string]interface{}{"operator": operator, "preset_token": token, "preset_name": name}
+	publishPTZ(events.PTZPresetSaved, cam, payload, err)
+	if err != nil {
+		return nil, ptzError(err)
+	}
+	return &onvif.Preset{Token: token, Name: name}, nil
+}
+
+// RemovePreset deletes a preset from a camera.
+func (u *PTZUsecase) RemovePreset(ctx context.Context, cameraID, operator, presetToken string) error {
+	cam, dev, err := u.control(ctx, cameraID, operator)
+	if err != nil {
+		return err
+	}
+	err = dev.client.RemovePreset(ctx, dev.profile, presetToken)
+	publishPTZ(events.PTZPresetRemoved, cam, map[string]interface{}{"operator": operator, "preset_token": presetToken}, err)
+	if err != nil {
+		return ptzError(err)
+	}
+	return nil
+}
+
+// ListTours returns the patrol tours of a camera, or of all cameras when
+// cameraID is empty.
+func (u *PTZUsecase) ListTours(cameraID string) ([]*domain.PatrolTour, error) {
+	return u.tours.List(cameraID)
+}
+
+// GetTour returns a single patrol tour.
+func (u *PTZUsecase) GetTour(id string) (*domain.PatrolTour, error) {
+	t, err := u.tours.GetByID(id)
+	if err != nil {
+		return nil, ErrPatrolTourNotFound
+	}
+	return t, nil
+}
+
+// CreateTour validates and stores a patrol tour for a PTZ camera. Tours are
+// enabled unless asked otherwise and start as soon as their schedule
+// allows.
+func (u *PTZUsecase) CreateTour(dto *PatrolTourDTO) (*domain.PatrolTour, error) {
+	if _, err := u.ptzCamera(dto.CameraID); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	t := &domain.PatrolTour{
+		ID:        uuid.New().String(),
+		CameraID:  dto.CameraID,
+		Name:      strings.TrimSpace(dto.Name),
+		Steps:     dto.Steps,
+		Enabled:   dto.Enabled == nil || *dto.Enabled,
+		Days:      dto.Days,
+		CreatedBy: dto.CreatedBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if dto.StartTime != nil {
+		t.StartTime = strings.TrimSpace(*dto.StartTime)
+	}
+	if dto.EndTime != nil {
+		t.EndTime = strings.TrimSpace(*dto.EndTime)
+	}
+	if err := validatePatrolTour(t); err != nil {
+		return nil, err
+	}
+	if err := u.tours.Create(t); err != nil {
+		return nil, err
+	}
+	u.syncPatrols()
+	return t, nil
+}
+
+// UpdateTour updates the given fields of a patrol tour. A running tour
+// starts over with the new settings.
+func (u *PTZUsecase) UpdateTour(dto *PatrolTourDTO) (*domain.PatrolTour, error) {
+	t, err := u.tours.GetByID(dto.ID)
+	if err != nil {
+		return nil, ErrPatrolTourNotFound
+	}
+	if dto.Name != "" {
+		t.Name = strings.TrimSpace(dto.Name)
+	}
+	if dto.Steps != nil {
+		t.Steps = dto.Steps
+	}
+	if dto.Enabled != nil {
+		t.Enabled = *dto.Enabled
+	}
+	if dto.Days != nil {
+		t.Days = dto.Days
+	}
+	if dto.StartTime != nil {
+		t.StartTime = strings.TrimSpace(*dto.StartTime)
+	}
+	if dto.EndTime != nil {
+		t.EndTime = strings.TrimSpace(*dto.EndTime)
+	}
+	if err := validatePatrolTour(t); err != nil {
+		return nil, err
+	}
+	t.UpdatedAt = time.Now()
+	if err := u.tours.Update(t); err != nil {
+		return nil, err
+	}
+	u.syncPatrols()
+	return t, nil
+}
+
+// DeleteTour removes a patrol tour, stopping it if it is running.
+func (u *PTZUsecase) DeleteTour(id string) error {
+	if _, err := u.tours.GetByID(id); err != nil {
+		return ErrPatrolTourNotFound
+	}
+	if err := u.tours.Delete(id); err != nil {
+		return err
+	}
+	u.syncPatrols()
+	return nil
+}
+
+// ptzCamera loads a camera that can be controlled over ONVIF.
+func (u *PTZUsecase) ptzCamera(cameraID string) (*domain.Camera, error) {
+	cam, err := u.cameras.GetByID(cameraID)
+	if err != nil {
+		return nil, ErrCameraNotFound
+	}
+	if cam.Hardware == nil || cam.Hardware.ONVIFAddress == "" {
+		return nil, fmt.Errorf("%w: camera was not added from an ONVIF device", ErrPTZUnsupported)
+	}
+	return cam, nil
+}
+
+// control takes or renews an operator's lock on a camera and returns the
+// device to send the command to.
+func (u *PTZUsecase) control(ctx context.Context, cameraID, operator string) (*domain.Camera, *ptzDevice, error) {
+	cam, err := u.ptzCamera(cameraID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := u.acquire(cam, operator, PTZLockTTL); err != nil {
+		return nil, nil, err
+	}
+	dev, err := u.device(ctx, cam)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cam, dev, nil
+}
+
+// acquire gives the operator the camera's lock, or renews it, unless
+// someone else holds it. A lock is never shortened by a renewal.
+func (u *PTZUsecase) acquire(cam *domain.Camera, operator string, ttl time.Duration) (*domain.PTZLock, error) {
+	if ttl == 0 {
+		ttl = PTZLockTTL
+	}
+	now := time.Now()
+	u.mu.Lock()
+	l := u.locks[cam.ID]
+	held := l.Held(now)
+	if held && l.Operator != operator {
+		u.mu.Unlock()
+		return nil, ErrPTZLocked
+	}
+	if !held {
+		l = &domain.PTZLock{CameraID: cam.ID, Operator: operator, AcquiredAt: now}
+		u.locks[cam.ID] = l
+	}
+	if until := now.Add(ttl); until.After(l.ExpiresAt) {
+		l.ExpiresAt = until
+	}
+	lock := *l
+	u.mu.Unlock()
+
+	if !held {
+		publishPTZ(events.PTZLocked, cam, map[string]interface{}{"operator": operator, "expires_at": lock.ExpiresAt}, nil)
+	}
+	return &lock, nil
+}
+
+// device returns the ONVIF client of a camera, creating it and picking the
+// PTZ profile the first time: the camera's own profile if it has PTZ,
+// otherwise the first one that does.
+func (u *PTZUsecase) device(ctx context.Context, cam *domain.Camera) (*ptzDevice, error) {
+	key := cam.Hardware.ONVIFAddress + "\x00" + cam.Username + "\x00" + cam.Password
+	u.mu.Lock()
+	dev := u.devices[cam.ID]
+	u.mu.Unlock()
+	if dev != nil && dev.key == key {
+		return dev, nil
+	}
+
+	client, err := onvif.NewClient(cam.Hardware.ONVIFAddress, cam.Username, cam.Password)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPTZUnsupported, err)
+	}
+	profiles, err := client.Profiles(ctx)
+	if err != nil {
+		return nil, ptzError(err)
+	}
+	dev = &ptzDevice{key: key, client: client}
+	for _, p := range profiles {
+		if p.PTZ && (dev.profile == "" || p.Token == cam.Hardware.ProfileToken) {
+			dev.profile = p.Token
+		}
+	}
+	if dev.profile == "" {
+		return nil, fmt.Errorf("%w: device has no PTZ profile", ErrPTZUnsupported)
+	}
+	u.mu.Lock()
+	u.devices[cam.ID] = dev
+	u.mu.Unlock()
+	return dev, nil
+}
+
+// audit publishes a PTZ command and its outcome and turns a device error
+// into a usecase error.
+func (u *PTZUsecase) audit(cam *domain.Camera, payload map[string]interface{}, err error) error {
+	publishPTZ(events.PTZMoved, cam, payload, err)
+	if err != nil {
+		return ptzError(err)
+	}
+	return nil
+}
+
+// syncPatrols starts the enabled tours whose schedule allows them to run
+// and stops the others. A camera runs one tour at a time, the oldest one
+// that is due.
+func (u *PTZUsecase) syncPatrols() {
+	tours, err := u.tours.List("")
+	if err != nil {
+		log.Printf("ptz: failed to list patrol tours: %v", err)
+		return
+	}
+	now := time.Now()
+	due := make(map[string]*domain.PatrolTour)
+	for _, t := range tours {
+		if t.Enabled && len(t.Steps) > 0 && t.ActiveAt(now) && due[t.CameraID] == nil {
+			due[t.CameraID] = t
+		}
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.stopped {
+		return
+	}
+	for cameraID, run := range u.patrols {
+		t := due[cameraID]
+		if t != nil && t.ID == run.tour.ID && t.UpdatedAt.Equal(run.tour.UpdatedAt) {
+			continue
+		}
+		close(run.stop)
+		delete(u.patrols, cameraID)
+		events.Publish(domain.Event{
+			Type:     events.PatrolStopped,
+			CameraID: cameraID,
+			Payload:  map[string]interface{}{"tour_id": run.tour.ID, "tour_name": run.tour.Name},
+		})
+	}
+	for cameraID, t := range due {
+		if _, running := u.patrols[cameraID]; running {
+			continue
+		}
+		run := &patrolRun{
+			tour:  t,
+			state: PatrolState{TourID: t.ID, TourName: t.Name, StartedAt: now},
+			stop:  make(chan struct{}),
+		}
+		u.patrols[cameraID] = run
+		u.wg.Add(1)
+		go u.patrol(run)
+		events.Publish(domain.Event{
+			Type:     events.PatrolStarted,
+			CameraID: cameraID,
+			Payload:  map[string]interface{}{"tour_id": t.ID, "tour_name": t.Name, "steps": len(t.Steps)},
+		})
+	}
+}
+
+// patrol visits the presets of a tour in turn until the tour is stopped.
+func (u *PTZUsecase) patrol(run *patrolRun) {
+	defer u.wg.Done()
+	steps := run.tour.Steps
+	for i := 0; ; i = (i + 1) % len(steps) {
+		u.patrolStep(run, i)
+		select {
+		case <-time.After(time.Duration(steps[i].DwellSeconds) * time.Second):
+		case <-run.stop:
+			return
+		}
+	}
+}
+
+// patrolStep moves the camera to a tour's preset. The step is skipped while
+// an operator holds the camera or the camera is offline or out of service.
+func (u *PTZUsecase) patrolStep(run *patrolRun, i int) {
+	step := run.tour.Steps[i]
+	u.mu.Lock()
+	run.state.Step, run.state.Preset = i, step.PresetToken
+	locked := u.locks[run.tour.CameraID].Held(time.Now())
+	u.mu.Unlock()
+	if locked {
+		return
+	}
+	cam, err := u.ptzCamera(run.tour.CameraID)
+	if err != nil || cam.Status != domain.CameraStatusOnline || cam.ServiceStatus(time.Now()) != "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ptzCallTimeout)
+	defer cancel()
+	dev, err := u.device(ctx, cam)
+	if err == nil {
+		err = dev.client.GotoPreset(ctx, dev.profile, step.PresetToken, speedVector(step.Speed))
+	}
+	payload := map[string]interface{}{"action": "goto_preset", "preset_token": step.PresetToken, "tour_id": run.tour.ID, "tour_name": run.tour.Name}
+	if err := u.audit(cam, payload, err); err != nil {
+		log.Printf("ptz: patrol %q on camera %s failed at preset %s: %v", run.tour.Name, cam.ID, step.PresetToken, err)
+	}
+}
+
+func validatePTZCommand(cmd *PTZCommand) error {
+	v := cmd.Vector
+	if v.Pan == nil && v.Tilt == nil && v.Zoom == nil {
+		return fmt.Errorf("%w: pan, tilt or zoom is required", ErrInvalidPTZCommand)
+	}
+	zoomMin := -1.0
+	switch cmd.Mode {
+	case PTZContinuous:
+		if cmd.Timeout == 0 {
+			cmd.Timeout = DefaultContinuousTimeout
+		}
+		if cmd.Timeout < 0 || cmd.Timeout > MaxContinuousTimeout {
+			return fmt.Errorf("%w: timeout must be at most %s", ErrInvalidPTZCommand, MaxContinuousTimeout)
+		}
+	case PTZAbsolute:
+		if (v.Pan == nil) != (v.Tilt == nil) {
+			return fmt.Errorf("%w: an absolute move needs both pan and tilt", ErrInvalidPTZCommand)
+		}
+		zoomMin = 0
+	case PTZRelative:
+	default:
+		return fmt.Errorf("%w: mode must be %s, %s or %s", ErrInvalidPTZCommand, PTZContinuous, PTZAbsolute, PTZRelative)
+	}
+	if !inRange(v.Pan, -1, 1) || !inRange(v.Tilt, -1, 1) {
+		return fmt.Errorf("%w: pan and tilt must be between -1 and 1", ErrInvalidPTZCommand)
+	}
+	if !inRange(v.Zoom, zoomMin, 1) {
+		return fmt.Errorf("%w: zoom must be between %g and 1", ErrInvalidPTZCommand, zoomMin)
+	}
+	return validateSpeed(cmd.Speed)
+}
+
+func validateSpeed(speed *float64) error {
+	if !inRange(speed, 0, 1) {
+		return fmt.Errorf("%w: speed must be between 0 and 1", ErrInvalidPTZCommand)
+	}
+	return nil
+}
+
+func validatePatrolTour(t *domain.PatrolTour) error {
+	if t.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidPatrolTour)
+	}
+	if len(t.Steps) == 0 {
+		return fmt.Errorf("%w: at least one step is required", ErrInvalidPatrolTour)
+	}
+	for i, s := range t.Steps {
+		if strings.TrimSpace(s.PresetToken) == "" {
+			return fmt.Errorf("%w: step %d has no preset token", ErrInvalidPatrolTour, i+1)
+		}
+		if s.DwellSeconds < MinPatrolDwell || s.DwellSeconds > MaxPatrolDwell {
+			return fmt.Errorf("%w: step %d dwell must be between %d and %d seconds", ErrInvalidPatrolTour, i+1, MinPatrolDwell, MaxPatrolDwell)
+		}
+		if !inRange(s.Speed, 0, 1) {
+			return fmt.Errorf("%w: step %d speed must be between 0 and 1", ErrInvalidPatrolTour, i+1)
+		}
+	}
+	seen := make(map[int]bool)
+	days := make([]int, 0, len(t.Days))
+	for _, d := range t.Days {
+		if d < 0 || d > 6 {
+			return fmt.Errorf("%w: days must be between 0 (Sunday) and 6 (Saturday)", ErrInvalidPatrolTour)
+		}
+		if !seen[d] {
+			seen[d] = true
+			days = append(days, d)
+		}
+	}
+	sort.Ints(days)
+	t.Days = days
+	if (t.StartTime == "") != (t.EndTime == "") {
+		return fmt.Errorf("%w: start_time and end_time go together", ErrInvalidPatrolTour)
+	}
+	if t.StartTime != "" {
+		start, err1 := time.Parse("15:04", t.StartTime)
+		end, err2 := time.Parse("15:04", t.EndTime)
+		if err1 != nil || err2 != nil {
+			return fmt.Errorf("%w: start_time and end_time must be HH:MM", ErrInvalidPatrolTour)
+		}
+		if start.Equal(end) {
+			return fmt.Errorf("%w: start_time and end_time must differ", ErrInvalidPatrolTour)
+		}
+	}
+	return nil
+}
+
+func inRange(v *float64, min, max float64) bool {
+	return v == nil || (*v >= min && *v <= max)
+}
+
+// speedVector turns a single speed into the same speed on every axis.
+func speedVector(speed *float64) *onvif.Vector {
+	if speed == nil {
+		return nil
+	}
+	return &onvif.Vector{Pan: speed, Tilt: speed, Zoom: speed}
+}
+
+func addVector(payload map[string]interface{}, v onvif.Vector) {
+	if v.Pan != nil {
+		payload["pan"] = *v.Pan
+	}
+	if v.Tilt != nil {
+		payload["tilt"] = *v.Tilt
+	}
+	if v.Zoom != nil {
+		payload["zoom"] = *v.Zoom
+	}
+}
+
+// publishPTZ records a PTZ action in the event log. Failed actions are
+// recorded too, as warnings with the device error.
+func publishPTZ(eventType string, cam *domain.Camera, payload map[string]interface{}, err error) {
+	payload["name"] = cam.Name
+	e := domain.Event{Type: eventType, CameraID: cam.ID, Severity: domain.SeverityInfo, Payload: payload}
+	if err != nil {
+		e.Severity = domain.SeverityWarning
+		payload["error"] = err.Error()
+	}
+	events.Publish(e)
+}
+
+func ptzError(err error) error {
+	if errors.Is(err, onvif.ErrNoService) {
+		return fmt.Errorf("%w: %v", ErrPTZUnsupported, err)
+	}
+	// the device refused the arguments, e.g. an unknown preset token
+	var fault *onvif.Fault
+	if errors.As(err, &fault) && strings.HasSuffix(fault.Code, "Sender") && !errors.Is(err, onvif.ErrUnauthorized) {
+		return fmt.Errorf("%w: %v", ErrInvalidPTZCommand, err)
+	}
+	return deviceError(err)
+}
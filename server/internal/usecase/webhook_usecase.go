@@ -0,0 +1,217 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrWebhookNotFound is returned for an unknown webhook or delivery id.
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrInvalidWebhook is returned when a webhook fails validation.
+	ErrInvalidWebhook = errors.New("invalid webhook")
+)
+
+// WebhookRepo is the minimal interface the webhook usecase depends on.
+type WebhookRepo interface {
+	List() ([]*domain.Webhook, error)
+	GetByID(id string) (*domain.Webhook, error)
+	Create(w *domain.Webhook) error
+	Update(w *domain.Webhook) error
+	Delete(id string) error
+	GetDelivery(id string) (*domain.WebhookDelivery, error)
+	ListDeliveries(webhookID, status string, limit int) ([]*domain.WebhookDelivery, error)
+}
+
+// WebhookSender sends test deliveries and requeues failed ones.
+type WebhookSender interface {
+	Test(w *domain.Webhook) (*domain.WebhookDelivery, error)
+	Redeliver(d *domain.WebhookDelivery) error
+}
+
+// WebhookDTO is a transport-friendly webhook representation for handlers.
+// Nil slices and Enabled leave the existing value unchanged on update.
+type WebhookDTO struct {
+	ID         string
+	Name       string
+	URL        string
+	EventTypes []string
+	CameraIDs  []string
+	Secret     string
+	Enabled    *bool
+}
+
+// WebhookUsecase contains business logic for webhook subscriptions.
+type WebhookUsecase struct {
+	repo   WebhookRepo
+	sender WebhookSender
+}
+
+// NewWebhookUsecase creates a new WebhookUsecase.
+func NewWebhookUsecase(r WebhookRepo, sender WebhookSender) *WebhookUsecase {
+	return &WebhookUsecase{repo: r, sender: sender}
+}
+
+// ListWebhooks returns all webhooks.
+func (u *WebhookUsecase) ListWebhooks() ([]*domain.Webhook, error) {
+	return u.repo.List()
+}
+
+// GetWebhook returns a single webhook.
+func (u *WebhookUsecase) GetWebhook(id string) (*domain.Webhook, error) {
+	w, err := u.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrWebhookNotFound
+	}
+	return w, nil
+}
+
+// CreateWebhook validates and stores a webhook. A signing secret is generated
+// when none is given; webhooks are enabled unless told otherwise.
+func (u *WebhookUsecase) CreateWebhook(dto *WebhookDTO) (*domain.Webhook, error) {
+	now := time.Now()
+	w := &domain.Webhook{
+		ID:         uuid.New().String(),
+		Name:       strings.TrimSpace(dto.Name),
+		URL:        strings.TrimSpace(dto.URL),
+		EventTypes: cleanList(dto.EventTypes),
+		CameraIDs:  cleanList(dto.CameraIDs),
+		Secret:     dto.Secret,
+		Enabled:    dto.Enabled == nil || *dto.Enabled,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if w.EventTypes == nil {
+		w.EventTypes = []string{}
+	}
+	if w.CameraIDs == nil {
+		w.CameraIDs = []string{}
+	}
+	if w.Secret == "" {
+		secret, err := newSecret()
+		if err != nil {
+			return nil, err
+		}
+		w.Secret = secret
+	}
+	if err := validateWebhook(w); err != nil {
+		return nil, err
+	}
+	if err := u.repo.Create(w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// UpdateWebhook updates the given fields of an existing webhook.
+func (u *WebhookUsecase) UpdateWebhook(dto *WebhookDTO) (*domain.Webhook, error) {
+	w, err := u.repo.GetByID(dto.ID)
+	if err != nil {
+		return nil, ErrWebhookNotFound
+	}
+	if dto.Name != "" {
+		w.Name = strings.TrimSpace(dto.Name)
+	}
+	if dto.URL != "" {
+		w.URL = strings.TrimSpace(dto.URL)
+	}
+	if dto.EventTypes != nil {
+		w.EventTypes = cleanList(dto.EventTypes)
+	}
+	if dto.CameraIDs != nil {
+		w.CameraIDs = cleanList(dto.CameraIDs)
+	}
+	if dto.Secret != "" {
+		w.Secret = dto.Secret
+	}
+	if dto.Enabled != nil {
+		w.Enabled = *dto.Enabled
+	}
+	w.UpdatedAt = time.Now()
+	if err := validateWebhook(w); err != nil {
+		return nil, err
+	}
+	if err := u.repo.Update(w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// DeleteWebhook removes a webhook and its delivery history.
+func (u *WebhookUsecase) DeleteWebhook(id string) error {
+	return u.repo.Delete(id)
+}
+
+// ListDeliveries returns a webhook's delivery history, newest first.
+func (u *WebhookUsecase) ListDeliveries(id, status string, limit int) ([]*domain.WebhookDelivery, error) {
+	if _, err := u.repo.GetByID(id); err != nil {
+		return nil, ErrWebhookNotFound
+	}
+	if limit <= 0 {
+		limit = defaultEventPageSize
+	}
+	return u.repo.ListDeliveries(id, status, limit)
+}
+
+// TestWebhook sends a test event to the webhook and returns the delivery.
+func (u *WebhookUsecase) TestWebhook(id string) (*domain.WebhookDelivery, error) {
+	w, err := u.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrWebhookNotFound
+	}
+	return u.sender.Test(w)
+}
+
+// Redeliver requeues one of the webhook's deliveries, e.g. a dead one.
+func (u *WebhookUsecase) Redeliver(id, deliveryID string) (*domain.WebhookDelivery, error) {
+	d, err := u.repo.GetDelivery(deliveryID)
+	if err != nil || d.WebhookID != id {
+		return nil, ErrWebhookNotFound
+	}
+	if err := u.sender.Redeliver(d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func validateWebhook(w *domain.Webhook) error {
+	parsed, err := url.Parse(w.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidWebhook)
+	}
+	return nil
+}
+
+// cleanList trims items and drops empty ones and duplicates.
+func cleanList(list []string) []string {
+	if list == nil {
+		return nil
+	}
+	out := []string{}
+	seen := map[string]bool{}
+	for _, v := range list {
+		v = strings.TrimSpace(strings.ReplaceAll(v, ",", " "))
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	return out
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
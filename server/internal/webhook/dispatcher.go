@@ -0,0 +1,315 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/boytur/cctv-recording-center/server/internal/domain"
+	"github.com/boytur/cctv-recording-center/server/internal/events"
+	"github.com/boytur/cctv-recording-center/server/internal/repository"
+	"github.com/google/uuid"
+)
+
+// Request headers sent with every delivery. The signature is
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)), so receivers
+// can reject replays by checking the timestamp.
+const (
+	HeaderEvent     = "X-CCTV-Event"
+	HeaderDelivery  = "X-CCTV-Delivery"
+	HeaderTimestamp = "X-CCTV-Timestamp"
+	HeaderSignature = "X-CCTV-Signature"
+)
+
+// TestEventType is the event type sent by Dispatcher.Test.
+const TestEventType = "webhook.test"
+
+const (
+	// maxAttempts is how many times a delivery is tried before it is dead.
+	maxAttempts = 8
+	// baseBackoff is the delay after the first failure; it doubles after
+	// each further failure up to maxBackoff.
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 1 * time.Hour
+	// requestTimeout bounds a single attempt.
+	requestTimeout = 10 * time.Second
+	// retryInterval is how often due retries are looked up.
+	retryInterval = 10 * time.Second
+)
+
+// Dispatcher delivers bus events to matching webhooks and retries failed
+// deliveries with exponential backoff.
+type Dispatcher struct {
+	repo   repository.WebhookRepository
+	client *http.Client
+
+	mu       sync.Mutex
+	inflight map[string]bool
+
+	stopChan chan struct{}
+	cancel   func()
+}
+
+// NewDispatcher creates a dispatcher using repo for webhooks and deliveries.
+func NewDispatcher(repo repository.WebhookRepository) *Dispatcher {
+	return &Dispatcher{
+		repo:     repo,
+		client:   &http.Client{Timeout: requestTimeout},
+		inflight: make(map[string]bool),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start subscribes to the event bus and starts the retry loop.
+func (d *Dispatcher) Start() {
+	ch, cancel := events.Subscribe(256, nil)
+	d.cancel = cancel
+	go func() {
+		for e := range ch {
+			d.dispatch(e)
+		}
+	}()
+	go d.retryLoop()
+}
+
+// Stop unsubscribes from the bus and stops retrying. Pending deliveries are
+// picked up again on the next start.
+func (d *Dispatcher) Stop() {
+	if d.cancel != nil {
+		d.cancel()
+	}
+	close(d.stopChan)
+}
+
+// dispatch records a delivery for every webhook matching the event and
+// attempts each one straight away.
+func (d *Dispatcher) dispatch(e domain.Event) {
+	hooks, err := d.repo.List()
+	if err != nil {
+		log.Printf("webhook: failed to list webhooks: %v", err)
+		return
+	}
+	for _, h := range hooks {
+		if !h.Matches(e) {
+			continue
+		}
+		del, err := d.newDelivery(h, e)
+		if err != nil {
+			log.Printf("webhook: failed to queue %s event for %s: %v", e.Type, h.ID, err)
+			continue
+		}
+		go d.attempt(h, del)
+	}
+}
+
+// Test sends a webhook.test event to a webhook once, regardless of its
+// filters or enabled flag, and returns the resulting delivery. A failed test
+// is not retried.
+func (d *Dispatcher) Test(h *domain.Webhook) (*domain.WebhookDelivery, error) {
+	e := domain.Event{
+		Type:     TestEventType,
+		Severity: domain.SeverityInfo,
+		Time:     time.Now(),
+		Payload:  map[string]interface{}{"message": "test delivery", "webhook_id": h.ID},
+	}
+	del, err := d.newDelivery(h, e)
+	if err != nil {
+		return nil, err
+	}
+	d.send(h, del)
+	if del.Status != domain.DeliverySucceeded {
+		del.Status = domain.DeliveryDead
+		del.NextAttemptAt = nil
+	}
+	del.UpdatedAt = time.Now()
+	if err := d.repo.UpdateDelivery(del); err != nil {
+		return nil, err
+	}
+	return del, nil
+}
+
+// Redeliver queues a delivery, typically a dead one, for another round of
+// attempts.
+func (d *Dispatcher) Redeliver(del *domain.WebhookDelivery) error {
+	now := time.Now()
+	del.Status = domain.DeliveryPending
+	del.Attempts = 0
+	del.NextAttemptAt = &now
+	del.UpdatedAt = now
+	return d.repo.UpdateDelivery(del)
+}
+
+func (d *Dispatcher) newDelivery(h *domain.Webhook, e domain.Event) (*domain.WebhookDelivery, error) {
+	now := time.Now()
+	del := &domain.WebhookDelivery{
+		ID:            uuid.New().String(),
+		WebhookID:     h.ID,
+		EventID:       e.ID,
+		EventType:     e.Type,
+		Status:        domain.DeliveryPending,
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"delivery_id": del.ID,
+		"webhook_id":  h.ID,
+		"event":       e,
+	})
+	if err != nil {
+		return nil, err
+	}
+	del.Body = string(body)
+	if err := d.repo.CreateDelivery(del); err != nil {
+		return nil, err
+	}
+	return del, nil
+}
+
+func (d *Dispatcher) retryLoop() {
+	ticker := time.NewTicker(retryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.retryDue()
+		case <-d.stopChan:
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) retryDue() {
+	due, err := d.repo.DueDeliveries(time.Now())
+	if err != nil {
+		log.Printf("webhook: failed to list due deliveries: %v", err)
+		return
+	}
+	for _, del := range due {
+		h, err := d.repo.GetByID(del.WebhookID)
+		if err != nil {
+			// webhook deleted while the delivery was waiting
+			del.Status = domain.DeliveryDead
+			del.LastError = "webhook no longer exists"
+			del.NextAttemptAt = nil
+			del.UpdatedAt = time.Now()
+			if err := d.repo.UpdateDelivery(del); err != nil {
+				log.Printf("webhook: failed to update delivery %s: %v", del.ID, err)
+			}
+			continue
+		}
+		go d.attempt(h, del)
+	}
+}
+
+// attempt sends a delivery once and schedules the next try on failure. A
+// delivery already being attempted is skipped.
+func (d *Dispatcher) attempt(h *domain.Webhook, queued *domain.WebhookDelivery) {
+	id := queued.ID
+	d.mu.Lock()
+	if d.inflight[id] {
+		d.mu.Unlock()
+		return
+	}
+	d.inflight[id] = true
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		delete(d.inflight, id)
+		d.mu.Unlock()
+	}()
+
+	// re-read so a copy loaded before a concurrent attempt finished isn't
+	// sent twice
+	del, err := d.repo.GetDelivery(id)
+	if err != nil || (del.Status != domain.DeliveryPending && del.Status != domain.DeliveryRetrying) {
+		return
+	}
+
+	d.send(h, del)
+	if del.Status != domain.DeliverySucceeded {
+		if del.Attempts >= maxAttempts {
+			del.Status = domain.DeliveryDead
+			del.NextAttemptAt = nil
+			log.Printf("webhook: delivery %s to %s is dead after %d attempts: %s", del.ID, h.URL, del.Attempts, del.LastError)
+		} else {
+			next := time.Now().Add(backoff(del.Attempts))
+			del.Status = domain.DeliveryRetrying
+			del.NextAttemptAt = &next
+		}
+	}
+	del.UpdatedAt = time.Now()
+	if err := d.repo.UpdateDelivery(del); err != nil {
+		log.Printf("webhook: failed to update delivery %s: %v", del.ID, err)
+	}
+}
+
+// send performs one signed POST and records its outcome on del. Any 2xx
+// response counts as delivered.
+func (d *Dispatcher) send(h *domain.Webhook, del *domain.WebhookDelivery) {
+	del.Attempts++
+	del.StatusCode = 0
+	del.LastError = ""
+
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader([]byte(del.Body)))
+	if err != nil {
+		del.LastError = err.Error()
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "cctv-recording-center-webhook/1")
+	req.Header.Set(HeaderEvent, del.EventType)
+	req.Header.Set(HeaderDelivery, del.ID)
+	req.Header.Set(HeaderTimestamp, ts)
+	req.Header.Set(HeaderSignature, Sign(h.Secret, ts, []byte(del.Body)))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		del.LastError = err.Error()
+		return
+	}
+	defer resp.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	del.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		del.LastError = fmt.Sprintf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(snippet))
+		return
+	}
+	now := time.Now()
+	del.Status = domain.DeliverySucceeded
+	del.DeliveredAt = &now
+	del.NextAttemptAt = nil
+}
+
+// Sign returns the signature header value for a request body.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff returns the delay after the given number of failed attempts.
+func backoff(attempts int) time.Duration {
+	d := baseBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
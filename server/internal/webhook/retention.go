@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultRetentionDays is how long finished deliveries are kept when
+	// WEBHOOK_DELIVERY_RETENTION_DAYS is not set.
+	defaultRetentionDays = 30
+	// pruneInterval is how often expired deliveries are deleted.
+	pruneInterval = time.Hour
+)
+
+// Pruner deletes finished deliveries older than a point in time.
+type Pruner interface {
+	DeleteFinishedDeliveries(before time.Time) (int64, error)
+}
+
+// Retention deletes succeeded and dead deliveries once they are older than
+// the retention period, so the delivery log does not grow without bound.
+// Pending and retrying deliveries are never deleted.
+type Retention struct {
+	store    Pruner
+	keep     time.Duration
+	stopChan chan struct{}
+}
+
+// NewRetention creates a retention policy keeping finished deliveries for
+// keep. Zero keeps them forever.
+func NewRetention(store Pruner, keep time.Duration) *Retention {
+	return &Retention{store: store, keep: keep, stopChan: make(chan struct{})}
+}
+
+// RetentionFromEnv reads WEBHOOK_DELIVERY_RETENTION_DAYS, 30 days by default.
+// 0 keeps deliveries forever.
+func RetentionFromEnv() time.Duration {
+	days := defaultRetentionDays
+	if v := os.Getenv("WEBHOOK_DELIVERY_RETENTION_DAYS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			days = n
+		} else {
+			log.Printf("webhook: invalid WEBHOOK_DELIVERY_RETENTION_DAYS %q, keeping deliveries for %d days", v, days)
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// Start deletes expired deliveries now and then every hour.
+func (r *Retention) Start() {
+	if r.keep <= 0 {
+		log.Println("webhook: keeping deliveries forever")
+		return
+	}
+	go r.run()
+	log.Printf("webhook: keeping finished deliveries for %s", r.keep)
+}
+
+// Stop stops deleting expired deliveries.
+func (r *Retention) Stop() {
+	close(r.stopChan)
+}
+
+func (r *Retention) run() {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		r.prune()
+		select {
+		case <-ticker.C:
+		case <-r.stopChan:
+			return
+		}
+	}
+}
+
+func (r *Retention) prune() {
+	n, err := r.store.DeleteFinishedDeliveries(time.Now().Add(-r.keep))
+	if err != nil {
+		log.Printf("webhook: failed to delete expired deliveries: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("webhook: deleted %d deliveries older than %s", n, r.keep)
+	}
+}